@@ -3,8 +3,13 @@ package apis
 import (
 	"k8s.io/apimachinery/pkg/runtime"
 
+	bmv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/branchingmodel/v1alpha1"
+	prv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/pullrequest/v1alpha1"
 	repov1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repo/v1alpha1"
+	rfv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repofile/v1alpha1"
+	rhv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repohook/v1alpha1"
 	rpuv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repopermissionuser/v1alpha1"
+	rbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/requiredbuilds/v1alpha1"
 	bbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
 )
 
@@ -14,6 +19,11 @@ func init() {
 		bbv1alpha1.SchemeBuilder.AddToScheme,
 		repov1alpha1.SchemeBuilder.AddToScheme,
 		rpuv1alpha1.SchemeBuilder.AddToScheme,
+		rhv1alpha1.SchemeBuilder.AddToScheme,
+		rbv1alpha1.SchemeBuilder.AddToScheme,
+		bmv1alpha1.SchemeBuilder.AddToScheme,
+		rfv1alpha1.SchemeBuilder.AddToScheme,
+		prv1alpha1.SchemeBuilder.AddToScheme,
 	)
 }
 
@@ -0,0 +1,117 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type BranchSetting struct {
+	// RefID: the branch ref (i.e. refs/heads/develop).
+	// +optional
+	RefID *string `json:"refId,omitempty"`
+
+	// UseDefault: whether the repository default branch is used (default: false).
+	// +optional
+	UseDefault *bool `json:"useDefault,omitempty"`
+}
+
+type BranchType struct {
+	// ID: the branch type (BUGFIX, FEATURE, HOTFIX, RELEASE).
+	// +kubebuilder:validation:Enum=BUGFIX;FEATURE;HOTFIX;RELEASE
+	ID string `json:"id"`
+
+	// Enabled: whether the branch type is enabled (default: true).
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Prefix: the branch name prefix (i.e. feature/).
+	// +optional
+	Prefix *string `json:"prefix,omitempty"`
+}
+
+type BranchingModelParams struct {
+	// Project: the project key.
+	// +immutable
+	Project string `json:"project"`
+
+	// RepoSlug: slug format of repository name; if omitted the branching model is configured at project level.
+	// +optional
+	// +immutable
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// Inherit: whether the repository inherits the project branching model (default: false).
+	// When true, the other settings are ignored.
+	// +optional
+	Inherit *bool `json:"inherit,omitempty"`
+
+	// Development: the development branch (default: the repository default branch).
+	// +optional
+	Development *BranchSetting `json:"development,omitempty"`
+
+	// Production: the production branch; if omitted no production branch is set.
+	// +optional
+	Production *BranchSetting `json:"production,omitempty"`
+
+	// Types: the branch types prefixes.
+	// +optional
+	Types []BranchType `json:"types,omitempty"`
+}
+
+type BranchingModelObservation struct {
+	// Project: the project key.
+	Project *string `json:"project,omitempty"`
+
+	// RepoSlug: the repository name slug.
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// Development: the development branch.
+	Development *string `json:"development,omitempty"`
+
+	// Production: the production branch.
+	Production *string `json:"production,omitempty"`
+
+	// Scope: where the branching model comes from (PROJECT, REPOSITORY).
+	Scope *string `json:"scope,omitempty"`
+}
+
+// A BranchingModelSpec defines the desired state of a BranchingModel.
+type BranchingModelSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       BranchingModelParams `json:"forProvider"`
+}
+
+// A BranchingModelStatus represents the observed state of a BranchingModel.
+type BranchingModelStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          BranchingModelObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A BranchingModel is a managed resource that represents a bitbucket project or repository branching model
+// +kubebuilder:printcolumn:name="PROJECT",type="string",JSONPath=".status.atProvider.project"
+// +kubebuilder:printcolumn:name="SLUG",type="string",JSONPath=".status.atProvider.repoSlug"
+// +kubebuilder:printcolumn:name="DEVELOPMENT",type="string",JSONPath=".status.atProvider.development"
+// +kubebuilder:printcolumn:name="PRODUCTION",type="string",JSONPath=".status.atProvider.production"
+// +kubebuilder:printcolumn:name="SCOPE",type="string",JSONPath=".status.atProvider.scope"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",priority=1
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,krateo,bitbucket}
+type BranchingModel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BranchingModelSpec   `json:"spec"`
+	Status BranchingModelStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BranchingModelList contains a list of BranchingModel.
+type BranchingModelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BranchingModel `json:"items"`
+}
@@ -0,0 +1,110 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type PullRequestParams struct {
+	// Project: the project key.
+	// +immutable
+	Project string `json:"project"`
+
+	// RepoSlug: slug format of repository name.
+	// +immutable
+	RepoSlug string `json:"repoSlug"`
+
+	// SourceRef: the branch to merge from (i.e. release/1.0 or refs/heads/release/1.0).
+	// +immutable
+	SourceRef string `json:"sourceRef"`
+
+	// TargetRef: the branch to merge into (i.e. main).
+	TargetRef string `json:"targetRef"`
+
+	// Title: the pull request title.
+	Title string `json:"title"`
+
+	// Description: the pull request description.
+	// +optional
+	Description *string `json:"description,omitempty"`
+
+	// Reviewers: the user names of the reviewers, the others (i.e. default reviewers) being removed;
+	// if omitted the reviewers are left as they are.
+	// +optional
+	Reviewers []string `json:"reviewers,omitempty"`
+
+	// Merge: whether to merge the pull request once the merge checks pass (default: false).
+	// +optional
+	Merge *bool `json:"merge,omitempty"`
+}
+
+type PullRequestObservation struct {
+	// ID: the pull request identifier.
+	ID *int `json:"id,omitempty"`
+
+	// URL: the pull request web address.
+	URL *string `json:"url,omitempty"`
+
+	// State: the pull request state (OPEN, MERGED, DECLINED).
+	State *string `json:"state,omitempty"`
+
+	// Approvals: the number of reviewers who approved the pull request.
+	Approvals *int `json:"approvals,omitempty"`
+
+	// Conflicted: whether the pull request has merge conflicts.
+	Conflicted *bool `json:"conflicted,omitempty"`
+
+	// CanMerge: whether all the merge checks pass.
+	CanMerge *bool `json:"canMerge,omitempty"`
+
+	// Vetoes: the merge checks preventing the merge.
+	Vetoes []string `json:"vetoes,omitempty"`
+
+	// BuildStatus: the aggregated build status of the source commit (SUCCESSFUL, FAILED, INPROGRESS, NONE).
+	BuildStatus *string `json:"buildStatus,omitempty"`
+
+	// SourceCommit: the latest commit of the source branch.
+	SourceCommit *string `json:"sourceCommit,omitempty"`
+}
+
+// A PullRequestSpec defines the desired state of a PullRequest.
+type PullRequestSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       PullRequestParams `json:"forProvider"`
+}
+
+// A PullRequestStatus represents the observed state of a PullRequest.
+type PullRequestStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          PullRequestObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A PullRequest is a managed resource that represents a bitbucket pull request
+// +kubebuilder:printcolumn:name="ID",type="integer",JSONPath=".status.atProvider.id"
+// +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.atProvider.state"
+// +kubebuilder:printcolumn:name="APPROVALS",type="integer",JSONPath=".status.atProvider.approvals"
+// +kubebuilder:printcolumn:name="BUILD",type="string",JSONPath=".status.atProvider.buildStatus"
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.atProvider.url",priority=1
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",priority=1
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,krateo,bitbucket}
+type PullRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PullRequestSpec   `json:"spec"`
+	Status PullRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PullRequestList contains a list of PullRequest.
+type PullRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PullRequest `json:"items"`
+}
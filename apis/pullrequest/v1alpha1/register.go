@@ -0,0 +1,34 @@
+package v1alpha1
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// Package type metadata.
+const (
+	Group   = "bitbucket.krateo.io"
+	Version = "v1alpha1"
+)
+
+var (
+	// SchemeGroupVersion is group version used to register these objects
+	SchemeGroupVersion = schema.GroupVersion{Group: Group, Version: Version}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+)
+
+// PullRequest type metadata.
+var (
+	PullRequestKind             = reflect.TypeOf(PullRequest{}).Name()
+	PullRequestGroupKind        = schema.GroupKind{Group: Group, Kind: PullRequestKind}.String()
+	PullRequestKindAPIVersion   = PullRequestKind + "." + SchemeGroupVersion.String()
+	PullRequestGroupVersionKind = SchemeGroupVersion.WithKind(PullRequestKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&PullRequest{}, &PullRequestList{})
+}
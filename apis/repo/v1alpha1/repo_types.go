@@ -5,6 +5,22 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AnnotationKeyCascadeDelete, when set to "true", lets a Repo be deleted along
+// with the managed resources referencing the same repository.
+const AnnotationKeyCascadeDelete = "bitbucket.krateo.io/cascade-delete"
+
+// AnnotationKeyForceAdopt, when set to "true", lets a Repo adopt and delete a
+// repository not marked as managed by it, then marks it.
+const AnnotationKeyForceAdopt = "bitbucket.krateo.io/force-adopt"
+
+type ForkSource struct {
+	// Project: the project key of the repository to fork.
+	Project string `json:"project"`
+
+	// RepoSlug: the slug of the repository to fork.
+	RepoSlug string `json:"repoSlug"`
+}
+
 type RepoParams struct {
 	// Project: the project key.
 	// +immutable
@@ -21,6 +37,20 @@ type RepoParams struct {
 	// Initialize: whether the repository must be initialized (default: true).
 	// +optional
 	Initialize *bool `json:"initialize,omitempty"`
+
+	// Archived: whether the repository is archived, becoming read-only (default: false).
+	// +optional
+	Archived *bool `json:"archived,omitempty"`
+
+	// ForkFrom: the repository to fork; when set the repository is created as a fork and initialize is ignored.
+	// +optional
+	// +immutable
+	ForkFrom *ForkSource `json:"forkFrom,omitempty"`
+
+	// RefSync: whether the fork branches are kept in sync with the origin ones (default: false).
+	// Applies to forks only.
+	// +optional
+	RefSync *bool `json:"refSync,omitempty"`
 }
 
 type RepoObservation struct {
@@ -32,6 +62,15 @@ type RepoObservation struct {
 
 	// State: the repository state.
 	State *string `json:"state,omitempty"`
+
+	// Archived: whether the repository is archived.
+	Archived *bool `json:"archived,omitempty"`
+
+	// Origin: the forked repository (project/slug).
+	Origin *string `json:"origin,omitempty"`
+
+	// RefSync: whether the fork branches are kept in sync with the origin ones.
+	RefSync *bool `json:"refSync,omitempty"`
 }
 
 // A RepoSpec defines the desired state of a Repo.
@@ -25,6 +25,21 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForkSource) DeepCopyInto(out *ForkSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForkSource.
+func (in *ForkSource) DeepCopy() *ForkSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ForkSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Repo) DeepCopyInto(out *Repo) {
 	*out = *in
@@ -102,6 +117,21 @@ func (in *RepoObservation) DeepCopyInto(out *RepoObservation) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Archived != nil {
+		in, out := &in.Archived, &out.Archived
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Origin != nil {
+		in, out := &in.Origin, &out.Origin
+		*out = new(string)
+		**out = **in
+	}
+	if in.RefSync != nil {
+		in, out := &in.RefSync, &out.RefSync
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoObservation.
@@ -127,6 +157,21 @@ func (in *RepoParams) DeepCopyInto(out *RepoParams) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Archived != nil {
+		in, out := &in.Archived, &out.Archived
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ForkFrom != nil {
+		in, out := &in.ForkFrom, &out.ForkFrom
+		*out = new(ForkSource)
+		**out = **in
+	}
+	if in.RefSync != nil {
+		in, out := &in.RefSync, &out.RefSync
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoParams.
@@ -0,0 +1,118 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ConfigMapKeySelector struct {
+	// Name: the name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace: the namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+
+	// Key: the key whose value is the file content.
+	Key string `json:"key"`
+}
+
+type ContentSource struct {
+	// ConfigMapKeyRef: selects a key of a ConfigMap.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef: selects a key of a Secret.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+type RepoFileParams struct {
+	// Project: the project key.
+	// +immutable
+	Project string `json:"project"`
+
+	// RepoSlug: slug format of repository name.
+	// +immutable
+	RepoSlug string `json:"repoSlug"`
+
+	// Path: the file path relative to the repository root (i.e. docs/README.md).
+	// +immutable
+	Path string `json:"path"`
+
+	// Branch: the branch to commit on (default: main).
+	// +optional
+	// +immutable
+	Branch *string `json:"branch,omitempty"`
+
+	// Content: the file content.
+	// +optional
+	Content *string `json:"content,omitempty"`
+
+	// ContentFrom: reads the file content from a ConfigMap or a Secret; ignored if content is set.
+	// +optional
+	ContentFrom *ContentSource `json:"contentFrom,omitempty"`
+
+	// CommitMessage: the commit message template; {{ .Path }}, {{ .Branch }} and {{ .Name }} are
+	// replaced with the file path, the branch and the resource name (default: Update {{ .Path }}).
+	// +optional
+	CommitMessage *string `json:"commitMessage,omitempty"`
+}
+
+type RepoFileObservation struct {
+	// Project: the project key.
+	Project *string `json:"project,omitempty"`
+
+	// RepoSlug: the repository name slug.
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// Path: the file path.
+	Path *string `json:"path,omitempty"`
+
+	// Branch: the branch.
+	Branch *string `json:"branch,omitempty"`
+
+	// CommitID: the latest commit modifying the file.
+	CommitID *string `json:"commitId,omitempty"`
+}
+
+// A RepoFileSpec defines the desired state of a RepoFile.
+type RepoFileSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RepoFileParams `json:"forProvider"`
+}
+
+// A RepoFileStatus represents the observed state of a RepoFile.
+type RepoFileStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RepoFileObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A RepoFile is a managed resource that represents a file in a bitbucket repository
+// +kubebuilder:printcolumn:name="PROJECT",type="string",JSONPath=".status.atProvider.project"
+// +kubebuilder:printcolumn:name="SLUG",type="string",JSONPath=".status.atProvider.repoSlug"
+// +kubebuilder:printcolumn:name="PATH",type="string",JSONPath=".status.atProvider.path"
+// +kubebuilder:printcolumn:name="BRANCH",type="string",JSONPath=".status.atProvider.branch"
+// +kubebuilder:printcolumn:name="COMMIT",type="string",JSONPath=".status.atProvider.commitId"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",priority=1
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,krateo,bitbucket}
+type RepoFile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RepoFileSpec   `json:"spec"`
+	Status RepoFileStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RepoFileList contains a list of RepoFile.
+type RepoFileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RepoFile `json:"items"`
+}
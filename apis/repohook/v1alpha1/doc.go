@@ -0,0 +1,5 @@
+// Package v1alpha1 contains managed resources.
+// +kubebuilder:object:generate=true
+// +groupName=bitbucket.krateo.io
+// +versionName=v1alpha1
+package v1alpha1
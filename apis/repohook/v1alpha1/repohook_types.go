@@ -0,0 +1,94 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type RepoHookParams struct {
+	// Project: the project key.
+	// +immutable
+	Project string `json:"project"`
+
+	// RepoSlug: slug format of repository name; if omitted the hook is configured at project level.
+	// +optional
+	// +immutable
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// HookKey: the complete key of the hook (i.e. com.atlassian.bitbucket.server.bitbucket-bundled-hooks:force-push-hook).
+	// +immutable
+	HookKey string `json:"hookKey"`
+
+	// Enabled: whether the hook is enabled (default: true).
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Inherit: whether the repository hook inherits the project configuration (default: false).
+	// When true, enabled and settings are ignored.
+	// +optional
+	Inherit *bool `json:"inherit,omitempty"`
+
+	// Settings: the hook specific settings.
+	// +optional
+	Settings *runtime.RawExtension `json:"settings,omitempty"`
+}
+
+type RepoHookObservation struct {
+	// Project: the project key.
+	Project *string `json:"project,omitempty"`
+
+	// RepoSlug: the repository name slug.
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// Name: the hook name.
+	Name *string `json:"name,omitempty"`
+
+	// Enabled: whether the hook is enabled.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Scope: where the hook configuration comes from (PROJECT, REPOSITORY).
+	Scope *string `json:"scope,omitempty"`
+}
+
+// A RepoHookSpec defines the desired state of a RepoHook.
+type RepoHookSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RepoHookParams `json:"forProvider"`
+}
+
+// A RepoHookStatus represents the observed state of a RepoHook.
+type RepoHookStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RepoHookObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A RepoHook is a managed resource that represents a bitbucket project or repository hook
+// +kubebuilder:printcolumn:name="PROJECT",type="string",JSONPath=".status.atProvider.project"
+// +kubebuilder:printcolumn:name="SLUG",type="string",JSONPath=".status.atProvider.repoSlug"
+// +kubebuilder:printcolumn:name="HOOK",type="string",JSONPath=".status.atProvider.name"
+// +kubebuilder:printcolumn:name="ENABLED",type="boolean",JSONPath=".status.atProvider.enabled"
+// +kubebuilder:printcolumn:name="SCOPE",type="string",JSONPath=".status.atProvider.scope"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",priority=1
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,krateo,bitbucket}
+type RepoHook struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RepoHookSpec   `json:"spec"`
+	Status RepoHookStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RepoHookList contains a list of RepoHook.
+type RepoHookList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RepoHook `json:"items"`
+}
@@ -0,0 +1,34 @@
+package v1alpha1
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+// Package type metadata.
+const (
+	Group   = "bitbucket.krateo.io"
+	Version = "v1alpha1"
+)
+
+var (
+	// SchemeGroupVersion is group version used to register these objects
+	SchemeGroupVersion = schema.GroupVersion{Group: Group, Version: Version}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+)
+
+// RequiredBuilds type metadata.
+var (
+	RequiredBuildsKind             = reflect.TypeOf(RequiredBuilds{}).Name()
+	RequiredBuildsGroupKind        = schema.GroupKind{Group: Group, Kind: RequiredBuildsKind}.String()
+	RequiredBuildsKindAPIVersion   = RequiredBuildsKind + "." + SchemeGroupVersion.String()
+	RequiredBuildsGroupVersionKind = SchemeGroupVersion.WithKind(RequiredBuildsKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&RequiredBuilds{}, &RequiredBuildsList{})
+}
@@ -0,0 +1,96 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type RefMatcher struct {
+	// Type: the matcher type (BRANCH, PATTERN, MODEL_BRANCH, MODEL_CATEGORY, ANY_REF).
+	// +kubebuilder:validation:Enum=BRANCH;PATTERN;MODEL_BRANCH;MODEL_CATEGORY;ANY_REF
+	Type string `json:"type"`
+
+	// ID: the value to match (i.e. refs/heads/main, release/*, production); not required for ANY_REF.
+	// +optional
+	ID string `json:"id,omitempty"`
+}
+
+type RequiredBuildsParams struct {
+	// Project: the project key.
+	// +immutable
+	Project string `json:"project"`
+
+	// RepoSlug: slug format of repository name; if omitted the merge check is configured at project level.
+	// +optional
+	// +immutable
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// BuildParentKeys: the keys of the builds that must succeed before merging.
+	// +kubebuilder:validation:MinItems=1
+	BuildParentKeys []string `json:"buildParentKeys"`
+
+	// TargetRefMatcher: the target branches of the pull requests the merge check applies to.
+	TargetRefMatcher RefMatcher `json:"targetRefMatcher"`
+
+	// SourceRefMatcher: the source branches of the pull requests the merge check applies to.
+	// +optional
+	SourceRefMatcher *RefMatcher `json:"sourceRefMatcher,omitempty"`
+
+	// ExemptRefMatcher: the branches exempted from the merge check.
+	// +optional
+	ExemptRefMatcher *RefMatcher `json:"exemptRefMatcher,omitempty"`
+}
+
+type RequiredBuildsObservation struct {
+	// ID: the merge check condition identifier.
+	ID *int `json:"id,omitempty"`
+
+	// Project: the project key.
+	Project *string `json:"project,omitempty"`
+
+	// RepoSlug: the repository name slug.
+	RepoSlug *string `json:"repoSlug,omitempty"`
+
+	// BuildParentKeys: the keys of the required builds.
+	BuildParentKeys []string `json:"buildParentKeys,omitempty"`
+}
+
+// A RequiredBuildsSpec defines the desired state of a RequiredBuilds.
+type RequiredBuildsSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RequiredBuildsParams `json:"forProvider"`
+}
+
+// A RequiredBuildsStatus represents the observed state of a RequiredBuilds.
+type RequiredBuildsStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RequiredBuildsObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A RequiredBuilds is a managed resource that represents a bitbucket required builds merge check
+// +kubebuilder:printcolumn:name="PROJECT",type="string",JSONPath=".status.atProvider.project"
+// +kubebuilder:printcolumn:name="SLUG",type="string",JSONPath=".status.atProvider.repoSlug"
+// +kubebuilder:printcolumn:name="ID",type="integer",JSONPath=".status.atProvider.id"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",priority=1
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,krateo,bitbucket}
+type RequiredBuilds struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RequiredBuildsSpec   `json:"spec"`
+	Status RequiredBuildsStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RequiredBuildsList contains a list of RequiredBuilds.
+type RequiredBuildsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RequiredBuilds `json:"items"`
+}
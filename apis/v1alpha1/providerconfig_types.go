@@ -8,20 +8,244 @@ import (
 
 // Credentials required to authenticate.
 type Credentials struct {
-	// Source of the ReST API Token.
-	// +kubebuilder:validation:Enum=None;Secret;Environment
+	// Source of the ReST API Token: a key of a Secret, an environment variable or a
+	// file (read again on every reconciliation); either the bare token or a JSON object
+	// with the username and the token, i.e. {"username":"jdoe","token":"..."}.
+	// +kubebuilder:validation:Enum=None;Secret;Environment;Filesystem
 	Source xpv1.CredentialsSource `json:"source"`
 
 	xpv1.CommonCredentialSelectors `json:",inline"`
 }
 
+// Repository deletion modes.
+const (
+	RepoDeletionArchive = "Archive"
+	RepoDeletionTrash   = "Trash"
+	RepoDeletionDelete  = "Delete"
+)
+
+// RepoExport configures the repository export done before a permanent deletion.
+type RepoExport struct {
+	// Directory: where archives are written (i.e. a mounted volume); each export
+	// is saved in <directory>/<project>/<slug>/<timestamp> along with a manifest.json.
+	Directory string `json:"directory"`
+
+	// Format: the archive format of each branch, zip or tgz (default: tgz).
+	// +kubebuilder:validation:Enum=zip;tgz
+	// +optional
+	Format *string `json:"format,omitempty"`
+}
+
+// RepoDeletionPolicy tells what happens to a repository when its Repo is deleted.
+type RepoDeletionPolicy struct {
+	// Mode: Archive marks the repository as archived, Trash moves it into the trash
+	// project with a timestamped name, Delete permanently deletes it (default: Archive,
+	// on the cloud flavor Trash with a trash project and Delete otherwise).
+	// +kubebuilder:validation:Enum=Archive;Trash;Delete
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// TrashProject: the project key where repositories are moved in Trash mode.
+	// +optional
+	TrashProject *string `json:"trashProject,omitempty"`
+
+	// Retention: how long repositories are kept in the trash project before
+	// being purged (i.e. 720h); if omitted they are never purged.
+	// +optional
+	Retention *metav1.Duration `json:"retention,omitempty"`
+
+	// Export: when set, repositories are exported and the export verified before being
+	// permanently deleted; deletion is retried until the export succeeds. Applies to Delete mode only.
+	// +optional
+	Export *RepoExport `json:"export,omitempty"`
+}
+
+// Authentication types.
+const (
+	AuthBasic                   = "basic"
+	AuthBearer                  = "bearer"
+	AuthOAuth2ClientCredentials = "oauth2-client-credentials"
+	AuthTokenExchange           = "token-exchange"
+)
+
+// Auth configures how the provider authenticates to Bitbucket; the secret
+// is always read from the credentials.
+type Auth struct {
+	// Type: basic sends the username and the secret (i.e. an app password), bearer sends
+	// the secret as a token, oauth2-client-credentials gets an access token from the
+	// token URL with the client id and the secret (an OAuth 2.0 client secret),
+	// token-exchange gets a short-lived token from the token URL exchanging the
+	// secret (i.e. a service account token) for it (RFC 8693).
+	// +kubebuilder:validation:Enum=basic;bearer;oauth2-client-credentials;token-exchange
+	Type string `json:"type"`
+
+	// Username: the user of the basic auth (default: spec.username).
+	// +optional
+	Username *string `json:"username,omitempty"`
+
+	// ClientID: the OAuth 2.0 client id; required with oauth2-client-credentials.
+	// +optional
+	ClientID *string `json:"clientId,omitempty"`
+
+	// TokenURL: the token endpoint; required with token-exchange. With oauth2-client-credentials
+	// the default is https://bitbucket.org/site/oauth2/access_token with the cloud flavor,
+	// and <apiUrl>/rest/oauth2/latest/token with the server one.
+	// +optional
+	TokenURL *string `json:"tokenUrl,omitempty"`
+
+	// Scopes: the scopes of the requested tokens.
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Audience: the service the exchanged token is for, with token-exchange.
+	// +optional
+	Audience *string `json:"audience,omitempty"`
+
+	// SubjectTokenType: the type of the secret exchanged with token-exchange
+	// (default: urn:ietf:params:oauth:token-type:jwt).
+	// +optional
+	SubjectTokenType *string `json:"subjectTokenType,omitempty"`
+}
+
+// TLS versions.
+const (
+	TLSVersion10 = "1.0"
+	TLSVersion11 = "1.1"
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// ConfigMapKeySelector is a reference to a key of a ConfigMap.
+type ConfigMapKeySelector struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+// CABundle is a PEM bundle of certificate authorities, from a Secret or a ConfigMap.
+type CABundle struct {
+	// +optional
+	SecretRef *xpv1.SecretKeySelector `json:"secretRef,omitempty"`
+
+	// +optional
+	ConfigMapRef *ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+// TLS configures the connections to Bitbucket.
+type TLS struct {
+	// CA: the certificate authorities of Bitbucket, i.e. an internal CA, trusted
+	// besides the ones of the system.
+	// +optional
+	CA *CABundle `json:"ca,omitempty"`
+
+	// ClientCertSecretRef: the PEM certificate presented to Bitbucket (mTLS).
+	// +optional
+	ClientCertSecretRef *xpv1.SecretKeySelector `json:"clientCertSecretRef,omitempty"`
+
+	// ClientKeySecretRef: the PEM private key of the client certificate.
+	// +optional
+	ClientKeySecretRef *xpv1.SecretKeySelector `json:"clientKeySecretRef,omitempty"`
+
+	// ServerName: the name the certificate of Bitbucket is verified against
+	// (default: the host of the apiUrl).
+	// +optional
+	ServerName *string `json:"serverName,omitempty"`
+
+	// MinVersion: the minimum TLS version (default: 1.2).
+	// +kubebuilder:validation:Enum="1.0";"1.1";"1.2";"1.3"
+	// +optional
+	MinVersion *string `json:"minVersion,omitempty"`
+}
+
+// Proxy is the HTTP(S) proxy of the connections to Bitbucket.
+type Proxy struct {
+	// URL: the proxy, i.e. http://proxy.internal:3128.
+	URL string `json:"url"`
+
+	// NoProxy: the hosts reached without the proxy: host names, domains
+	// (i.e. .example.com), IP addresses and CIDRs, optionally with a port.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+
+	// CredentialsSecretRef: the "username:password" of the proxy basic auth.
+	// +optional
+	CredentialsSecretRef *xpv1.SecretKeySelector `json:"credentialsSecretRef,omitempty"`
+}
+
+// Transport tunes the connections to Bitbucket.
+type Transport struct {
+	// Proxy: the HTTP(S) proxy (default: from the HTTPS_PROXY, HTTP_PROXY
+	// and NO_PROXY environment variables).
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// ConnectTimeout: how long to wait for a connection, the TLS handshake
+	// included (default: 15s).
+	// +optional
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+
+	// ResponseTimeout: how long to wait for the headers of a response once the
+	// request is sent (default: 30s).
+	// +optional
+	ResponseTimeout *metav1.Duration `json:"responseTimeout,omitempty"`
+
+	// Timeout: how long a request can take, the reading of the response body
+	// included, except the archive downloads of the exports, bounded by the
+	// reconcile only (default: the connect timeout plus the response one).
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// MaxIdleConns: the maximum number of idle connections (default: 100).
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxIdleConns *int `json:"maxIdleConns,omitempty"`
+
+	// MaxIdleConnsPerHost: the maximum number of idle connections to a host (default: 5).
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxIdleConnsPerHost *int `json:"maxIdleConnsPerHost,omitempty"`
+
+	// IdleConnTimeout: how long an idle connection is kept (default: 90s).
+	// +optional
+	IdleConnTimeout *metav1.Duration `json:"idleConnTimeout,omitempty"`
+
+	// HTTP2: whether HTTP/2 is used when Bitbucket supports it (default: true).
+	// +optional
+	HTTP2 *bool `json:"http2,omitempty"`
+}
+
+// Bitbucket flavors.
+const (
+	FlavorServer = "server"
+	FlavorCloud  = "cloud"
+)
+
 // A ProviderConfigSpec defines the desired state of a ProviderConfig.
 type ProviderConfigSpec struct {
-	// Username: specify this if you want to use basic auth
+	// Flavor: the Bitbucket platform, server (Bitbucket Server and Data Center)
+	// or cloud (Bitbucket Cloud) (default: server).
+	// +kubebuilder:validation:Enum=server;cloud
+	// +optional
+	// +immutable
+	Flavor *string `json:"flavor,omitempty"`
+
+	// Workspace: the Bitbucket Cloud workspace owning the repositories; required with the cloud flavor.
+	// +optional
+	// +immutable
+	Workspace *string `json:"workspace,omitempty"`
+
+	// Username: specify this if you want to use basic auth (i.e. with a Bitbucket Cloud
+	// app password); otherwise the token is sent as bearer (i.e. an OAuth access token).
+	// Ignored when auth is set, but as the default of its username.
 	// +optional
 	Username *string `json:"username,omitempty"`
 
-	// ApiUrl: the baseUrl for the REST API provider.
+	// Auth: how to authenticate with the credentials (default: basic when
+	// the username is set, otherwise bearer).
+	// +optional
+	Auth *Auth `json:"auth,omitempty"`
+
+	// ApiUrl: the baseUrl for the REST API provider (default with the cloud flavor: https://api.bitbucket.org).
 	// +optional
 	// +immutable
 	ApiUrl string `json:"apiUrl,omitempty"`
@@ -33,9 +257,28 @@ type ProviderConfigSpec struct {
 	// +optional
 	Verbose *bool `json:"verbose,omitempty"`
 
-	// Insecure is useful with hand made SSL certs (default: false)
+	// Insecure is useful with hand made SSL certs (default: false); prefer tls.ca.
 	// +optional
 	Insecure *bool `json:"insecure,omitempty"`
+
+	// TLS: the certificate authorities, the client certificate and the
+	// versions of the connections to Bitbucket.
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
+
+	// Transport: the proxy, the timeouts and the connection pool of the
+	// connections to Bitbucket.
+	// +optional
+	Transport *Transport `json:"transport,omitempty"`
+
+	// RepoDeletion: what happens to repositories when their Repo is deleted.
+	// +optional
+	RepoDeletion *RepoDeletionPolicy `json:"repoDeletion,omitempty"`
+
+	// ClusterID: identifies this cluster in the marker label applied to the repositories
+	// the provider manages (default: the ProviderConfig UID).
+	// +optional
+	ClusterID *string `json:"clusterId,omitempty"`
 }
 
 // A ProviderConfigStatus reflects the observed state of a ProviderConfig.
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/simulator"
+)
+
+func main() {
+	var (
+		app       = kingpin.New(filepath.Base(os.Args[0]), "Bitbucket Server simulator for local development.").DefaultEnvars()
+		debug     = app.Flag("debug", "Run with debug logging.").Short('d').Default("false").Bool()
+		listen    = app.Flag("listen", "Address to listen on.").Default(":7990").String()
+		state     = app.Flag("state", "File persisting the state; if empty the state is kept in memory.").Default("").String()
+		seed      = app.Flag("seed", "YAML file with the projects and repositories to create at startup.").Default("").String()
+		username  = app.Flag("username", "Username accepted with basic auth.").Default("").String()
+		token     = app.Flag("token", "The only token accepted; if empty any request is accepted.").Default("").String()
+		pageLimit = app.Flag("page-limit", "Maximum size of the pages.").Default("25").Int()
+	)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	log := logging.NewLogrLogger(zap.New(zap.UseDevMode(*debug)).WithName("bitbucket-sim"))
+
+	sim, err := simulator.New(simulator.Options{
+		StatePath: *state,
+		Username:  *username,
+		Token:     *token,
+		PageLimit: *pageLimit,
+		Log:       log,
+	})
+	kingpin.FatalIfError(err, "Cannot create the simulator")
+
+	if len(*seed) > 0 {
+		s, err := simulator.LoadSeed(*seed)
+		kingpin.FatalIfError(err, "Cannot load the seed")
+		kingpin.FatalIfError(sim.Seed(s), "Cannot seed the simulator")
+	}
+
+	log.Info("Bitbucket simulator listening", "address", *listen, "state", *state)
+	kingpin.FatalIfError(http.ListenAndServe(*listen, sim), "Cannot serve")
+}
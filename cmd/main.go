@@ -16,6 +16,8 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/krateoplatformops/provider-bitbucket/apis"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/cassette"
 	bitbucket "github.com/krateoplatformops/provider-bitbucket/pkg/controller"
 )
 
@@ -28,9 +30,14 @@ func main() {
 		syncInterval     = app.Flag("sync", "How often all resources will be double-checked for drift from the desired state.").Short('s').Default("1h").Duration()
 		pollInterval     = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("3m").Duration()
 		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("4").Int()
+
+		cassetteDir  = app.Flag("cassette-dir", "Directory of the cassettes recording the Bitbucket interactions, one per ProviderConfig; empty to disable them.").String()
+		cassetteMode = app.Flag("cassette-mode", "Record appends the Bitbucket interactions to the cassettes, Replay answers from them.").Default(cassette.ModeRecord).Enum(cassette.ModeRecord, cassette.ModeReplay)
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
+	clients.UseCassettes(*cassetteDir, *cassetteMode)
+
 	zl := zap.New(zap.UseDevMode(*debug))
 	log := logging.NewLogrLogger(zl.WithName("provider-bitbucket"))
 	if *debug {
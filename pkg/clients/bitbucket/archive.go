@@ -0,0 +1,98 @@
+package bitbucket
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Archive formats supported by Bitbucket.
+const (
+	ArchiveFormatZip = "zip"
+	ArchiveFormatTgz = "tgz"
+)
+
+type Branch struct {
+	Id           string `json:"id"`
+	DisplayId    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit,omitempty"`
+	IsDefault    bool   `json:"isDefault"`
+}
+
+type GroupPermission struct {
+	Group struct {
+		Name string `json:"name"`
+	} `json:"group"`
+	Permission string `json:"permission"`
+}
+
+// Branches returns all the branches of the repository.
+func (s *serverRepoService) Branches(ctx context.Context, opts GetRepoOpts) ([]Branch, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/branches", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+
+	all := []Branch{}
+	err := s.api.serverPages(req).All(ctx, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// ListUserPermissions returns the users granted a permission on the repository.
+func (s *serverRepoService) ListUserPermissions(ctx context.Context, opts GetRepoOpts) ([]UserPermission, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+
+	all := []UserPermission{}
+	err := s.api.serverPages(req).All(ctx, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// ListGroupPermissions returns the groups granted a permission on the repository.
+func (s *serverRepoService) ListGroupPermissions(ctx context.Context, opts GetRepoOpts) ([]GroupPermission, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/permissions/groups", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+
+	all := []GroupPermission{}
+	err := s.api.serverPages(req).All(ctx, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+type ArchiveOpts struct {
+	ProjectKey string
+	RepoSlug   string
+	// At is the ref (i.e. refs/heads/main) to archive.
+	At string
+	// Format is one of ArchiveFormatZip or ArchiveFormatTgz.
+	Format string
+}
+
+// Archive streams an archive of the repository content at the given ref.
+// The download is neither retried nor bounded by the call timeout: if it
+// fails, what was written to w must be discarded.
+func (s *serverRepoService) Archive(ctx context.Context, opts ArchiveOpts, w io.Writer) error {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/archive", opts.ProjectKey, opts.RepoSlug).
+		expect(200).
+		streaming()
+	req.Builder.
+		Param("at", opts.At).
+		Param("format", opts.Format).
+		ToWriter(w)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
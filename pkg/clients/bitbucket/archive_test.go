@@ -0,0 +1,104 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRepoArchive(t *testing.T) {
+	ctx := context.Background()
+	var at, format string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo/archive"; req.URL.Path != want {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		at, format = req.URL.Query().Get("at"), req.URL.Query().Get("format")
+		rw.Write([]byte("archive-content"))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	buf := &bytes.Buffer{}
+	err := NewClient(co).Repos().Archive(ctx, ArchiveOpts{
+		ProjectKey: "JXP",
+		RepoSlug:   "demo-repo",
+		At:         "refs/heads/main",
+		Format:     ArchiveFormatZip,
+	}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if at != "refs/heads/main" || format != ArchiveFormatZip {
+		t.Fatalf("unexpected params: at=%s, format=%s", at, format)
+	}
+	if got := buf.String(); got != "archive-content" {
+		t.Fatalf("unexpected content: %s", got)
+	}
+}
+
+func TestRepoArchiveInterrupted(t *testing.T) {
+	ctx := context.Background()
+	content := "archive-content"
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		rw.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if calls == 1 {
+			// the connection is closed after half of the body.
+			rw.Write([]byte(content[:len(content)/2]))
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		rw.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	events := []RetryEvent{}
+	cli := NewClient(&ClientOpts{
+		ApiBaseUrl:  server.URL,
+		HttpClient:  server.Client(),
+		CallTimeout: 10 * time.Millisecond,
+		Retry: RetryOpts{
+			BaseDelay: time.Millisecond,
+			OnRetry: func(ev RetryEvent) {
+				events = append(events, ev)
+			},
+		},
+	})
+	opts := ArchiveOpts{
+		ProjectKey: "JXP",
+		RepoSlug:   "demo-repo",
+		At:         "refs/heads/main",
+		Format:     ArchiveFormatZip,
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cli.Repos().Archive(ctx, opts, buf); err == nil {
+		t.Fatal("expected the interrupted download to fail")
+	}
+	if calls != 1 || len(events) != 0 {
+		t.Fatalf("the download must not be retried: %d calls, %d retries", calls, len(events))
+	}
+
+	// the next download outlasts the call timeout.
+	buf = &bytes.Buffer{}
+	if err := cli.Repos().Archive(ctx, opts, buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != content {
+		t.Fatalf("unexpected content: %s", got)
+	}
+}
@@ -0,0 +1,266 @@
+package bitbucket
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// Authenticator adds the credentials to the requests.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *Request) error
+}
+
+// refresher is an Authenticator whose credentials can be discarded when
+// Bitbucket rejects them, getting new ones for the next requests.
+type refresher interface {
+	Authenticator
+	invalidate()
+}
+
+// Authenticating adds the credentials of the authenticator to the requests;
+// the request Bitbucket rejects with an unauthorized error is sent once more
+// with new credentials, if the authenticator gets them from a token endpoint.
+func Authenticating(auth Authenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			send := func() (sent bool, err error) {
+				r := req.clone()
+				if err := auth.Authenticate(ctx, r); err != nil {
+					return false, err
+				}
+				return true, next(ctx, r)
+			}
+
+			sent, err := send()
+			if ref, ok := auth.(refresher); ok && sent && errors.Is(err, ErrUnauthorized) {
+				ref.invalidate()
+				_, err = send()
+			}
+			return err
+		}
+	}
+}
+
+// BasicAuth sends the username and the password (i.e. an app password).
+func BasicAuth(username, password string) Authenticator {
+	return basicAuth{username: username, password: password}
+}
+
+type basicAuth struct {
+	username, password string
+}
+
+func (a basicAuth) Authenticate(ctx context.Context, req *Request) error {
+	req.Builder.BasicAuth(a.username, a.password)
+	return nil
+}
+
+// BearerToken sends the token as a bearer one (i.e. an access token).
+func BearerToken(token string) Authenticator {
+	return bearerToken(token)
+}
+
+type bearerToken string
+
+func (a bearerToken) Authenticate(ctx context.Context, req *Request) error {
+	req.Builder.Bearer(string(a))
+	return nil
+}
+
+// Grant types and token types of the token endpoints.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// CloudTokenUrl is the token endpoint of Bitbucket Cloud.
+const CloudTokenUrl = "https://bitbucket.org/site/oauth2/access_token"
+
+// ServerTokenPath is the path of the token endpoint of Bitbucket Server.
+const ServerTokenPath = "/rest/oauth2/latest/token"
+
+// tokenExpiryDelta is how long before their expiry the tokens are refreshed.
+const tokenExpiryDelta = 30 * time.Second
+
+// OAuth2Opts configures the OAuth 2.0 client credentials grant.
+type OAuth2Opts struct {
+	TokenUrl     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HttpClient sends the requests to the token endpoint (default: http.DefaultClient).
+	HttpClient *http.Client
+}
+
+// OAuth2ClientCredentials sends the access tokens got from the token endpoint
+// with the client credentials of an OAuth 2.0 consumer (i.e. an app link).
+func OAuth2ClientCredentials(opts OAuth2Opts) Authenticator {
+	form := url.Values{"grant_type": {GrantClientCredentials}}
+	if len(opts.Scopes) > 0 {
+		form.Set("scope", strings.Join(opts.Scopes, " "))
+	}
+
+	return cachedTokens(opts.HttpClient, opts.TokenUrl, form, opts.ClientID, opts.ClientSecret)
+}
+
+// TokenExchangeOpts configures the OAuth 2.0 token exchange (RFC 8693).
+type TokenExchangeOpts struct {
+	TokenUrl string
+	// SubjectToken is exchanged for the access token, i.e. a service account token.
+	SubjectToken string
+	// SubjectTokenType is the type of the subject token (default: TokenTypeJWT).
+	SubjectTokenType string
+	Audience         string
+	Scopes           []string
+	// HttpClient sends the requests to the token endpoint (default: http.DefaultClient).
+	HttpClient *http.Client
+}
+
+// TokenExchange sends the short-lived access tokens the token
+// endpoint exchanges for the subject token.
+func TokenExchange(opts TokenExchangeOpts) Authenticator {
+	typ := opts.SubjectTokenType
+	if len(typ) == 0 {
+		typ = TokenTypeJWT
+	}
+
+	form := url.Values{
+		"grant_type":           {GrantTokenExchange},
+		"subject_token":        {opts.SubjectToken},
+		"subject_token_type":   {typ},
+		"requested_token_type": {TokenTypeAccessToken},
+	}
+	if len(opts.Audience) > 0 {
+		form.Set("audience", opts.Audience)
+	}
+	if len(opts.Scopes) > 0 {
+		form.Set("scope", strings.Join(opts.Scopes, " "))
+	}
+
+	return cachedTokens(opts.HttpClient, opts.TokenUrl, form, "", "")
+}
+
+// tokens caches the access tokens by token endpoint and grant, since the
+// clients are built for every reconciliation; the secrets are hashed in the keys.
+var tokens sync.Map
+
+// cachedTokens returns the tokenSource of the grant, shared by the clients.
+func cachedTokens(client *http.Client, tokenUrl string, form url.Values, clientID, clientSecret string) *tokenSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s", tokenUrl, clientID, clientSecret, form.Encode())
+	key := hex.EncodeToString(h.Sum(nil))
+
+	src, _ := tokens.LoadOrStore(key, &tokenSource{})
+	res := src.(*tokenSource)
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.fetch = func(ctx context.Context) (*tokenResponse, error) {
+		rb := requests.URL(tokenUrl).
+			Client(client).
+			BodyForm(form).
+			Accept("application/json").
+			AddValidator(tokenErrorHandler)
+		if len(clientID) > 0 {
+			rb.BasicAuth(clientID, clientSecret)
+		}
+
+		var tok tokenResponse
+		err := rb.ToJSON(&tok).Fetch(ctx)
+		if err == nil && len(tok.AccessToken) == 0 {
+			err = fmt.Errorf("no access token returned by %s", tokenUrl)
+		}
+		return &tok, err
+	}
+	return res
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type,omitempty"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+}
+
+// tokenSource gets the access tokens from a token endpoint, refreshing them
+// shortly before their expiry; a token without expiry is used until rejected.
+type tokenSource struct {
+	mu     sync.Mutex
+	fetch  func(ctx context.Context) (*tokenResponse, error)
+	token  string
+	expiry time.Time
+}
+
+func (s *tokenSource) Authenticate(ctx context.Context, req *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.token) == 0 || (!s.expiry.IsZero() && time.Now().Add(tokenExpiryDelta).After(s.expiry)) {
+		tok, err := s.fetch(ctx)
+		if err != nil {
+			return err
+		}
+
+		s.token, s.expiry = tok.AccessToken, time.Time{}
+		if tok.ExpiresIn > 0 {
+			s.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+		}
+	}
+
+	req.Builder.Bearer(s.token)
+	return nil
+}
+
+func (s *tokenSource) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// tokenErrorHandler fails the responses of the token endpoint with the
+// OAuth 2.0 error; the rejected credentials are reported as unauthorized,
+// the throttled and unavailable endpoint with its status to retry the call.
+func tokenErrorHandler(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	var ex struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if data, err := io.ReadAll(res.Body); err == nil {
+		_ = json.Unmarshal(data, &ex)
+	}
+
+	msg := fmt.Sprintf("cannot get an access token: unexpected status: %d", res.StatusCode)
+	if len(ex.Error) > 0 {
+		msg = fmt.Sprintf("cannot get an access token: %s", ex.Error)
+		if len(ex.Description) > 0 {
+			msg = fmt.Sprintf("%s: %s", msg, ex.Description)
+		}
+	}
+
+	code := res.StatusCode
+	if code >= 400 && code < 500 && code != http.StatusTooManyRequests {
+		code = http.StatusUnauthorized
+	}
+	return StatusError{Code: code, Inner: errors.New(msg), RetryAfter: retryAfter(res.Header)}
+}
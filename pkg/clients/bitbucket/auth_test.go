@@ -0,0 +1,159 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	issued := 0
+	tokens := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if id, secret, ok := req.BasicAuth(); !ok || id != "provider" || secret != "s3cr3t" {
+			t.Errorf("expected the client credentials, got %q", req.Header.Get("Authorization"))
+		}
+		if err := req.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if got := req.PostForm.Get("grant_type"); got != GrantClientCredentials {
+			t.Errorf("unexpected grant type: %s", got)
+		}
+		if got := req.PostForm.Get("scope"); got != "repository:admin project" {
+			t.Errorf("unexpected scope: %s", got)
+		}
+		issued++
+		fmt.Fprintf(rw, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, issued)
+	}))
+	defer tokens.Close()
+
+	auths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		auths = append(auths, req.Header.Get("Authorization"))
+		// The first token is revoked after the first call.
+		if len(auths) > 1 && req.Header.Get("Authorization") == "Bearer token-1" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Write([]byte(`{"slug":"demo-repo","project":{"key":"JXP"}}`))
+	}))
+	defer server.Close()
+
+	newRepos := func() RepoService {
+		return NewClient(&ClientOpts{
+			ApiBaseUrl: server.URL,
+			HttpClient: server.Client(),
+			Auth: OAuth2ClientCredentials(OAuth2Opts{
+				TokenUrl:     tokens.URL,
+				ClientID:     "provider",
+				ClientSecret: "s3cr3t",
+				Scopes:       []string{"repository:admin", "project"},
+				HttpClient:   tokens.Client(),
+			}),
+		}).Repos()
+	}
+
+	// The clients of the same credentials share the token, until rejected.
+	for i := 0; i < 3; i++ {
+		if _, err := newRepos().Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"Bearer token-1", "Bearer token-1", "Bearer token-2", "Bearer token-2"}
+	if !reflect.DeepEqual(auths, want) || issued != 2 {
+		t.Fatalf("expected %v with 2 tokens issued, got %v with %d", want, auths, issued)
+	}
+}
+
+func TestOAuth2TokenRefresh(t *testing.T) {
+	issued := 0
+	tokens := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		issued++
+		// The tokens expire within the refresh delta.
+		fmt.Fprintf(rw, `{"access_token":"token-%d","expires_in":10}`, issued)
+	}))
+	defer tokens.Close()
+
+	auth := OAuth2ClientCredentials(OAuth2Opts{TokenUrl: tokens.URL, ClientID: "provider", ClientSecret: "s3cr3t"})
+	for i := 1; i <= 2; i++ {
+		req := &Request{Builder: (&api{}).newRequest(http.MethodGet, "/").Builder}
+		if err := auth.Authenticate(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		r, err := req.Builder.Request(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Header.Get("Authorization"), fmt.Sprintf("Bearer token-%d", i); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestTokenExchange(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if req.PostForm.Get("subject_token") == "expired" {
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(`{"error":"invalid_grant","error_description":"The subject token is expired."}`))
+			return
+		}
+		want := map[string]string{
+			"grant_type":           GrantTokenExchange,
+			"subject_token":        "sa-token",
+			"subject_token_type":   TokenTypeJWT,
+			"requested_token_type": TokenTypeAccessToken,
+			"audience":             "bitbucket",
+		}
+		for k, v := range want {
+			if got := req.PostForm.Get(k); got != v {
+				t.Errorf("expected %s=%s, got %s", k, v, got)
+			}
+		}
+		rw.Write([]byte(`{"access_token":"exchanged","token_type":"Bearer"}`))
+	}))
+	defer tokens.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Authorization"); got != "Bearer exchanged" {
+			t.Errorf("expected the exchanged token, got %s", got)
+		}
+		rw.Write([]byte(`{"slug":"demo-repo","project":{"key":"JXP"}}`))
+	}))
+	defer server.Close()
+
+	get := func(subjectToken string) error {
+		_, err := NewClient(&ClientOpts{
+			ApiBaseUrl: server.URL,
+			HttpClient: server.Client(),
+			Retry:      RetryOpts{MaxAttempts: 1},
+			Auth: TokenExchange(TokenExchangeOpts{
+				TokenUrl:     tokens.URL,
+				SubjectToken: subjectToken,
+				Audience:     "bitbucket",
+				HttpClient:   tokens.Client(),
+			}),
+		}).Repos().Get(context.Background(), GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+		return err
+	}
+
+	if err := get("sa-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := get("expired")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+	if want := "cannot get an access token: invalid_grant: The subject token is expired."; err.Error() != want {
+		t.Fatalf("expected [%s], got [%s]", want, err.Error())
+	}
+}
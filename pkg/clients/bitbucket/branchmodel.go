@@ -0,0 +1,112 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	BranchModelScopeProject    = "PROJECT"
+	BranchModelScopeRepository = "REPOSITORY"
+)
+
+type BranchModelBranch struct {
+	RefId      string `json:"refId,omitempty"`
+	UseDefault bool   `json:"useDefault"`
+}
+
+type BranchModelType struct {
+	Id          string `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+	Prefix      string `json:"prefix,omitempty"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type BranchModelConfiguration struct {
+	Development *BranchModelBranch `json:"development"`
+	Production  *BranchModelBranch `json:"production"`
+	Types       []BranchModelType  `json:"types"`
+	Scope       *struct {
+		Type       string `json:"type"`
+		ResourceId int    `json:"resourceId"`
+	} `json:"scope,omitempty"`
+}
+
+// BranchModelService provides methods for managing
+// the branching model of projects and repositories.
+type BranchModelService struct {
+	api *api
+}
+
+type BranchModelOpts struct {
+	ProjectKey string
+	// RepoSlug is optional: when empty the branching model is managed at project level.
+	RepoSlug string
+}
+
+// route returns the path format of the branching model and its args.
+func (o BranchModelOpts) route() (string, []interface{}) {
+	if len(o.RepoSlug) == 0 {
+		return "/rest/branch-utils/latest/projects/%s/branchmodel/configuration", []interface{}{o.ProjectKey}
+	}
+	return "/rest/branch-utils/latest/projects/%s/repos/%s/branchmodel/configuration", []interface{}{o.ProjectKey, o.RepoSlug}
+}
+
+// Get returns the branching model configuration or nil if not configured.
+func (s *BranchModelService) Get(ctx context.Context, opts BranchModelOpts) (*BranchModelConfiguration, error) {
+	resp := &BranchModelConfiguration{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route, args...).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Update replaces the branching model configuration.
+func (s *BranchModelService) Update(ctx context.Context, opts BranchModelOpts, cfg BranchModelConfiguration) (*BranchModelConfiguration, error) {
+	resp := &BranchModelConfiguration{}
+
+	cfg.Scope = nil
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPut, route, args...).
+		expect(200)
+	req.Builder.
+		BodyJSON(cfg).
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Delete removes the branching model configuration; a repository
+// will then inherit the project one.
+func (s *BranchModelService) Delete(ctx context.Context, opts BranchModelOpts) error {
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodDelete, route, args...).
+		expect(200, 204)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBranchModelUpdate(t *testing.T) {
+	ctx := context.Background()
+	var got map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/branch-utils/latest/projects/JXP/repos/demo-repo/branchmodel/configuration"; req.URL.Path != want {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Write([]byte(`{"development":{"refId":"refs/heads/develop","useDefault":false},"production":null,"types":[],"scope":{"type":"REPOSITORY","resourceId":1}}`))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	cfg := BranchModelConfiguration{
+		Development: &BranchModelBranch{RefId: "refs/heads/develop"},
+		Types:       []BranchModelType{{Id: "FEATURE", Prefix: "feature/", Enabled: true}},
+	}
+	res, err := NewClient(co).BranchModels().Update(ctx, BranchModelOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := got["scope"]; ok {
+		t.Fatalf("scope must not be sent")
+	}
+	if res.Scope == nil || res.Scope.Type != BranchModelScopeRepository {
+		t.Fatalf("unexpected scope: %+v", res.Scope)
+	}
+}
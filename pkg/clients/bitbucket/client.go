@@ -1,52 +1,137 @@
 package bitbucket
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"mime/multipart"
+	"io"
 	"net/http"
-	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
 
-	"github.com/carlmjohnson/requests"
+// Bitbucket flavors.
+const (
+	FlavorServer = "server"
+	FlavorCloud  = "cloud"
 )
 
+// ErrNotSupported is returned by the operations a Bitbucket flavor does not provide.
+var ErrNotSupported = errors.New("not supported by this Bitbucket flavor")
+
 type ClientOpts struct {
 	ApiBaseUrl string
 	Username   string
 	Token      string
+	// Auth adds the credentials to the calls (default: BasicAuth with the
+	// username and the token if the username is set, otherwise BearerToken).
+	Auth       Authenticator
 	HttpClient *http.Client
+	// Flavor is one of FlavorServer (default) or FlavorCloud.
+	Flavor string
+	// Workspace owns the repositories on Bitbucket Cloud.
+	Workspace string
+	// CallTimeout bounds every attempt of a call, within the deadline
+	// of the context it is made with (default: DefaultCallTimeout).
+	CallTimeout time.Duration
+	// Retry controls the retries of the calls failing for transient reasons.
+	Retry RetryOpts
+	// Paging controls how the list calls walk the pages of the results.
+	Paging PageOpts
+	// Middlewares wrap every call, in order, the first being the outermost;
+	// they run before the retries, the credentials and the error decoding.
+	Middlewares []Middleware
+}
+
+// DefaultCallTimeout is the default timeout of a Bitbucket call.
+const DefaultCallTimeout = 30 * time.Second
+
+// Interface is the Bitbucket client used by the controllers; it is implemented
+// by Client and by the in-memory fake Bitbucket of the fake package.
+type Interface interface {
+	Flavor() string
+	Repos() RepoService
+	Permissions() PermissionService
+	Hooks() *HookService
+	RequiredBuilds() *RequiredBuildsService
+	BranchModels() *BranchModelService
+	PullRequests() *PullRequestService
 }
 
 // Client is a tiny Github client
 type Client struct {
 	apiBaseUrl string
 	httpClient *http.Client
-	repos      *RepoService
+	flavor     string
+	repos      RepoService
+	perms      PermissionService
+	hooks      *HookService
+	builds     *RequiredBuildsService
+	models     *BranchModelService
+	pulls      *PullRequestService
 }
 
 // NewClient returns a new Github Client
-func NewClient(opts *ClientOpts) *Client {
+func NewClient(opts *ClientOpts) Interface {
 	res := &Client{
 		apiBaseUrl: opts.ApiBaseUrl,
 		httpClient: opts.HttpClient,
+		flavor:     opts.Flavor,
 	}
 
-	res.repos = &RepoService{
-		client:     res.httpClient,
-		apiBaseUrl: res.apiBaseUrl,
-		username:   opts.Username,
-		token:      opts.Token,
+	api := newAPI(opts)
+
+	if res.flavor == FlavorCloud {
+		repos := &cloudRepoService{api: api, workspace: opts.Workspace}
+		res.repos, res.perms = repos, repos
+	} else {
+		repos := &serverRepoService{api: api}
+		res.repos, res.perms = repos, repos
 	}
 
+	res.hooks = &HookService{api: api}
+	res.builds = &RequiredBuildsService{api: api}
+	res.models = &BranchModelService{api: api}
+	res.pulls = &PullRequestService{api: api}
+
 	return res
 }
 
-func (c *Client) Repos() *RepoService {
+// Flavor returns the Bitbucket flavor the client talks to.
+func (c *Client) Flavor() string {
+	if c.flavor == FlavorCloud {
+		return FlavorCloud
+	}
+	return FlavorServer
+}
+
+// Repos returns the repositories service of the client flavor.
+func (c *Client) Repos() RepoService {
 	return c.repos
 }
 
+// Permissions returns the repository permissions service of the client flavor.
+func (c *Client) Permissions() PermissionService {
+	return c.perms
+}
+
+func (c *Client) Hooks() *HookService {
+	return c.hooks
+}
+
+func (c *Client) RequiredBuilds() *RequiredBuildsService {
+	return c.builds
+}
+
+func (c *Client) BranchModels() *BranchModelService {
+	return c.models
+}
+
+func (c *Client) PullRequests() *PullRequestService {
+	return c.pulls
+}
+
 type Repository struct {
 	Name        string `json:"name"`
 	ScmId       string `json:"scmId,omitempty"`
@@ -54,18 +139,63 @@ type Repository struct {
 	Description string `json:"description,omitempty"`
 	State       string `json:"state,omitempty"`
 	Public      bool   `json:"public"`
+	Archived    bool   `json:"archived,omitempty"`
 	Project     struct {
 		Key  string `json:"key"`
 		Name string `json:"name"`
 	} `json:"project"`
+	Origin *Repository `json:"origin,omitempty"`
 }
 
-// RepoService provides methods for creating repositories.
-type RepoService struct {
-	client     *http.Client
-	apiBaseUrl string
-	username   string
-	token      string
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// Slug returns the slug Bitbucket assigns to a repository name.
+func Slug(name string) string {
+	return slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// RepoService provides methods for managing repositories and their
+// content; it is implemented for both Bitbucket Server and Cloud.
+type RepoService interface {
+	Get(ctx context.Context, opts GetRepoOpts) (*Repository, error)
+	Create(ctx context.Context, opts CreateRepoOpts) (*Repository, error)
+	Update(ctx context.Context, projectKey, slug string, opts UpdateRepoOpts) (*Repository, error)
+	List(ctx context.Context, projectKey string) ([]Repository, error)
+	Init(ctx context.Context, opts RepoInitOpts) error
+	Delete(ctx context.Context, projectKey, slug string) error
+
+	Fork(ctx context.Context, opts ForkRepoOpts) (*Repository, error)
+	GetRefSync(ctx context.Context, opts GetRepoOpts) (*RefSyncStatus, error)
+	SetRefSync(ctx context.Context, opts GetRepoOpts, enabled bool) (*RefSyncStatus, error)
+
+	Labels(ctx context.Context, opts GetRepoOpts) ([]string, error)
+	AddLabel(ctx context.Context, opts GetRepoOpts, name string) error
+
+	Branches(ctx context.Context, opts GetRepoOpts) ([]Branch, error)
+	Archive(ctx context.Context, opts ArchiveOpts, w io.Writer) error
+
+	GetRaw(ctx context.Context, opts FileOpts) ([]byte, error)
+	LastCommit(ctx context.Context, opts FileOpts) (*Commit, error)
+	CommitFile(ctx context.Context, opts CommitFileOpts) (*Commit, error)
+}
+
+// PermissionService provides methods for managing the users and groups
+// permissions of repositories; it is implemented for both Bitbucket Server and Cloud.
+type PermissionService interface {
+	SetUserPermissions(ctx context.Context, opts UserPermissionOpts) error
+	GetUserPermissions(ctx context.Context, opts UserPermissionOpts) (*UserPermission, error)
+	DeleteUserPermissions(ctx context.Context, opts UserPermissionOpts) error
+	ListUserPermissions(ctx context.Context, opts GetRepoOpts) ([]UserPermission, error)
+
+	SetGroupPermissions(ctx context.Context, opts GroupPermissionOpts) error
+	GetGroupPermissions(ctx context.Context, opts GroupPermissionOpts) (*GroupPermission, error)
+	DeleteGroupPermissions(ctx context.Context, opts GroupPermissionOpts) error
+	ListGroupPermissions(ctx context.Context, opts GetRepoOpts) ([]GroupPermission, error)
+}
+
+// serverRepoService implements RepoService and PermissionService for Bitbucket Server.
+type serverRepoService struct {
+	api *api
 }
 
 type CreateRepoOpts struct {
@@ -81,29 +211,17 @@ type GetRepoOpts struct {
 }
 
 // https://docs.atlassian.com/bitbucket-server/rest/7.6.13/bitbucket-rest.html#idp175
-func (s *RepoService) Get(opts GetRepoOpts) (*Repository, error) {
+func (s *serverRepoService) Get(ctx context.Context, opts GetRepoOpts) (*Repository, error) {
 	resp := &Repository{}
 
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodGet).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s", opts.ProjectKey, opts.RepoSlug).
-		Client(s.client).
-		AddValidator(ErrorHandler(200)).
-		ToJSON(resp)
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
-	}
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+	req.Builder.ToJSON(resp)
 
-	err := builder.Fetch(context.Background())
+	err := s.api.do(ctx, req)
 	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			if e.Code == 404 {
-				return nil, nil
-			}
-			return nil, fmt.Errorf(e.Error())
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
 		}
 		return nil, err
 	}
@@ -112,114 +230,133 @@ func (s *RepoService) Get(opts GetRepoOpts) (*Repository, error) {
 }
 
 // https://docs.atlassian.com/bitbucket-server/rest/7.6.13/bitbucket-rest.html#idp174
-func (s *RepoService) Create(opts CreateRepoOpts) (*Repository, error) {
+func (s *serverRepoService) Create(ctx context.Context, opts CreateRepoOpts) (*Repository, error) {
 	if opts.DefaultBranch == "" {
 		opts.DefaultBranch = "main"
 	}
 
 	resp := &Repository{}
 
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodPost).
-		Pathf("/rest/api/1.0/projects/%s/repos", opts.ProjectKey).
-		Client(s.client).
+	// A failed attempt may have created the repository.
+	check := func(ctx context.Context) (bool, error) {
+		all, err := s.list(ctx, opts.ProjectKey, true)
+		for i := range all {
+			if all[i].Name == opts.Name {
+				*resp = all[i]
+				return true, nil
+			}
+		}
+		return false, err
+	}
+
+	req := s.api.newRequest(http.MethodPost, "/rest/api/1.0/projects/%s/repos", opts.ProjectKey).
+		expect(201)
+	req.Builder.
 		BodyJSON(map[string]interface{}{
 			"name":          opts.Name,
 			"public":        opts.Public,
 			"defaultBranch": opts.DefaultBranch,
 		}).
-		AddValidator(ErrorHandler(201)).
 		ToJSON(resp)
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
-	}
-	err := builder.Fetch(context.Background())
+	err := s.api.do(ctx, req.checking(check))
 	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			return nil, fmt.Errorf(e.Error())
-		}
 		return nil, err
 	}
 
 	return resp, nil
 }
 
-type RepoInitOpts struct {
-	ProjectKey string
-	RepoSlug   string
-	Title      string
+type UpdateRepoOpts struct {
+	// Name renames the repository.
+	Name *string
+	// ProjectKey moves the repository into another project.
+	ProjectKey *string
+	// Archived archives or unarchives the repository.
+	Archived *bool
 }
 
-func (s *RepoService) Init(opts RepoInitOpts) error {
-	bodyBuf := &bytes.Buffer{}
-	bodyWriter := multipart.NewWriter(bodyBuf)
-	bodyWriter.WriteField("message", "first commit")
-	bodyWriter.WriteField("branch", "main")
+// Update changes the repository; only the options set are sent.
+func (s *serverRepoService) Update(ctx context.Context, projectKey, slug string, opts UpdateRepoOpts) (*Repository, error) {
+	body := map[string]interface{}{}
+	if opts.Name != nil {
+		body["name"] = *opts.Name
+	}
+	if opts.ProjectKey != nil {
+		body["project"] = map[string]string{"key": *opts.ProjectKey}
+	}
+	if opts.Archived != nil {
+		body["archived"] = *opts.Archived
+	}
+
+	resp := &Repository{}
 
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", `form-data; name="content"; filename="README.md"`)
-	h.Set("Content-Type", "application/octet-stream")
-	part, err := bodyWriter.CreatePart(h)
+	req := s.api.newRequest(http.MethodPut, "/rest/api/1.0/projects/%s/repos/%s", projectKey, slug).
+		expect(200, 201)
+	req.Builder.
+		BodyJSON(body).
+		ToJSON(resp)
+	err := s.api.do(ctx, req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if opts.Title == "" {
-		opts.Title = opts.RepoSlug
-	}
-	content := []byte(fmt.Sprintf("# %s", opts.Title))
-	if _, err := part.Write(content); err != nil {
-		return err
-	}
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
-
-	//var res string
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodPut).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s/browse/README.md", opts.ProjectKey, opts.RepoSlug).
-		Client(s.client).
-		ContentType(contentType).
-		BodyBytes(bodyBuf.Bytes())
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
+	return resp, nil
+}
+
+// List returns all the repositories of a project.
+func (s *serverRepoService) List(ctx context.Context, projectKey string) ([]Repository, error) {
+	return s.list(ctx, projectKey, false)
+}
+
+// list returns the repositories of a project, all of them for a lookup.
+func (s *serverRepoService) list(ctx context.Context, projectKey string, lookup bool) ([]Repository, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos", projectKey).
+		expect(200)
+	if lookup {
+		req.unbounded()
 	}
-	err = builder.Fetch(context.Background())
+
+	all := []Repository{}
+	err := s.api.serverPages(req).All(ctx, &all)
 	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			return err
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
-	return nil
+	return all, nil
 }
 
-func (s *RepoService) Delete(projectKey, slug string) error {
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodDelete).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s", projectKey, slug).
-		Client(s.client).
-		AddValidator(ErrorHandler(200, 202, 204))
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
+type RepoInitOpts struct {
+	ProjectKey string
+	RepoSlug   string
+	Title      string
+}
+
+func (s *serverRepoService) Init(ctx context.Context, opts RepoInitOpts) error {
+	if opts.Title == "" {
+		opts.Title = opts.RepoSlug
 	}
-	err := builder.Fetch(context.Background())
+
+	_, err := s.CommitFile(ctx, CommitFileOpts{
+		ProjectKey: opts.ProjectKey,
+		RepoSlug:   opts.RepoSlug,
+		Path:       "README.md",
+		Branch:     "main",
+		Message:    "first commit",
+		Content:    []byte(fmt.Sprintf("# %s", opts.Title)),
+	})
+	return err
+}
+
+func (s *serverRepoService) Delete(ctx context.Context, projectKey, slug string) error {
+	req := s.api.newRequest(http.MethodDelete, "/rest/api/1.0/projects/%s/repos/%s", projectKey, slug).
+		expect(200, 202, 204)
+	err := s.api.do(ctx, req)
 	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			if e.Code == 404 {
-				return nil
-			}
-			return fmt.Errorf(e.Error())
+		if errors.Is(err, ErrNotFound) {
+			return nil
 		}
 		return err
 	}
@@ -235,27 +372,17 @@ type UserPermissionOpts struct {
 }
 
 // https://docs.atlassian.com/bitbucket-server/rest/7.6.13/bitbucket-rest.html#idp286
-func (s *RepoService) SetUserPermissions(opts UserPermissionOpts) error {
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodPut).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
-		Param("name", opts.User).Param("permission", opts.Permission).
-		Client(s.client).
-		AddValidator(ErrorHandler(200, 204))
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
-	}
+func (s *serverRepoService) SetUserPermissions(ctx context.Context, opts UserPermissionOpts) error {
+	req := s.api.newRequest(http.MethodPut, "/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
+		expect(200, 204)
+	req.Builder.
+		Param("name", opts.User).
+		Param("permission", opts.Permission)
 
-	err := builder.Fetch(context.Background())
+	err := s.api.do(ctx, req)
 	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			if e.Code == 404 {
-				return nil
-			}
-			return fmt.Errorf(e.Error())
+		if errors.Is(err, ErrNotFound) {
+			return nil
 		}
 		return err
 	}
@@ -266,67 +393,113 @@ func (s *RepoService) SetUserPermissions(opts UserPermissionOpts) error {
 type UserPermission struct {
 	User struct {
 		Name string `json:"name"`
-	}
+	} `json:"user"`
 	Permission string `json:"permission"`
 }
 
-func (s *RepoService) GetUserPermissions(opts UserPermissionOpts) (*UserPermission, error) {
-	res := struct {
-		Values []UserPermission `json:"values,omitempty"`
-	}{}
-
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodGet).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
-		Param("filter", opts.User).
-		Client(s.client).
-		AddValidator(ErrorHandler(200)).
-		ToJSON(&res)
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
+func (s *serverRepoService) GetUserPermissions(ctx context.Context, opts UserPermissionOpts) (*UserPermission, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
+		expect(200).
+		unbounded()
+	req.Builder.Param("filter", opts.User)
+
+	// The filter matches user names partially.
+	it := s.api.serverPages(req)
+	for it.Next(ctx) {
+		res := &UserPermission{}
+		if err := it.Decode(res); err != nil {
+			return nil, err
+		}
+		if res.User.Name == opts.User {
+			return res, nil
+		}
 	}
 
-	err := builder.Fetch(context.Background())
-	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			if e.Code == 404 {
-				return nil, nil
-			}
-			return nil, fmt.Errorf(e.Error())
+	if err := it.Err(); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
 		}
 		return nil, err
 	}
 
-	if len(res.Values) > 0 {
-		return &res.Values[0], nil
-	}
 	return nil, nil
 }
 
-func (s *RepoService) DeleteUserPermissions(opts UserPermissionOpts) error {
-	builder := requests.URL(s.apiBaseUrl).
-		Method(http.MethodDelete).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
-		Param("name", opts.User).
-		Client(s.client).
-		AddValidator(ErrorHandler(200, 204))
-	if len(s.username) > 0 {
-		builder = builder.BasicAuth(s.username, s.token)
-	} else {
-		builder = builder.Bearer(s.token)
+func (s *serverRepoService) DeleteUserPermissions(ctx context.Context, opts UserPermissionOpts) error {
+	req := s.api.newRequest(http.MethodDelete, "/rest/api/1.0/projects/%s/repos/%s/permissions/users", opts.ProjectKey, opts.RepoSlug).
+		expect(200, 204)
+	req.Builder.Param("name", opts.User)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
 	}
 
-	err := builder.Fetch(context.Background())
+	return nil
+}
+
+type GroupPermissionOpts struct {
+	ProjectKey string
+	RepoSlug   string
+	Group      string
+	Permission string
+}
+
+func (s *serverRepoService) SetGroupPermissions(ctx context.Context, opts GroupPermissionOpts) error {
+	req := s.api.newRequest(http.MethodPut, "/rest/api/1.0/projects/%s/repos/%s/permissions/groups", opts.ProjectKey, opts.RepoSlug).
+		expect(200, 204)
+	req.Builder.
+		Param("name", opts.Group).
+		Param("permission", opts.Permission)
+
+	err := s.api.do(ctx, req)
 	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			if e.Code == 404 {
-				return nil
-			}
-			return fmt.Errorf(e.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (s *serverRepoService) GetGroupPermissions(ctx context.Context, opts GroupPermissionOpts) (*GroupPermission, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/permissions/groups", opts.ProjectKey, opts.RepoSlug).
+		expect(200).
+		unbounded()
+	req.Builder.Param("filter", opts.Group)
+
+	// The filter matches group names partially.
+	it := s.api.serverPages(req)
+	for it.Next(ctx) {
+		res := &GroupPermission{}
+		if err := it.Decode(res); err != nil {
+			return nil, err
+		}
+		if res.Group.Name == opts.Group {
+			return res, nil
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *serverRepoService) DeleteGroupPermissions(ctx context.Context, opts GroupPermissionOpts) error {
+	req := s.api.newRequest(http.MethodDelete, "/rest/api/1.0/projects/%s/repos/%s/permissions/groups", opts.ProjectKey, opts.RepoSlug).
+		expect(200, 204)
+	req.Builder.Param("name", opts.Group)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
 		}
 		return err
 	}
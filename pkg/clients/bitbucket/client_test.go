@@ -1,30 +1,66 @@
 package bitbucket
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
-	"net/textproto"
 	"testing"
-
-	"github.com/carlmjohnson/requests"
+	"time"
 )
 
 func TestGetRepos(t *testing.T) {
+	ctx := context.Background()
+	dat, err := ioutil.ReadFile("../../../testdata/repo-create-ok.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var readme string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer Mjg1NjM3MzA2NDIy" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/rest/api/1.0/projects/JXP/repos":
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write(dat)
+		case req.Method == http.MethodPut && req.URL.Path == "/rest/api/1.0/projects/JXP/repos/test-repo-2/browse/README.md":
+			if err := req.ParseMultipartForm(1 << 20); err != nil || req.FormValue("branch") != "main" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f, _, err := req.FormFile("content")
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			defer f.Close()
+			b, _ := ioutil.ReadAll(f)
+			readme = string(b)
+			rw.Write([]byte(`{"id":"0123456789abcdef","displayId":"0123456789a"}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
 	co := &ClientOpts{
-		ApiBaseUrl: "http://10.99.99.37:7990",
-		Token:      "Mjg1NjM3MzA2NDIyOrGyf3Jw9/pUcoOFwy0uzQtsECox",
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Token:      "Mjg1NjM3MzA2NDIy",
 	}
 
 	repos := NewClient(co).Repos()
 
 	projectKey := "JXP"
-	res, err := repos.Create(CreateRepoOpts{
-		Name:       "Test Krateo 1",
+	res, err := repos.Create(ctx, CreateRepoOpts{
+		Name:       "test-repo-2",
 		Public:     false,
 		ProjectKey: projectKey,
 	})
@@ -32,46 +68,25 @@ func TestGetRepos(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	bodyBuf := &bytes.Buffer{}
-	bodyWriter := multipart.NewWriter(bodyBuf)
-	bodyWriter.WriteField("message", "first commit")
-	bodyWriter.WriteField("branch", "main")
-
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", `form-data; name="content"; filename="README.md"`)
-	h.Set("Content-Type", "application/octet-stream")
-	part, err := bodyWriter.CreatePart(h)
+	err = repos.Init(ctx, RepoInitOpts{
+		ProjectKey: projectKey,
+		RepoSlug:   res.Slug,
+		Title:      "Hello",
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err := part.Write([]byte(`# Hello`)); err != nil {
-		t.Fatal(err)
+
+	if res.Slug != "test-repo-2" || res.Project.Key != projectKey {
+		t.Fatalf("unexpected repo: %+v", res)
 	}
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
-
-	var s string
-	err = requests.URL(co.ApiBaseUrl).
-		Method(http.MethodPut).
-		Pathf("/rest/api/1.0/projects/%s/repos/%s/browse/README.md", projectKey, res.Slug).
-		Bearer(co.Token).
-		ContentType(contentType).
-		BodyBytes(bodyBuf.Bytes()).
-		ToString(&s).
-		//AddValidator(ErrorHandler(200)).
-		Fetch(context.Background())
-	if err != nil {
-		var e StatusError
-		if errors.As(err, &e) {
-			t.Fatal(e.Error())
-		}
-		t.Fatal(err)
+	if readme != "# Hello" {
+		t.Fatalf("unexpected README content: %s", readme)
 	}
-
-	t.Log(res)
 }
 
 func TestRepoAlreadyExists(t *testing.T) {
+	ctx := context.Background()
 	dat, err := ioutil.ReadFile("../../../testdata/repo-already-exists.json")
 	if err != nil {
 		t.Fatal(err)
@@ -89,7 +104,7 @@ func TestRepoAlreadyExists(t *testing.T) {
 	}
 
 	projectKey := ""
-	_, err = NewClient(co).Repos().Create(CreateRepoOpts{ProjectKey: projectKey, Name: "test-krateo-1"})
+	_, err = NewClient(co).Repos().Create(ctx, CreateRepoOpts{ProjectKey: projectKey, Name: "test-krateo-1"})
 	if err == nil {
 		t.Fatalf("expecting an error, got nil")
 	}
@@ -100,6 +115,7 @@ func TestRepoAlreadyExists(t *testing.T) {
 }
 
 func TestRepoCreateSuccess(t *testing.T) {
+	ctx := context.Background()
 	dat, err := ioutil.ReadFile("../../../testdata/repo-create-ok.json")
 	if err != nil {
 		t.Fatal(err)
@@ -117,8 +133,81 @@ func TestRepoCreateSuccess(t *testing.T) {
 	}
 
 	projectKey := "xxx"
-	_, err = NewClient(co).Repos().Create(CreateRepoOpts{ProjectKey: projectKey, Name: "test-krateo-1"})
+	_, err = NewClient(co).Repos().Create(ctx, CreateRepoOpts{ProjectKey: projectKey, Name: "test-krateo-1"})
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestRepoUpdateMove(t *testing.T) {
+	ctx := context.Background()
+	var got map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo"; req.URL.Path != want || req.Method != http.MethodPut {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte(`{"name":"demo-repo-20261018120000","slug":"demo-repo-20261018120000","project":{"key":"TRASH"}}`))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	name, trash := "demo-repo-20261018120000", "TRASH"
+	res, err := NewClient(co).Repos().Update(ctx, "JXP", "demo-repo", UpdateRepoOpts{Name: &name, ProjectKey: &trash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := got["archived"]; ok {
+		t.Fatalf("archived must not be sent")
+	}
+	if prj, ok := got["project"].(map[string]interface{}); !ok || prj["key"] != trash {
+		t.Fatalf("unexpected project: %v", got["project"])
+	}
+	if res.Project.Key != trash || res.Slug != name {
+		t.Fatalf("unexpected repo: %+v", res)
+	}
+}
+
+func TestCallDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	co := &ClientOpts{
+		ApiBaseUrl:  server.URL,
+		HttpClient:  server.Client(),
+		CallTimeout: 50 * time.Millisecond,
+		Retry:       RetryOpts{MaxAttempts: 1},
+	}
+
+	_, err := NewClient(co).Repos().Get(context.Background(), GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the call to time out, got %v", err)
+	}
+
+	co.CallTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = NewClient(co).Repos().Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the call to be canceled, got %v", err)
+	}
+}
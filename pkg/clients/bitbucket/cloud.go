@@ -0,0 +1,488 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// CloudApiUrl is the Bitbucket Cloud REST API base URL.
+const CloudApiUrl = "https://api.bitbucket.org"
+
+// cloudLabelPrefix prefixes the lines of the repository description
+// holding labels, since Bitbucket Cloud has no repository labels.
+const cloudLabelPrefix = "krateo-label: "
+
+// cloudRepoService implements RepoService and PermissionService for Bitbucket
+// Cloud; the project key of the options is the key of a project of the workspace.
+type cloudRepoService struct {
+	api       *api
+	workspace string
+}
+
+type cloudRepository struct {
+	Slug        string `json:"slug,omitempty"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Scm         string `json:"scm,omitempty"`
+	IsPrivate   bool   `json:"is_private"`
+	Project     *struct {
+		Key  string `json:"key"`
+		Name string `json:"name,omitempty"`
+	} `json:"project,omitempty"`
+	Parent *cloudRepository `json:"parent,omitempty"`
+}
+
+// repository converts the Cloud repository to the Server representation.
+func (r *cloudRepository) repository() *Repository {
+	desc, _ := splitLabels(r.Description)
+	res := &Repository{
+		Name:        r.Name,
+		ScmId:       r.Scm,
+		Slug:        r.Slug,
+		Description: desc,
+		State:       "AVAILABLE",
+		Public:      !r.IsPrivate,
+	}
+	if r.Project != nil {
+		res.Project.Key = r.Project.Key
+		res.Project.Name = r.Project.Name
+	}
+	if r.Parent != nil {
+		res.Origin = r.Parent.repository()
+	}
+	return res
+}
+
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-get
+func (s *cloudRepoService) Get(ctx context.Context, opts GetRepoOpts) (*Repository, error) {
+	resp := &cloudRepository{}
+
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s", s.workspace, opts.RepoSlug).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Repositories in other projects are not this one.
+	if resp.Project != nil && len(opts.ProjectKey) > 0 && resp.Project.Key != opts.ProjectKey {
+		return nil, nil
+	}
+
+	res := resp.repository()
+	// The parent is summarized, the origin project is known only getting it.
+	if resp.Parent != nil && len(resp.Parent.FullName) > 0 {
+		parts := strings.SplitN(resp.Parent.FullName, "/", 2)
+		if len(parts) == 2 && parts[0] == s.workspace {
+			origin, err := s.Get(ctx, GetRepoOpts{RepoSlug: parts[1]})
+			if err != nil {
+				return nil, err
+			}
+			if origin != nil {
+				res.Origin = origin
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-post
+func (s *cloudRepoService) Create(ctx context.Context, opts CreateRepoOpts) (*Repository, error) {
+	resp := &cloudRepository{}
+
+	// A failed attempt may have created the repository.
+	var created *Repository
+	check := func(ctx context.Context) (bool, error) {
+		repo, err := s.Get(ctx, GetRepoOpts{ProjectKey: opts.ProjectKey, RepoSlug: Slug(opts.Name)})
+		if err != nil || repo == nil {
+			return false, err
+		}
+		created = repo
+		return true, nil
+	}
+
+	req := s.api.newRequest(http.MethodPost, "/2.0/repositories/%s/%s", s.workspace, Slug(opts.Name)).
+		expect(200, 201)
+	req.Builder.
+		BodyJSON(map[string]interface{}{
+			"scm":        "git",
+			"name":       opts.Name,
+			"is_private": !opts.Public,
+			"project": map[string]string{
+				"key": opts.ProjectKey,
+			},
+		}).
+		ToJSON(resp)
+	err := s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return nil, err
+	}
+	if created != nil {
+		return created, nil
+	}
+
+	return resp.repository(), nil
+}
+
+// Update renames or moves the repository into another project of the
+// workspace; Bitbucket Cloud repositories cannot be archived.
+func (s *cloudRepoService) Update(ctx context.Context, projectKey, slug string, opts UpdateRepoOpts) (*Repository, error) {
+	if opts.Archived != nil {
+		return nil, fmt.Errorf("archiving repositories: %w", ErrNotSupported)
+	}
+
+	body := map[string]interface{}{}
+	if opts.Name != nil {
+		body["name"] = *opts.Name
+	}
+	if opts.ProjectKey != nil {
+		body["project"] = map[string]string{"key": *opts.ProjectKey}
+	}
+
+	resp := &cloudRepository{}
+
+	req := s.api.newRequest(http.MethodPut, "/2.0/repositories/%s/%s", s.workspace, slug).
+		expect(200, 201)
+	req.Builder.
+		BodyJSON(body).
+		ToJSON(resp)
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.repository(), nil
+}
+
+// List returns all the repositories of a project of the workspace.
+func (s *cloudRepoService) List(ctx context.Context, projectKey string) ([]Repository, error) {
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s", s.workspace).
+		expect(200)
+	req.Builder.Param("q", fmt.Sprintf(`project.key="%s"`, projectKey))
+
+	values := []cloudRepository{}
+	err := s.api.cloudPages(req).All(ctx, &values)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	all := make([]Repository, 0, len(values))
+	for i := range values {
+		all = append(all, *values[i].repository())
+	}
+
+	return all, nil
+}
+
+func (s *cloudRepoService) Init(ctx context.Context, opts RepoInitOpts) error {
+	if opts.Title == "" {
+		opts.Title = opts.RepoSlug
+	}
+
+	_, err := s.CommitFile(ctx, CommitFileOpts{
+		ProjectKey: opts.ProjectKey,
+		RepoSlug:   opts.RepoSlug,
+		Path:       "README.md",
+		Branch:     "main",
+		Message:    "first commit",
+		Content:    []byte(fmt.Sprintf("# %s", opts.Title)),
+	})
+	return err
+}
+
+func (s *cloudRepoService) Delete(ctx context.Context, projectKey, slug string) error {
+	req := s.api.newRequest(http.MethodDelete, "/2.0/repositories/%s/%s", s.workspace, slug).
+		expect(200, 202, 204)
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Fork creates a fork of the repository in the same workspace.
+func (s *cloudRepoService) Fork(ctx context.Context, opts ForkRepoOpts) (*Repository, error) {
+	resp := &cloudRepository{}
+
+	// A failed attempt may have created the fork.
+	var created *Repository
+	check := func(ctx context.Context) (bool, error) {
+		repo, err := s.Get(ctx, GetRepoOpts{ProjectKey: opts.TargetProjectKey, RepoSlug: Slug(opts.Name)})
+		if err != nil || repo == nil || repo.Origin == nil {
+			return false, err
+		}
+		created = repo
+		return true, nil
+	}
+
+	req := s.api.newRequest(http.MethodPost, "/2.0/repositories/%s/%s/forks", s.workspace, opts.RepoSlug).
+		expect(200, 201)
+	req.Builder.
+		BodyJSON(map[string]interface{}{
+			"name": opts.Name,
+			"workspace": map[string]string{
+				"slug": s.workspace,
+			},
+			"project": map[string]string{
+				"key": opts.TargetProjectKey,
+			},
+		}).
+		ToJSON(resp)
+	err := s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return nil, err
+	}
+	if created != nil {
+		return created, nil
+	}
+
+	res := resp.repository()
+	if res.Origin != nil && len(res.Origin.Project.Key) == 0 {
+		res.Origin.Project.Key = opts.ProjectKey
+	}
+	return res, nil
+}
+
+// GetRefSync always returns nil, since Bitbucket Cloud forks have no ref synchronization.
+func (s *cloudRepoService) GetRefSync(ctx context.Context, opts GetRepoOpts) (*RefSyncStatus, error) {
+	return nil, nil
+}
+
+func (s *cloudRepoService) SetRefSync(ctx context.Context, opts GetRepoOpts, enabled bool) (*RefSyncStatus, error) {
+	return nil, fmt.Errorf("fork ref synchronization: %w", ErrNotSupported)
+}
+
+// description returns the description of the repository, labels included.
+func (s *cloudRepoService) description(ctx context.Context, opts GetRepoOpts) (string, error) {
+	resp := &cloudRepository{}
+
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s", s.workspace, opts.RepoSlug).
+		expect(200)
+	req.Builder.
+		Param("fields", "description").
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Description, nil
+}
+
+// splitLabels returns the repository description without the
+// lines holding the labels, and the labels.
+func splitLabels(desc string) (string, []string) {
+	text, labels := []string{}, []string{}
+	for _, el := range strings.Split(desc, "\n") {
+		if strings.HasPrefix(el, cloudLabelPrefix) {
+			labels = append(labels, strings.TrimPrefix(el, cloudLabelPrefix))
+			continue
+		}
+		text = append(text, el)
+	}
+	return strings.TrimRight(strings.Join(text, "\n"), "\n"), labels
+}
+
+// Labels returns the labels of the repository, kept in its description.
+func (s *cloudRepoService) Labels(ctx context.Context, opts GetRepoOpts) ([]string, error) {
+	desc, err := s.description(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	_, labels := splitLabels(desc)
+	return labels, nil
+}
+
+// AddLabel applies a label to the repository adding a line to its description;
+// the description is read and written back, so an edit in between is lost.
+func (s *cloudRepoService) AddLabel(ctx context.Context, opts GetRepoOpts, name string) error {
+	desc, err := s.description(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	_, labels := splitLabels(desc)
+	for _, el := range labels {
+		if el == name {
+			return nil
+		}
+	}
+	if len(desc) > 0 {
+		desc += "\n"
+	}
+	desc += cloudLabelPrefix + name
+
+	req := s.api.newRequest(http.MethodPut, "/2.0/repositories/%s/%s", s.workspace, opts.RepoSlug).
+		expect(200)
+	req.Builder.BodyJSON(map[string]string{"description": desc})
+
+	err = s.api.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type cloudBranch struct {
+	Name   string `json:"name"`
+	Target struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+// Branches returns all the branches of the repository.
+func (s *cloudRepoService) Branches(ctx context.Context, opts GetRepoOpts) ([]Branch, error) {
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s/refs/branches", s.workspace, opts.RepoSlug).
+		expect(200)
+
+	values := []cloudBranch{}
+	err := s.api.cloudPages(req).All(ctx, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]Branch, 0, len(values))
+	for _, el := range values {
+		all = append(all, Branch{
+			Id:           "refs/heads/" + el.Name,
+			DisplayId:    el.Name,
+			LatestCommit: el.Target.Hash,
+		})
+	}
+
+	return all, nil
+}
+
+func (s *cloudRepoService) Archive(ctx context.Context, opts ArchiveOpts, w io.Writer) error {
+	return fmt.Errorf("repository archive download: %w", ErrNotSupported)
+}
+
+// GetRaw returns the raw content of the file or nil if it does not exists.
+func (s *cloudRepoService) GetRaw(ctx context.Context, opts FileOpts) ([]byte, error) {
+	at := opts.Branch
+	if len(at) == 0 {
+		at = "HEAD"
+	}
+
+	buf := &bytes.Buffer{}
+
+	req := s.api.newEscapedRequest(http.MethodGet, "/2.0/repositories/%s/%s/src/%s/%s",
+		url.PathEscape(s.workspace), url.PathEscape(opts.RepoSlug), url.PathEscape(at), escapePath(opts.Path)).
+		expect(200)
+	req.Builder.ToBytesBuffer(buf)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// LastCommit returns the latest commit modifying the file on
+// the branch, or nil if the file has never been committed.
+func (s *cloudRepoService) LastCommit(ctx context.Context, opts FileOpts) (*Commit, error) {
+	res := struct {
+		Values []struct {
+			Hash    string `json:"hash"`
+			Message string `json:"message,omitempty"`
+		} `json:"values,omitempty"`
+	}{}
+
+	req := s.api.newEscapedRequest(http.MethodGet, "/2.0/repositories/%s/%s/commits/%s",
+		url.PathEscape(s.workspace), url.PathEscape(opts.RepoSlug), url.PathEscape(opts.Branch)).
+		expect(200)
+	req.Builder.
+		Param("path", opts.Path).
+		ParamInt("pagelen", 1).
+		ToJSON(&res)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if len(res.Values) == 0 {
+		return nil, nil
+	}
+
+	el := res.Values[0]
+	return &Commit{Id: el.Hash, DisplayId: shortHash(el.Hash), Message: el.Message}, nil
+}
+
+// CommitFile creates or updates a file committing it on the branch; with a
+// SourceCommitId the commit is based on it, so concurrent edits are detected.
+func (s *cloudRepoService) CommitFile(ctx context.Context, opts CommitFileOpts) (*Commit, error) {
+	form := url.Values{}
+	form.Set(opts.Path, string(opts.Content))
+	form.Set("message", opts.Message)
+	form.Set("branch", opts.Branch)
+	if len(opts.SourceCommitId) > 0 {
+		form.Set("parents", opts.SourceCommitId)
+	}
+
+	headers := http.Header{}
+
+	req := s.api.newRequest(http.MethodPost, "/2.0/repositories/%s/%s/src", s.workspace, opts.RepoSlug).
+		expect(200, 201)
+	req.Builder.
+		BodyForm(form).
+		Handle(requests.ToHeaders(headers))
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The new commit is only reported by its location.
+	hash := path.Base(headers.Get("Location"))
+	return &Commit{Id: hash, DisplayId: shortHash(hash), Message: opts.Message}, nil
+}
+
+// escapePath escapes the segments of a file path.
+func escapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i := range segs {
+		segs[i] = url.PathEscape(segs[i])
+	}
+	return strings.Join(segs, "/")
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 11 {
+		return hash[:11]
+	}
+	return hash
+}
@@ -0,0 +1,209 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Bitbucket Server repository permissions, also accepted on Cloud.
+const (
+	PermissionRepoRead  = "REPO_READ"
+	PermissionRepoWrite = "REPO_WRITE"
+	PermissionRepoAdmin = "REPO_ADMIN"
+)
+
+var cloudPermissions = map[string]string{
+	PermissionRepoRead:  "read",
+	PermissionRepoWrite: "write",
+	PermissionRepoAdmin: "admin",
+}
+
+// toCloudPermission returns the Cloud permission matching the Server one.
+func toCloudPermission(perm string) string {
+	if res, ok := cloudPermissions[perm]; ok {
+		return res
+	}
+	return perm
+}
+
+// fromCloudPermission returns the Server permission matching the Cloud one.
+func fromCloudPermission(perm string) string {
+	for k, v := range cloudPermissions {
+		if v == perm {
+			return k
+		}
+	}
+	return perm
+}
+
+type cloudUserPermission struct {
+	Permission string `json:"permission"`
+	User       struct {
+		AccountId string `json:"account_id,omitempty"`
+		Uuid      string `json:"uuid,omitempty"`
+	} `json:"user"`
+}
+
+func (p *cloudUserPermission) userPermission() UserPermission {
+	res := UserPermission{Permission: fromCloudPermission(p.Permission)}
+	res.User.Name = p.User.AccountId
+	if len(res.User.Name) == 0 {
+		res.User.Name = p.User.Uuid
+	}
+	return res
+}
+
+type cloudGroupPermission struct {
+	Permission string `json:"permission"`
+	Group      struct {
+		Slug string `json:"slug"`
+	} `json:"group"`
+}
+
+func (p *cloudGroupPermission) groupPermission() GroupPermission {
+	res := GroupPermission{Permission: fromCloudPermission(p.Permission)}
+	res.Group.Name = p.Group.Slug
+	return res
+}
+
+// SetUserPermissions grants a permission to a user, identified
+// by account id or UUID, on the repository.
+func (s *cloudRepoService) SetUserPermissions(ctx context.Context, opts UserPermissionOpts) error {
+	req := s.api.newRequest(http.MethodPut, "/2.0/repositories/%s/%s/permissions-config/users/%s", s.workspace, opts.RepoSlug, opts.User).
+		expect(200, 201)
+	req.Builder.BodyJSON(map[string]string{
+		"permission": toCloudPermission(opts.Permission),
+	})
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *cloudRepoService) GetUserPermissions(ctx context.Context, opts UserPermissionOpts) (*UserPermission, error) {
+	resp := &cloudUserPermission{}
+
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s/permissions-config/users/%s", s.workspace, opts.RepoSlug, opts.User).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	res := resp.userPermission()
+	// Report the user as requested, be it the account id or the UUID.
+	res.User.Name = opts.User
+	return &res, nil
+}
+
+func (s *cloudRepoService) DeleteUserPermissions(ctx context.Context, opts UserPermissionOpts) error {
+	req := s.api.newRequest(http.MethodDelete, "/2.0/repositories/%s/%s/permissions-config/users/%s", s.workspace, opts.RepoSlug, opts.User).
+		expect(200, 204)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ListUserPermissions returns the users granted a permission on the repository.
+func (s *cloudRepoService) ListUserPermissions(ctx context.Context, opts GetRepoOpts) ([]UserPermission, error) {
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s/permissions-config/users", s.workspace, opts.RepoSlug).
+		expect(200)
+
+	values := []cloudUserPermission{}
+	err := s.api.cloudPages(req).All(ctx, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]UserPermission, 0, len(values))
+	for i := range values {
+		all = append(all, values[i].userPermission())
+	}
+
+	return all, nil
+}
+
+// SetGroupPermissions grants a permission to a group, identified by slug, on the repository.
+func (s *cloudRepoService) SetGroupPermissions(ctx context.Context, opts GroupPermissionOpts) error {
+	req := s.api.newRequest(http.MethodPut, "/2.0/repositories/%s/%s/permissions-config/groups/%s", s.workspace, opts.RepoSlug, opts.Group).
+		expect(200, 201)
+	req.Builder.BodyJSON(map[string]string{
+		"permission": toCloudPermission(opts.Permission),
+	})
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *cloudRepoService) GetGroupPermissions(ctx context.Context, opts GroupPermissionOpts) (*GroupPermission, error) {
+	resp := &cloudGroupPermission{}
+
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s/permissions-config/groups/%s", s.workspace, opts.RepoSlug, opts.Group).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	res := resp.groupPermission()
+	return &res, nil
+}
+
+func (s *cloudRepoService) DeleteGroupPermissions(ctx context.Context, opts GroupPermissionOpts) error {
+	req := s.api.newRequest(http.MethodDelete, "/2.0/repositories/%s/%s/permissions-config/groups/%s", s.workspace, opts.RepoSlug, opts.Group).
+		expect(200, 204)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ListGroupPermissions returns the groups granted a permission on the repository.
+func (s *cloudRepoService) ListGroupPermissions(ctx context.Context, opts GetRepoOpts) ([]GroupPermission, error) {
+	req := s.api.newRequest(http.MethodGet, "/2.0/repositories/%s/%s/permissions-config/groups", s.workspace, opts.RepoSlug).
+		expect(200)
+
+	values := []cloudGroupPermission{}
+	err := s.api.cloudPages(req).All(ctx, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]GroupPermission, 0, len(values))
+	for i := range values {
+		all = append(all, values[i].groupPermission())
+	}
+
+	return all, nil
+}
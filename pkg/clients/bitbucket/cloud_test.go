@@ -0,0 +1,296 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCloudClient(server *httptest.Server) Interface {
+	return NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Flavor:     FlavorCloud,
+		Workspace:  "acme",
+		Username:   "jdoe",
+		Token:      "app-password",
+	})
+}
+
+func TestCloudRepoGet(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "jdoe" || pass != "app-password" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch req.URL.Path {
+		case "/2.0/repositories/acme/demo-repo-fork":
+			rw.Write([]byte(`{"slug":"demo-repo-fork","name":"Demo Repo Fork","is_private":true,"project":{"key":"JXPF"},"parent":{"full_name":"acme/demo-repo"}}`))
+		case "/2.0/repositories/acme/demo-repo":
+			rw.Write([]byte(`{"slug":"demo-repo","name":"demo-repo","is_private":false,"project":{"key":"JXP"}}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"type":"error","error":{"message":"Repository not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	repos := newCloudClient(server).Repos()
+
+	res, err := repos.Get(ctx, GetRepoOpts{ProjectKey: "JXPF", RepoSlug: "demo-repo-fork"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Public || res.Project.Key != "JXPF" {
+		t.Fatalf("unexpected repo: %+v", res)
+	}
+	if res.Origin == nil || res.Origin.Project.Key != "JXP" || res.Origin.Slug != "demo-repo" {
+		t.Fatalf("unexpected origin: %+v", res.Origin)
+	}
+
+	res, err = repos.Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "missing"})
+	if err != nil || res != nil {
+		t.Fatalf("expected no repo, got %+v (%v)", res, err)
+	}
+
+	// The repository exists, but in another project.
+	res, err = repos.Get(ctx, GetRepoOpts{ProjectKey: "OTHER", RepoSlug: "demo-repo"})
+	if err != nil || res != nil {
+		t.Fatalf("expected no repo, got %+v (%v)", res, err)
+	}
+}
+
+func TestCloudRepoCreateAndList(t *testing.T) {
+	ctx := context.Background()
+	var created map[string]interface{}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/2.0/repositories/acme/my-repo":
+			json.NewDecoder(req.Body).Decode(&created)
+			rw.Write([]byte(`{"slug":"my-repo","name":"My Repo","is_private":true,"project":{"key":"JXP"}}`))
+		case req.URL.Path == "/2.0/repositories/acme" && req.URL.Query().Get("page") == "":
+			if q := req.URL.Query().Get("q"); q != `project.key="JXP"` {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(rw).Encode(map[string]interface{}{
+				"values": []map[string]interface{}{{"slug": "my-repo", "name": "My Repo"}},
+				"next":   server.URL + "/2.0/repositories/acme?page=2",
+			})
+		case req.URL.Path == "/2.0/repositories/acme" && req.URL.Query().Get("page") == "2":
+			rw.Write([]byte(`{"values":[{"slug":"other","name":"other"}]}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	repos := newCloudClient(server).Repos()
+
+	res, err := repos.Create(ctx, CreateRepoOpts{Name: "My Repo", ProjectKey: "JXP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Slug != "my-repo" || created["is_private"] != true || created["scm"] != "git" {
+		t.Fatalf("unexpected creation: %+v (%v)", res, created)
+	}
+
+	all, err := repos.List(ctx, "JXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Slug != "other" {
+		t.Fatalf("unexpected repos: %+v", all)
+	}
+}
+
+func TestCloudRepoArchiveNotSupported(t *testing.T) {
+	ctx := context.Background()
+	repos := NewClient(&ClientOpts{Flavor: FlavorCloud, Workspace: "acme"}).Repos()
+
+	archived := true
+	_, err := repos.Update(ctx, "JXP", "demo-repo", UpdateRepoOpts{Archived: &archived})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestCloudUserPermissions(t *testing.T) {
+	ctx := context.Background()
+	var got map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/2.0/repositories/acme/demo-repo/permissions-config/users/557058:abc"; req.URL.Path != want {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodPut:
+			json.NewDecoder(req.Body).Decode(&got)
+			rw.Write([]byte(`{"permission":"write"}`))
+		case http.MethodGet:
+			rw.Write([]byte(`{"permission":"admin","user":{"account_id":"557058:abc"}}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	perms := newCloudClient(server).Permissions()
+	opts := UserPermissionOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", User: "557058:abc", Permission: PermissionRepoWrite}
+
+	if err := perms.SetUserPermissions(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got["permission"] != "write" {
+		t.Fatalf("unexpected permission sent: %v", got)
+	}
+
+	res, err := perms.GetUserPermissions(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Permission != PermissionRepoAdmin || res.User.Name != "557058:abc" {
+		t.Fatalf("unexpected permission: %+v", res)
+	}
+
+	if err := perms.DeleteUserPermissions(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloudCommitFile(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.URL.Path != "/2.0/repositories/acme/demo-repo/src" || req.ParseForm() != nil {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.PostForm.Get("README.md") != "# demo" || req.PostForm.Get("branch") != "main" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Header().Set("Location", "https://api.bitbucket.org/2.0/repositories/acme/demo-repo/commit/0123456789abcdef")
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	res, err := newCloudClient(server).Repos().CommitFile(ctx, CommitFileOpts{
+		ProjectKey: "JXP",
+		RepoSlug:   "demo-repo",
+		Path:       "README.md",
+		Branch:     "main",
+		Message:    "first commit",
+		Content:    []byte("# demo"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Id != "0123456789abcdef" || res.DisplayId != "0123456789a" {
+		t.Fatalf("unexpected commit: %+v", res)
+	}
+}
+
+func TestCloudLabels(t *testing.T) {
+	ctx := context.Background()
+	desc := "Demo repository"
+	puts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/2.0/repositories/acme/demo-repo" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			json.NewEncoder(rw).Encode(map[string]string{"slug": "demo-repo", "description": desc})
+		case http.MethodPut:
+			puts++
+			body := map[string]string{}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			desc = body["description"]
+			json.NewEncoder(rw).Encode(map[string]string{"slug": "demo-repo", "description": desc})
+		}
+	}))
+	defer server.Close()
+
+	repos := newCloudClient(server).Repos()
+	opts := GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	// The label is added once, as a line of the description.
+	for i := 0; i < 2; i++ {
+		if err := repos.AddLabel(ctx, opts, "krateo-a-b"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "Demo repository\nkrateo-label: krateo-a-b"; puts != 1 || desc != want {
+		t.Fatalf("unexpected description after %d updates: %q", puts, desc)
+	}
+
+	labels, err := repos.Labels(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0] != "krateo-a-b" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+
+	// The repository description is reported without the labels.
+	res, err := repos.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Description != "Demo repository" {
+		t.Fatalf("unexpected description: %q", res.Description)
+	}
+}
+
+func TestCloudGetRawEscaping(t *testing.T) {
+	ctx := context.Background()
+	paths := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.EscapedPath())
+		switch req.URL.EscapedPath() {
+		case "/2.0/repositories/acme/demo-repo/src/feature%2Fdocs/docs/my%20file%231.md":
+			rw.Write([]byte("# docs"))
+		case "/2.0/repositories/acme/demo-repo/commits/feature%2Fdocs":
+			rw.Write([]byte(`{"values":[{"hash":"0123456789abcdef","message":"docs"}]}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	repos := newCloudClient(server).Repos()
+	opts := FileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Branch: "feature/docs", Path: "docs/my file#1.md"}
+
+	// The branch slash is escaped, the ones of the file path are not.
+	dat, err := repos.GetRaw(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dat) != "# docs" {
+		t.Fatalf("unexpected content %q from %v", dat, paths)
+	}
+
+	res, err := repos.LastCommit(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Id != "0123456789abcdef" {
+		t.Fatalf("unexpected commit %+v from %v", res, paths)
+	}
+}
@@ -0,0 +1,79 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/cassette"
+)
+
+// contractClient returns a client replaying the cassette of the test; with
+// BITBUCKET_CONTRACT_URL set the cassette is recorded again against that
+// server, authenticating with BITBUCKET_CONTRACT_TOKEN.
+func contractClient(t *testing.T) Interface {
+	path := filepath.Join("../../../testdata/cassettes", t.Name()+".json")
+
+	url, token := os.Getenv("BITBUCKET_CONTRACT_URL"), os.Getenv("BITBUCKET_CONTRACT_TOKEN")
+	if len(url) == 0 {
+		rep, err := cassette.NewReplayer(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewClient(&ClientOpts{
+			ApiBaseUrl: "https://" + cassette.Host,
+			HttpClient: &http.Client{Transport: rep},
+		})
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	rec, err := cassette.NewRecorder(path, http.DefaultTransport, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(&ClientOpts{
+		ApiBaseUrl: url,
+		HttpClient: &http.Client{Transport: rec},
+		Token:      token,
+	})
+}
+
+// TestContractRepos needs the JXP project, without a test-repo-2 repository, to be recorded.
+func TestContractRepos(t *testing.T) {
+	ctx := context.Background()
+	repos := contractClient(t).Repos()
+	opts := CreateRepoOpts{Name: "test-repo-2", ProjectKey: "JXP"}
+
+	res, err := repos.Create(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Slug != "test-repo-2" || res.Project.Key != "JXP" || res.Public {
+		t.Fatalf("unexpected repo: %+v", res)
+	}
+
+	if _, err := repos.Create(ctx, opts); err == nil {
+		t.Fatalf("expected the creation of an existing repo to fail")
+	}
+
+	res, err = repos.Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "test-repo-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.State != "AVAILABLE" || res.ScmId != "git" {
+		t.Fatalf("unexpected repo: %+v", res)
+	}
+
+	if err := repos.Delete(ctx, "JXP", "test-repo-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = repos.Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "test-repo-2"})
+	if err != nil || res != nil {
+		t.Fatalf("expected no repo, got %+v (%v)", res, err)
+	}
+}
@@ -0,0 +1,84 @@
+package bitbucket
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Kinds of the errors reported by Bitbucket; a StatusError matches
+// the one of its status code or exception name with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrValidation   = errors.New("validation failed")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// FieldError is an error of a field of the request; Field is
+// empty when the error is not about a specific one.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError reports the fields of the request Bitbucket rejected;
+// it is the inner error of a StatusError matching ErrValidation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, el := range e.Fields {
+		msgs = append(msgs, el.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e StatusError) Is(target error) bool {
+	kind := e.kind()
+	return kind != nil && kind == target
+}
+
+// kind returns the kind of the error, derived from the exception name
+// reported by Bitbucket Server when known, otherwise from the status code.
+func (e StatusError) kind() error {
+	name := e.ExceptionName
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(name, "NoSuch"), strings.HasSuffix(name, "NotFoundException"):
+		return ErrNotFound
+	case strings.HasPrefix(name, "Duplicate"), strings.HasSuffix(name, "AlreadyExistsException"):
+		return ErrConflict
+	case name == "AuthorisationException":
+		return ErrForbidden
+	case strings.HasSuffix(name, "AuthenticationException"), name == "NotAuthenticatedException":
+		return ErrUnauthorized
+	case strings.HasSuffix(name, "ValidationException"):
+		return ErrValidation
+	case strings.Contains(name, "RateLimit"):
+		return ErrRateLimited
+	}
+
+	switch e.Code {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrValidation
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		status int
+		body   string
+		want   error
+	}{
+		"Conflict": {
+			status: http.StatusConflict,
+			body:   `{"errors":[{"message":"This repository URL is already taken.","exceptionName":"com.atlassian.bitbucket.repository.DuplicateRepositoryNameException"}]}`,
+			want:   ErrConflict,
+		},
+		"Unauthorized": {
+			status: http.StatusUnauthorized,
+			body:   `{"errors":[{"message":"Authentication failed.","exceptionName":"com.atlassian.bitbucket.auth.IncorrectPasswordAuthenticationException"}]}`,
+			want:   ErrUnauthorized,
+		},
+		"Forbidden": {
+			status: http.StatusUnauthorized,
+			body:   `{"errors":[{"message":"You are not permitted to access this resource","exceptionName":"com.atlassian.bitbucket.AuthorisationException"}]}`,
+			want:   ErrForbidden,
+		},
+		"ForbiddenByStatus": {
+			status: http.StatusForbidden,
+			want:   ErrForbidden,
+		},
+		"RateLimited": {
+			status: http.StatusTooManyRequests,
+			body:   `{"errors":[{"message":"Rate limit exceeded"}]}`,
+			want:   ErrRateLimited,
+		},
+		"Unknown": {
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tc.status)
+				rw.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(&ClientOpts{
+				ApiBaseUrl: server.URL,
+				HttpClient: server.Client(),
+				Retry:      RetryOpts{MaxAttempts: 1},
+			}).Repos().Create(ctx, CreateRepoOpts{ProjectKey: "JXP", Name: "demo-repo"})
+
+			var se StatusError
+			if !errors.As(err, &se) || se.Code != tc.status {
+				t.Fatalf("expected a status error %d, got %v", tc.status, err)
+			}
+			for _, kind := range []error{ErrNotFound, ErrConflict, ErrUnauthorized, ErrForbidden, ErrValidation, ErrRateLimited} {
+				if got := errors.Is(err, kind); got != (kind == tc.want) {
+					t.Fatalf("expected errors.Is(%v) to be %t", kind, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"errors":[{"message":"Repository JXP/demo-repo does not exist.","exceptionName":"com.atlassian.bitbucket.repository.NoSuchRepositoryException"}]}`))
+	}))
+	defer server.Close()
+
+	res, err := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}).Repos().Get(context.Background(), GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if res != nil || err != nil {
+		t.Fatalf("expected no repo and no error, got %+v, %v", res, err)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	tests := map[string]struct {
+		flavor string
+		body   string
+		msg    string
+		fields []FieldError
+	}{
+		"Server": {
+			body: `{"errors":[
+				{"context":"name","message":"The name is required.","exceptionName":null},
+				{"context":"scmId","message":"The SCM is not supported.","exceptionName":null}]}`,
+			msg: "The name is required.; The SCM is not supported.",
+			fields: []FieldError{
+				{Field: "name", Message: "The name is required."},
+				{Field: "scmId", Message: "The SCM is not supported."},
+			},
+		},
+		"Cloud": {
+			flavor: FlavorCloud,
+			body:   `{"type":"error","error":{"message":"Bad request","fields":{"name":["This field is required."]}}}`,
+			msg:    "Bad request; This field is required.",
+			fields: []FieldError{
+				{Message: "Bad request"},
+				{Field: "name", Message: "This field is required."},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusBadRequest)
+				rw.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(&ClientOpts{
+				ApiBaseUrl: server.URL,
+				HttpClient: server.Client(),
+				Flavor:     tc.flavor,
+				Workspace:  "acme",
+				Retry:      RetryOpts{MaxAttempts: 1},
+			}).Repos().Update(context.Background(), "JXP", "demo-repo", UpdateRepoOpts{})
+
+			if !errors.Is(err, ErrValidation) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected the fields of the validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(verr.Fields, tc.fields) {
+				t.Fatalf("expected %+v, got %+v", tc.fields, verr.Fields)
+			}
+			if err.Error() != tc.msg {
+				t.Fatalf("expected [%s], got [%s]", tc.msg, err.Error())
+			}
+		})
+	}
+}
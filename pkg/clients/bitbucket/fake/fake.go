@@ -0,0 +1,816 @@
+// Package fake provides an in-memory Bitbucket Server implementing the
+// repository and permission services, and serving over HTTP the endpoints
+// of the other services, to test the controllers offline.
+package fake
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+)
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+type branch struct {
+	head  string
+	files map[string][]byte
+	// commits is the last commit of every file.
+	commits map[string]bitbucket.Commit
+}
+
+type repo struct {
+	bitbucket.Repository
+	labels   []string
+	refSync  bool
+	branches map[string]*branch
+	users    map[string]string
+	groups   map[string]string
+	settings *settings
+	pulls    []*bitbucket.PullRequest
+}
+
+func newRepo() *repo {
+	return &repo{
+		branches: map[string]*branch{},
+		users:    map[string]string{},
+		groups:   map[string]string{},
+		settings: newSettings(),
+	}
+}
+
+// Bitbucket is an in-memory Bitbucket Server; its clients report errors
+// with the status codes of the real one: 404 for missing projects and
+// repositories, 409 for conflicts and 401 for wrong tokens.
+type Bitbucket struct {
+	// Token, when set, is the only token accepted.
+	Token string
+	// Cloud, when set, makes the clients report the cloud flavor
+	// and refuse to archive repositories, as Bitbucket Cloud does.
+	Cloud bool
+
+	mu       sync.Mutex
+	projects map[string]bool
+	repos    map[string]*repo
+	commits  int
+	// settings are the ones of the projects.
+	settings map[string]*settings
+	// ids is the last identifier of the required builds conditions.
+	ids int
+}
+
+// New returns an empty Bitbucket with the given projects.
+func New(projects ...string) *Bitbucket {
+	res := &Bitbucket{
+		projects: map[string]bool{},
+		repos:    map[string]*repo{},
+		settings: map[string]*settings{},
+	}
+	for _, el := range projects {
+		res.projects[el] = true
+	}
+	return res
+}
+
+// NewClient returns a client of the Bitbucket; it can replace bitbucket.NewClient.
+func (b *Bitbucket) NewClient(opts *bitbucket.ClientOpts) bitbucket.Interface {
+	authorized := len(b.Token) == 0 || opts.Token == b.Token
+	return &client{
+		svc: &service{
+			b:          b,
+			authorized: authorized,
+		},
+		http: bitbucket.NewClient(&bitbucket.ClientOpts{
+			ApiBaseUrl: baseURL,
+			Token:      opts.Token,
+			HttpClient: &http.Client{Transport: &transport{b: b, authorized: authorized}},
+		}),
+	}
+}
+
+// AddProject creates a project.
+func (b *Bitbucket) AddProject(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.projects[key] = true
+}
+
+// Repo returns a copy of a repository, or nil if it does not exist.
+func (b *Bitbucket) Repo(projectKey, slug string) *bitbucket.Repository {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.repos[repoKey(projectKey, slug)]
+	if !ok {
+		return nil
+	}
+	res := r.Repository
+	return &res
+}
+
+// Labels returns the labels of a repository.
+func (b *Bitbucket) Labels(projectKey, slug string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.repos[repoKey(projectKey, slug)]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, r.labels...)
+}
+
+// AddBranch creates a branch of a repository from the head of another one.
+func (b *Bitbucket) AddBranch(projectKey, slug, name, from string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, err := b.repo(projectKey, slug)
+	if err != nil {
+		return err
+	}
+	src, ok := r.branches[from]
+	if !ok {
+		return notFound("Branch %s does not exist.", from)
+	}
+	if _, ok := r.branches[name]; ok {
+		return conflict("Branch %s already exists.", name)
+	}
+
+	br := &branch{head: src.head, files: map[string][]byte{}, commits: map[string]bitbucket.Commit{}}
+	for k, v := range src.files {
+		br.files[k] = v
+	}
+	for k, v := range src.commits {
+		br.commits[k] = v
+	}
+	r.branches[name] = br
+	return nil
+}
+
+// File returns the content of a file, or nil if it does not exist.
+func (b *Bitbucket) File(projectKey, slug, branch, path string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.repos[repoKey(projectKey, slug)]
+	if !ok {
+		return nil
+	}
+	br, ok := r.branches[branch]
+	if !ok {
+		return nil
+	}
+	return br.files[path]
+}
+
+func repoKey(projectKey, slug string) string {
+	return projectKey + "/" + slug
+}
+
+func slugify(name string) string {
+	return slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+func notFound(format string, a ...interface{}) error {
+	return bitbucket.StatusError{Code: 404, Inner: fmt.Errorf(format, a...)}
+}
+
+func conflict(format string, a ...interface{}) error {
+	return bitbucket.StatusError{Code: 409, Inner: fmt.Errorf(format, a...)}
+}
+
+func unauthorized() error {
+	return bitbucket.StatusError{Code: 401, Inner: fmt.Errorf("Authentication failed. Please check your credentials and try again.")}
+}
+
+type client struct {
+	svc *service
+	// http is the client of the endpoints served over HTTP.
+	http bitbucket.Interface
+}
+
+func (c *client) Flavor() string {
+	if c.svc.b.Cloud {
+		return bitbucket.FlavorCloud
+	}
+	return bitbucket.FlavorServer
+}
+
+func (c *client) Repos() bitbucket.RepoService {
+	return c.svc
+}
+
+func (c *client) Permissions() bitbucket.PermissionService {
+	return c.svc
+}
+
+func (c *client) Hooks() *bitbucket.HookService {
+	return c.http.Hooks()
+}
+
+func (c *client) RequiredBuilds() *bitbucket.RequiredBuildsService {
+	return c.http.RequiredBuilds()
+}
+
+func (c *client) BranchModels() *bitbucket.BranchModelService {
+	return c.http.BranchModels()
+}
+
+func (c *client) PullRequests() *bitbucket.PullRequestService {
+	return c.http.PullRequests()
+}
+
+// service implements bitbucket.RepoService and bitbucket.PermissionService.
+type service struct {
+	b          *Bitbucket
+	authorized bool
+}
+
+// lock locks the Bitbucket, checking the client is authorized
+// and, like a real call, that the context is not done.
+func (s *service) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.authorized {
+		return unauthorized()
+	}
+	s.b.mu.Lock()
+	return nil
+}
+
+func (s *service) unlock() {
+	s.b.mu.Unlock()
+}
+
+// repo returns the repository or a 404 error; the Bitbucket must be locked.
+func (s *service) repo(projectKey, slug string) (*repo, error) {
+	return s.b.repo(projectKey, slug)
+}
+
+func (b *Bitbucket) repo(projectKey, slug string) (*repo, error) {
+	if !b.projects[projectKey] {
+		return nil, notFound("Project %s does not exist.", projectKey)
+	}
+	r, ok := b.repos[repoKey(projectKey, slug)]
+	if !ok {
+		return nil, notFound("Repository %s/%s does not exist.", projectKey, slug)
+	}
+	return r, nil
+}
+
+func (s *service) nextCommit(message string) bitbucket.Commit {
+	s.b.commits++
+	id := fmt.Sprintf("%040x", s.b.commits)
+	return bitbucket.Commit{Id: id, DisplayId: id[:11], Message: message}
+}
+
+func (s *service) Get(ctx context.Context, opts bitbucket.GetRepoOpts) (*bitbucket.Repository, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, nil
+	}
+	res := r.Repository
+	return &res, nil
+}
+
+func (s *service) Create(ctx context.Context, opts bitbucket.CreateRepoOpts) (*bitbucket.Repository, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	if !s.b.projects[opts.ProjectKey] {
+		return nil, notFound("Project %s does not exist.", opts.ProjectKey)
+	}
+
+	slug := slugify(opts.Name)
+	if _, ok := s.b.repos[repoKey(opts.ProjectKey, slug)]; ok {
+		return nil, conflict("This repository URL is already taken.")
+	}
+
+	r := newRepo()
+	r.Name = opts.Name
+	r.Slug = slug
+	r.ScmId = "git"
+	r.State = "AVAILABLE"
+	r.Public = opts.Public
+	r.Project.Key = opts.ProjectKey
+	r.Project.Name = opts.ProjectKey
+	s.b.repos[repoKey(opts.ProjectKey, slug)] = r
+
+	res := r.Repository
+	return &res, nil
+}
+
+func (s *service) Update(ctx context.Context, projectKey, slug string, opts bitbucket.UpdateRepoOpts) (*bitbucket.Repository, error) {
+	if opts.Archived != nil && s.b.Cloud {
+		return nil, fmt.Errorf("archiving repositories: %w", bitbucket.ErrNotSupported)
+	}
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(projectKey, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	newProject, newSlug := projectKey, slug
+	if opts.ProjectKey != nil {
+		newProject = *opts.ProjectKey
+		if !s.b.projects[newProject] {
+			return nil, notFound("Project %s does not exist.", newProject)
+		}
+	}
+	if opts.Name != nil {
+		newSlug = slugify(*opts.Name)
+	}
+	if newProject != projectKey || newSlug != slug {
+		if _, ok := s.b.repos[repoKey(newProject, newSlug)]; ok {
+			return nil, conflict("This repository URL is already taken.")
+		}
+	}
+
+	if opts.Name != nil {
+		r.Name = *opts.Name
+	}
+	if opts.Archived != nil {
+		r.Archived = *opts.Archived
+	}
+	r.Slug = newSlug
+	r.Project.Key = newProject
+	r.Project.Name = newProject
+
+	delete(s.b.repos, repoKey(projectKey, slug))
+	s.b.repos[repoKey(newProject, newSlug)] = r
+
+	res := r.Repository
+	return &res, nil
+}
+
+func (s *service) List(ctx context.Context, projectKey string) ([]bitbucket.Repository, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	if !s.b.projects[projectKey] {
+		return nil, nil
+	}
+
+	all := []bitbucket.Repository{}
+	for _, r := range s.b.repos {
+		if r.Project.Key == projectKey {
+			all = append(all, r.Repository)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Slug < all[j].Slug })
+	return all, nil
+}
+
+func (s *service) Init(ctx context.Context, opts bitbucket.RepoInitOpts) error {
+	if opts.Title == "" {
+		opts.Title = opts.RepoSlug
+	}
+
+	_, err := s.CommitFile(ctx, bitbucket.CommitFileOpts{
+		ProjectKey: opts.ProjectKey,
+		RepoSlug:   opts.RepoSlug,
+		Path:       "README.md",
+		Branch:     "main",
+		Message:    "first commit",
+		Content:    []byte(fmt.Sprintf("# %s", opts.Title)),
+	})
+	return err
+}
+
+func (s *service) Delete(ctx context.Context, projectKey, slug string) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	delete(s.b.repos, repoKey(projectKey, slug))
+	return nil
+}
+
+func (s *service) Fork(ctx context.Context, opts bitbucket.ForkRepoOpts) (*bitbucket.Repository, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	src, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+	if !s.b.projects[opts.TargetProjectKey] {
+		return nil, notFound("Project %s does not exist.", opts.TargetProjectKey)
+	}
+
+	slug := slugify(opts.Name)
+	if _, ok := s.b.repos[repoKey(opts.TargetProjectKey, slug)]; ok {
+		return nil, conflict("This repository URL is already taken.")
+	}
+
+	r := newRepo()
+	r.Repository = src.Repository
+	r.Name = opts.Name
+	r.Slug = slug
+	r.Project.Key = opts.TargetProjectKey
+	r.Project.Name = opts.TargetProjectKey
+	origin := src.Repository
+	origin.Origin = nil
+	r.Origin = &origin
+	for name, br := range src.branches {
+		cp := &branch{head: br.head, files: map[string][]byte{}, commits: map[string]bitbucket.Commit{}}
+		for k, v := range br.files {
+			cp.files[k] = v
+		}
+		for k, v := range br.commits {
+			cp.commits[k] = v
+		}
+		r.branches[name] = cp
+	}
+	s.b.repos[repoKey(opts.TargetProjectKey, slug)] = r
+
+	res := r.Repository
+	return &res, nil
+}
+
+func (s *service) GetRefSync(ctx context.Context, opts bitbucket.GetRepoOpts) (*bitbucket.RefSyncStatus, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil || r.Origin == nil {
+		return nil, nil
+	}
+	return &bitbucket.RefSyncStatus{Available: true, Enabled: r.refSync}, nil
+}
+
+func (s *service) SetRefSync(ctx context.Context, opts bitbucket.GetRepoOpts, enabled bool) (*bitbucket.RefSyncStatus, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+	if r.Origin == nil {
+		return nil, conflict("Repository %s/%s is not a fork.", opts.ProjectKey, opts.RepoSlug)
+	}
+	r.refSync = enabled
+	return &bitbucket.RefSyncStatus{Available: true, Enabled: r.refSync}, nil
+}
+
+func (s *service) Labels(ctx context.Context, opts bitbucket.GetRepoOpts) ([]string, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{}, r.labels...), nil
+}
+
+func (s *service) AddLabel(ctx context.Context, opts bitbucket.GetRepoOpts, name string) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return err
+	}
+	for _, el := range r.labels {
+		if el == name {
+			return conflict("Label %s is already applied.", name)
+		}
+	}
+	r.labels = append(r.labels, name)
+	return nil
+}
+
+func (s *service) Branches(ctx context.Context, opts bitbucket.GetRepoOpts) ([]bitbucket.Branch, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	all := []bitbucket.Branch{}
+	for name, br := range r.branches {
+		all = append(all, bitbucket.Branch{
+			Id:           "refs/heads/" + name,
+			DisplayId:    name,
+			LatestCommit: br.head,
+			IsDefault:    name == "main",
+		})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Id < all[j].Id })
+	return all, nil
+}
+
+// Archive writes the files of the branch as a tgz, whatever the format.
+func (s *service) Archive(ctx context.Context, opts bitbucket.ArchiveOpts, w io.Writer) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return err
+	}
+	br, ok := r.branches[strings.TrimPrefix(opts.At, "refs/heads/")]
+	if !ok {
+		return notFound("Object %s does not exist.", opts.At)
+	}
+
+	paths := []string{}
+	for k := range br.files {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, p := range paths {
+		dat := br.files[p]
+		if err := tw.WriteHeader(&tar.Header{Name: p, Mode: 0o644, Size: int64(len(dat))}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(dat); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (s *service) GetRaw(ctx context.Context, opts bitbucket.FileOpts) ([]byte, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, nil
+	}
+	br, ok := s.branchAt(r, opts.Branch)
+	if !ok {
+		return nil, nil
+	}
+	return br.files[opts.Path], nil
+}
+
+func (s *service) LastCommit(ctx context.Context, opts bitbucket.FileOpts) (*bitbucket.Commit, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, nil
+	}
+	br, ok := s.branchAt(r, opts.Branch)
+	if !ok {
+		return nil, nil
+	}
+	c, ok := br.commits[opts.Path]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// CommitFile commits the file; the branch is created only if the repository is
+// empty and, as Bitbucket does, edits of a file changed after the commit they
+// are based on are rejected.
+func (s *service) CommitFile(ctx context.Context, opts bitbucket.CommitFileOpts) (*bitbucket.Commit, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.branchName(opts.Branch)
+	br, ok := r.branches[name]
+	if !ok {
+		if len(r.branches) > 0 {
+			return nil, notFound("Branch %s does not exist.", name)
+		}
+		br = &branch{files: map[string][]byte{}, commits: map[string]bitbucket.Commit{}}
+		r.branches[name] = br
+	}
+
+	last, exists := br.commits[opts.Path]
+	switch {
+	case exists && len(opts.SourceCommitId) == 0:
+		return nil, conflict("The file '%s' already exists.", opts.Path)
+	case exists && opts.SourceCommitId < last.Id:
+		// The commits are numbered, thus ordered.
+		return nil, conflict("The file '%s' has been modified since the commit %s.", opts.Path, opts.SourceCommitId)
+	}
+
+	c := s.nextCommit(opts.Message)
+	br.files[opts.Path] = append([]byte{}, opts.Content...)
+	br.commits[opts.Path] = c
+	br.head = c.Id
+
+	return &c, nil
+}
+
+// branchAt returns the branch named by the ref or whose head is the commit.
+func (s *service) branchAt(r *repo, ref string) (*branch, bool) {
+	if br, ok := r.branches[s.branchName(ref)]; ok {
+		return br, true
+	}
+	for _, br := range r.branches {
+		if br.head == ref {
+			return br, true
+		}
+	}
+	return nil, false
+}
+
+func (s *service) branchName(name string) string {
+	name = strings.TrimPrefix(name, "refs/heads/")
+	if len(name) == 0 {
+		return "main"
+	}
+	return name
+}
+
+func (s *service) SetUserPermissions(ctx context.Context, opts bitbucket.UserPermissionOpts) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil
+	}
+	r.users[opts.User] = opts.Permission
+	return nil
+}
+
+func (s *service) GetUserPermissions(ctx context.Context, opts bitbucket.UserPermissionOpts) (*bitbucket.UserPermission, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, nil
+	}
+	perm, ok := r.users[opts.User]
+	if !ok {
+		return nil, nil
+	}
+	res := &bitbucket.UserPermission{Permission: perm}
+	res.User.Name = opts.User
+	return res, nil
+}
+
+func (s *service) DeleteUserPermissions(ctx context.Context, opts bitbucket.UserPermissionOpts) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil
+	}
+	delete(r.users, opts.User)
+	return nil
+}
+
+func (s *service) ListUserPermissions(ctx context.Context, opts bitbucket.GetRepoOpts) ([]bitbucket.UserPermission, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	all := []bitbucket.UserPermission{}
+	for user, perm := range r.users {
+		el := bitbucket.UserPermission{Permission: perm}
+		el.User.Name = user
+		all = append(all, el)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].User.Name < all[j].User.Name })
+	return all, nil
+}
+
+func (s *service) SetGroupPermissions(ctx context.Context, opts bitbucket.GroupPermissionOpts) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return err
+	}
+	r.groups[opts.Group] = opts.Permission
+	return nil
+}
+
+func (s *service) GetGroupPermissions(ctx context.Context, opts bitbucket.GroupPermissionOpts) (*bitbucket.GroupPermission, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, nil
+	}
+	perm, ok := r.groups[opts.Group]
+	if !ok {
+		return nil, nil
+	}
+	res := &bitbucket.GroupPermission{Permission: perm}
+	res.Group.Name = opts.Group
+	return res, nil
+}
+
+func (s *service) DeleteGroupPermissions(ctx context.Context, opts bitbucket.GroupPermissionOpts) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil
+	}
+	delete(r.groups, opts.Group)
+	return nil
+}
+
+func (s *service) ListGroupPermissions(ctx context.Context, opts bitbucket.GetRepoOpts) ([]bitbucket.GroupPermission, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	r, err := s.repo(opts.ProjectKey, opts.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	all := []bitbucket.GroupPermission{}
+	for group, perm := range r.groups {
+		el := bitbucket.GroupPermission{Permission: perm}
+		el.Group.Name = group
+		all = append(all, el)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Group.Name < all[j].Group.Name })
+	return all, nil
+}
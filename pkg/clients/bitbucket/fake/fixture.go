@@ -0,0 +1,50 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+)
+
+// Fixture names.
+const (
+	Project = "JXP"
+	Repo    = "demo-repo"
+)
+
+// A Fixture is what the controllers tests start from: a Bitbucket with the
+// initialized Repo in the Project, a client of it and a Kubernetes client
+// listing no other managed resource.
+type Fixture struct {
+	Bitbucket *Bitbucket
+	Client    bitbucket.Interface
+	Kube      *test.MockClient
+}
+
+// NewFixture returns a new Fixture; the test fails if it cannot be set up.
+func NewFixture(t testing.TB) *Fixture {
+	t.Helper()
+
+	ctx := context.Background()
+
+	bb := New(Project)
+	cli := bb.NewClient(&bitbucket.ClientOpts{})
+	if _, err := cli.Repos().Create(ctx, bitbucket.CreateRepoOpts{Name: Repo, ProjectKey: Project}); err != nil {
+		t.Fatalf("cannot create the fixture repo: %v", err)
+	}
+	if err := cli.Repos().Init(ctx, bitbucket.RepoInitOpts{ProjectKey: Project, RepoSlug: Repo}); err != nil {
+		t.Fatalf("cannot initialize the fixture repo: %v", err)
+	}
+
+	return &Fixture{
+		Bitbucket: bb,
+		Client:    cli,
+		Kube: &test.MockClient{
+			MockList:   test.NewMockListFn(nil),
+			MockUpdate: test.NewMockUpdateFn(nil),
+		},
+	}
+}
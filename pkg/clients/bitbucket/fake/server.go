@@ -0,0 +1,528 @@
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+)
+
+// baseURL is the address of the Bitbucket served by the HTTP clients of the fake.
+const baseURL = "http://bitbucket.fake"
+
+// Routes of the endpoints served over HTTP.
+var (
+	hookRoute         = regexp.MustCompile(`^/rest/api/1.0/projects/([^/]+)(?:/repos/([^/]+))?/settings/hooks/([^/]+)(/enabled|/settings)?$`)
+	conditionsRoute   = regexp.MustCompile(`^/rest/required-builds/latest/projects/([^/]+)(?:/repos/([^/]+))?/(conditions|condition)(?:/(\d+))?$`)
+	branchModelRoute  = regexp.MustCompile(`^/rest/branch-utils/latest/projects/([^/]+)(?:/repos/([^/]+))?/branchmodel/configuration$`)
+	pullRequestsRoute = regexp.MustCompile(`^/rest/api/1.0/projects/([^/]+)/repos/([^/]+)/pull-requests(?:/(\d+)(/merge|/decline)?)?$`)
+	buildStatsRoute   = regexp.MustCompile(`^/rest/build-status/1.0/commits/stats/([^/]+)$`)
+)
+
+// settings are the hooks, branching model and required builds of a project or repository.
+type settings struct {
+	hooks      map[string]*hook
+	model      *bitbucket.BranchModelConfiguration
+	conditions []bitbucket.RequiredBuildCondition
+}
+
+func newSettings() *settings {
+	return &settings{hooks: map[string]*hook{}}
+}
+
+type hook struct {
+	enabled  bool
+	settings json.RawMessage
+}
+
+// ServeHTTP serves the REST API of the repository hooks, required builds, branching
+// models and pull requests, the endpoints of the services the client does not fake;
+// every hook key is installed.
+func (b *Bitbucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		status int
+		res    interface{}
+		err    error
+	)
+
+	path := r.URL.Path
+	switch {
+	case hookRoute.MatchString(path):
+		m := hookRoute.FindStringSubmatch(path)
+		status, res, err = b.serveHook(r, m[1], m[2], m[3], m[4])
+	case conditionsRoute.MatchString(path):
+		m := conditionsRoute.FindStringSubmatch(path)
+		status, res, err = b.serveConditions(r, m[1], m[2], m[3], m[4])
+	case branchModelRoute.MatchString(path):
+		m := branchModelRoute.FindStringSubmatch(path)
+		status, res, err = b.serveBranchModel(r, m[1], m[2])
+	case pullRequestsRoute.MatchString(path):
+		m := pullRequestsRoute.FindStringSubmatch(path)
+		status, res, err = b.servePullRequests(r, m[1], m[2], m[3], m[4])
+	case buildStatsRoute.MatchString(path) && r.Method == http.MethodGet:
+		status, res = http.StatusOK, &bitbucket.BuildStats{}
+	default:
+		err = notFound("No endpoint %s %s.", r.Method, path)
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if raw, ok := res.(json.RawMessage); ok {
+		w.Write(raw)
+		return
+	}
+	if res != nil {
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+// writeError writes the error as Bitbucket Server does.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var se bitbucket.StatusError
+	if errors.As(err, &se) {
+		code = se.Code
+		if se.Inner != nil {
+			err = se.Inner
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": err.Error()}},
+	})
+}
+
+func notAllowed(r *http.Request) error {
+	return bitbucket.StatusError{Code: http.StatusMethodNotAllowed, Inner: fmt.Errorf("Method %s is not supported.", r.Method)}
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return bitbucket.StatusError{Code: http.StatusBadRequest, Inner: fmt.Errorf(format, a...)}
+}
+
+// scope returns the settings of the project, or of the repository if the slug is set,
+// and the Bitbucket scope of the settings; the Bitbucket must be locked.
+func (b *Bitbucket) scope(projectKey, slug string) (*settings, string, error) {
+	if len(slug) == 0 {
+		if !b.projects[projectKey] {
+			return nil, "", notFound("Project %s does not exist.", projectKey)
+		}
+		res, ok := b.settings[projectKey]
+		if !ok {
+			res = newSettings()
+			b.settings[projectKey] = res
+		}
+		return res, bitbucket.HookScopeProject, nil
+	}
+
+	r, err := b.repo(projectKey, slug)
+	if err != nil {
+		return nil, "", err
+	}
+	return r.settings, bitbucket.HookScopeRepository, nil
+}
+
+func decode(r *http.Request, v interface{}) error {
+	dat, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(dat, v); err != nil {
+		return badRequest("Invalid request body: %s", err.Error())
+	}
+	return nil
+}
+
+// serverPage returns the values as the only page of a Bitbucket Server paged API.
+func serverPage(values interface{}) interface{} {
+	return map[string]interface{}{
+		"values":     values,
+		"isLastPage": true,
+		"start":      0,
+	}
+}
+
+func (b *Bitbucket) serveHook(r *http.Request, projectKey, slug, key, suffix string) (int, interface{}, error) {
+	s, scope, err := b.scope(projectKey, slug)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// A repository without its own configuration inherits the project one.
+	h, ok := s.hooks[key]
+	if !ok && len(slug) > 0 {
+		project, _, _ := b.scope(projectKey, "")
+		h, scope = project.hooks[key], bitbucket.HookScopeProject
+	}
+	if h == nil {
+		h = &hook{}
+	}
+
+	switch {
+	case suffix == "/settings" && r.Method == http.MethodGet:
+		if len(h.settings) == 0 {
+			return http.StatusNoContent, nil, nil
+		}
+		return http.StatusOK, h.settings, nil
+
+	case suffix == "/settings" && r.Method == http.MethodPut:
+		var raw json.RawMessage
+		if err := decode(r, &raw); err != nil {
+			return 0, nil, err
+		}
+		s.hooks[key] = &hook{enabled: h.enabled, settings: raw}
+		return http.StatusOK, raw, nil
+
+	case suffix == "/enabled" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		h = &hook{enabled: r.Method == http.MethodPut, settings: h.settings}
+		s.hooks[key], scope = h, bitbucket.HookScopeProject
+		if len(slug) > 0 {
+			scope = bitbucket.HookScopeRepository
+		}
+		return http.StatusOK, hookOf(key, h, scope), nil
+
+	case suffix == "" && r.Method == http.MethodGet:
+		return http.StatusOK, hookOf(key, h, scope), nil
+
+	case suffix == "" && r.Method == http.MethodDelete && len(slug) > 0:
+		delete(s.hooks, key)
+		return http.StatusNoContent, nil, nil
+	}
+
+	return 0, nil, notAllowed(r)
+}
+
+func hookOf(key string, h *hook, scope string) *bitbucket.RepositoryHook {
+	res := &bitbucket.RepositoryHook{
+		Enabled:    h.enabled,
+		Configured: len(h.settings) > 0,
+	}
+	res.Details.Key = key
+	res.Details.Name = key
+	res.Details.Type = "PRE_RECEIVE"
+	res.Scope.Type = scope
+	res.Scope.ResourceId = 1
+	return res
+}
+
+func (b *Bitbucket) serveConditions(r *http.Request, projectKey, slug, resource, id string) (int, interface{}, error) {
+	s, _, err := b.scope(projectKey, slug)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch {
+	case resource == "conditions" && len(id) == 0 && r.Method == http.MethodGet:
+		return http.StatusOK, serverPage(s.conditions), nil
+
+	case resource == "condition" && len(id) == 0 && r.Method == http.MethodPost:
+		cond := bitbucket.RequiredBuildCondition{}
+		if err := decode(r, &cond); err != nil {
+			return 0, nil, err
+		}
+		if len(cond.BuildParentKeys) == 0 || cond.RefMatcher == nil {
+			return 0, nil, badRequest("The build keys and the target branch are required.")
+		}
+		b.ids++
+		cond.Id = b.ids
+		s.conditions = append(s.conditions, cond)
+		return http.StatusOK, cond, nil
+
+	case resource == "condition" && len(id) > 0:
+		n, _ := strconv.Atoi(id)
+		i := indexOfCondition(s.conditions, n)
+		if i < 0 {
+			return 0, nil, notFound("Required builds merge check %d does not exist.", n)
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			cond := bitbucket.RequiredBuildCondition{}
+			if err := decode(r, &cond); err != nil {
+				return 0, nil, err
+			}
+			cond.Id = n
+			s.conditions[i] = cond
+			return http.StatusOK, cond, nil
+		case http.MethodDelete:
+			s.conditions = append(s.conditions[:i], s.conditions[i+1:]...)
+			return http.StatusNoContent, nil, nil
+		}
+	}
+
+	return 0, nil, notAllowed(r)
+}
+
+func indexOfCondition(all []bitbucket.RequiredBuildCondition, id int) int {
+	for i := range all {
+		if all[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Bitbucket) serveBranchModel(r *http.Request, projectKey, slug string) (int, interface{}, error) {
+	s, scope, err := b.scope(projectKey, slug)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// A repository without its own configuration inherits the project
+		// one, and a project without configuration has the default one.
+		model := s.model
+		if model == nil && len(slug) > 0 {
+			project, _, _ := b.scope(projectKey, "")
+			model, scope = project.model, bitbucket.BranchModelScopeProject
+		}
+		if model == nil {
+			model = defaultBranchModel()
+		}
+		return http.StatusOK, branchModelOf(model, scope), nil
+
+	case http.MethodPut:
+		model := &bitbucket.BranchModelConfiguration{}
+		if err := decode(r, model); err != nil {
+			return 0, nil, err
+		}
+		s.model = model
+		return http.StatusOK, branchModelOf(model, scope), nil
+
+	case http.MethodDelete:
+		s.model = nil
+		return http.StatusNoContent, nil, nil
+	}
+
+	return 0, nil, notAllowed(r)
+}
+
+func defaultBranchModel() *bitbucket.BranchModelConfiguration {
+	return &bitbucket.BranchModelConfiguration{
+		Development: &bitbucket.BranchModelBranch{UseDefault: true},
+		Types: []bitbucket.BranchModelType{
+			{Id: "BUGFIX", DisplayName: "Bugfix", Prefix: "bugfix/", Enabled: true},
+			{Id: "FEATURE", DisplayName: "Feature", Prefix: "feature/", Enabled: true},
+			{Id: "HOTFIX", DisplayName: "Hotfix", Prefix: "hotfix/", Enabled: true},
+			{Id: "RELEASE", DisplayName: "Release", Prefix: "release/", Enabled: true},
+		},
+	}
+}
+
+func branchModelOf(model *bitbucket.BranchModelConfiguration, scope string) *bitbucket.BranchModelConfiguration {
+	res := *model
+	res.Types = append([]bitbucket.BranchModelType{}, model.Types...)
+	res.Scope = &struct {
+		Type       string `json:"type"`
+		ResourceId int    `json:"resourceId"`
+	}{Type: scope, ResourceId: 1}
+	return &res
+}
+
+func (b *Bitbucket) servePullRequests(r *http.Request, projectKey, slug, id, action string) (int, interface{}, error) {
+	repo, err := b.repo(projectKey, slug)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(id) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, serverPage(filterPullRequests(repo.pulls, r)), nil
+		case http.MethodPost:
+			pr := bitbucket.PullRequest{}
+			if err := decode(r, &pr); err != nil {
+				return 0, nil, err
+			}
+			res, err := b.openPullRequest(repo, pr)
+			if err != nil {
+				return 0, nil, err
+			}
+			return http.StatusCreated, res, nil
+		}
+		return 0, nil, notAllowed(r)
+	}
+
+	n, _ := strconv.Atoi(id)
+	if n < 1 || n > len(repo.pulls) {
+		return 0, nil, notFound("Pull request %d does not exist in %s/%s.", n, projectKey, slug)
+	}
+	pr := repo.pulls[n-1]
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		return http.StatusOK, pr, nil
+
+	case action == "" && r.Method == http.MethodPut:
+		in := bitbucket.PullRequest{}
+		if err := decode(r, &in); err != nil {
+			return 0, nil, err
+		}
+		if err := checkVersion(pr, in.Version); err != nil {
+			return 0, nil, err
+		}
+		if _, ok := repo.branches[branchOf(in.ToRef.Id)]; !ok {
+			return 0, nil, notFound("Repository ref %s does not exist.", in.ToRef.Id)
+		}
+		pr.Title = in.Title
+		pr.Description = in.Description
+		pr.ToRef = refOf(repo, in.ToRef.Id)
+		pr.Reviewers = reviewersOf(in.Reviewers)
+		pr.Version++
+		return http.StatusOK, pr, nil
+
+	case action == "/merge" && r.Method == http.MethodGet:
+		res := &bitbucket.MergeStatus{CanMerge: pr.State == bitbucket.PullRequestStateOpen, Outcome: "CLEAN"}
+		return http.StatusOK, res, nil
+
+	case action != "" && r.Method == http.MethodPost:
+		version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+		if err := checkVersion(pr, version); err != nil {
+			return 0, nil, err
+		}
+		if pr.State != bitbucket.PullRequestStateOpen {
+			return 0, nil, conflict("Pull request %d is already closed.", n)
+		}
+		pr.State = bitbucket.PullRequestStateDeclined
+		if action == "/merge" {
+			pr.State = bitbucket.PullRequestStateMerged
+		}
+		pr.Version++
+		return http.StatusOK, pr, nil
+	}
+
+	return 0, nil, notAllowed(r)
+}
+
+// openPullRequest adds the pull request to the repository; the Bitbucket must be locked.
+func (b *Bitbucket) openPullRequest(r *repo, in bitbucket.PullRequest) (*bitbucket.PullRequest, error) {
+	for _, ref := range []string{in.FromRef.Id, in.ToRef.Id} {
+		if _, ok := r.branches[branchOf(ref)]; !ok {
+			return nil, notFound("Repository ref %s does not exist.", ref)
+		}
+	}
+	if in.FromRef.Id == in.ToRef.Id {
+		return nil, badRequest("The source and target refs are the same.")
+	}
+	for _, el := range r.pulls {
+		if el.State == bitbucket.PullRequestStateOpen && el.FromRef.Id == in.FromRef.Id && el.ToRef.Id == in.ToRef.Id {
+			return nil, conflict("Only one pull request may be open for a given source and target branch.")
+		}
+	}
+
+	res := &bitbucket.PullRequest{
+		Id:          len(r.pulls) + 1,
+		Title:       in.Title,
+		Description: in.Description,
+		State:       bitbucket.PullRequestStateOpen,
+		FromRef:     refOf(r, in.FromRef.Id),
+		ToRef:       refOf(r, in.ToRef.Id),
+		Reviewers:   reviewersOf(in.Reviewers),
+	}
+	res.Links.Self = append(res.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d", baseURL, r.Project.Key, r.Slug, res.Id)})
+
+	r.pulls = append(r.pulls, res)
+	return res, nil
+}
+
+// filterPullRequests returns the pull requests in the state, OPEN by default, from
+// the at ref if the direction is OUTGOING, otherwise to it, as Bitbucket does.
+func filterPullRequests(all []*bitbucket.PullRequest, r *http.Request) []*bitbucket.PullRequest {
+	q := r.URL.Query()
+	state := q.Get("state")
+	if len(state) == 0 {
+		state = bitbucket.PullRequestStateOpen
+	}
+
+	res := []*bitbucket.PullRequest{}
+	for _, el := range all {
+		if state != "ALL" && el.State != state {
+			continue
+		}
+		if at := q.Get("at"); len(at) > 0 {
+			ref := el.ToRef.Id
+			if q.Get("direction") == "OUTGOING" {
+				ref = el.FromRef.Id
+			}
+			if ref != at {
+				continue
+			}
+		}
+		res = append(res, el)
+	}
+	return res
+}
+
+func checkVersion(pr *bitbucket.PullRequest, version int) error {
+	if pr.Version != version {
+		return conflict("You are attempting to modify a pull request based on out-of-date information.")
+	}
+	return nil
+}
+
+func branchOf(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
+
+func refOf(r *repo, id string) bitbucket.PullRequestRef {
+	res := bitbucket.PullRequestRef{Id: id, DisplayId: branchOf(id)}
+	if br, ok := r.branches[branchOf(id)]; ok {
+		res.LatestCommit = br.head
+	}
+	res.Repository.Slug = r.Slug
+	res.Repository.Project.Key = r.Project.Key
+	return res
+}
+
+func reviewersOf(in []bitbucket.PullRequestParticipant) []bitbucket.PullRequestParticipant {
+	res := []bitbucket.PullRequestParticipant{}
+	for _, el := range in {
+		p := bitbucket.PullRequestParticipant{Status: "UNAPPROVED"}
+		p.User.Name = el.User.Name
+		res = append(res, p)
+	}
+	return res
+}
+
+// transport sends the requests of a client to the Bitbucket, in memory.
+type transport struct {
+	b          *Bitbucket
+	authorized bool
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	rec := httptest.NewRecorder()
+	if t.authorized {
+		t.b.ServeHTTP(rec, req)
+	} else {
+		writeError(rec, unauthorized())
+	}
+
+	res := rec.Result()
+	res.Request = req
+	return res, nil
+}
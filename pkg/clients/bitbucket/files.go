@@ -0,0 +1,133 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+)
+
+type Commit struct {
+	Id        string `json:"id"`
+	DisplayId string `json:"displayId,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+type FileOpts struct {
+	ProjectKey string
+	RepoSlug   string
+	Path       string
+	// Branch is the branch (or any ref) to read from.
+	Branch string
+}
+
+// GetRaw returns the raw content of the file or nil if it does not exists.
+func (s *serverRepoService) GetRaw(ctx context.Context, opts FileOpts) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/raw/%s", opts.ProjectKey, opts.RepoSlug, opts.Path).
+		expect(200)
+	req.Builder.ToBytesBuffer(buf)
+	if len(opts.Branch) > 0 {
+		req.Builder.Param("at", opts.Branch)
+	}
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// LastCommit returns the latest commit modifying the file on
+// the branch, or nil if the file has never been committed.
+func (s *serverRepoService) LastCommit(ctx context.Context, opts FileOpts) (*Commit, error) {
+	res := struct {
+		Values []Commit `json:"values,omitempty"`
+	}{}
+
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/commits", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+	req.Builder.
+		Param("path", opts.Path).
+		ParamInt("limit", 1).
+		ToJSON(&res)
+	if len(opts.Branch) > 0 {
+		req.Builder.Param("until", opts.Branch)
+	}
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if len(res.Values) > 0 {
+		return &res.Values[0], nil
+	}
+	return nil, nil
+}
+
+type CommitFileOpts struct {
+	ProjectKey string
+	RepoSlug   string
+	Path       string
+	Branch     string
+	Message    string
+	Content    []byte
+	// SourceCommitId is the commit of the branch the edit is based on,
+	// i.e. its head; it must be empty for new files. Bitbucket rejects
+	// the commit if the file has been changed after it.
+	SourceCommitId string
+}
+
+// CommitFile creates or updates a file committing it on the branch.
+func (s *serverRepoService) CommitFile(ctx context.Context, opts CommitFileOpts) (*Commit, error) {
+	bodyBuf := &bytes.Buffer{}
+	bodyWriter := multipart.NewWriter(bodyBuf)
+	bodyWriter.WriteField("message", opts.Message)
+	bodyWriter.WriteField("branch", opts.Branch)
+	if len(opts.SourceCommitId) > 0 {
+		bodyWriter.WriteField("sourceCommitId", opts.SourceCommitId)
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="content"; filename="%s"`, path.Base(opts.Path)))
+	h.Set("Content-Type", "application/octet-stream")
+	part, err := bodyWriter.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := part.Write(opts.Content); err != nil {
+		return nil, err
+	}
+	contentType := bodyWriter.FormDataContentType()
+	bodyWriter.Close()
+
+	resp := &Commit{}
+
+	req := s.api.newRequest(http.MethodPut, "/rest/api/1.0/projects/%s/repos/%s/browse/%s", opts.ProjectKey, opts.RepoSlug, opts.Path).
+		expect(200, 201)
+	req.Builder.
+		ContentType(contentType).
+		BodyBytes(bodyBuf.Bytes()).
+		ToJSON(resp)
+
+	err = s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
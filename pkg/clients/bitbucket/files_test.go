@@ -0,0 +1,66 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCommitFile(t *testing.T) {
+	ctx := context.Background()
+	var sourceCommitId, content string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo/browse/docs/README.md"; req.URL.Path != want {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sourceCommitId = req.FormValue("sourceCommitId")
+
+		f, _, err := req.FormFile("content")
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		buf := make([]byte, 64)
+		n, _ := f.Read(buf)
+		content = string(buf[:n])
+
+		rw.Write([]byte(`{"id":"def456","displayId":"def456","message":"Update docs/README.md"}`))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	res, err := NewClient(co).Repos().CommitFile(ctx, CommitFileOpts{
+		ProjectKey:     "JXP",
+		RepoSlug:       "demo-repo",
+		Path:           "docs/README.md",
+		Branch:         "main",
+		Message:        "Update docs/README.md",
+		Content:        []byte("# Demo"),
+		SourceCommitId: "abc123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sourceCommitId != "abc123" {
+		t.Fatalf("expected sourceCommitId 'abc123', got: '%s'", sourceCommitId)
+	}
+	if content != "# Demo" {
+		t.Fatalf("unexpected content: '%s'", content)
+	}
+	if res.Id != "def456" {
+		t.Fatalf("expected commit 'def456', got: '%s'", res.Id)
+	}
+}
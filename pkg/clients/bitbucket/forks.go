@@ -0,0 +1,107 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type ForkRepoOpts struct {
+	// ProjectKey and RepoSlug identify the repository to fork.
+	ProjectKey string
+	RepoSlug   string
+	// Name and TargetProjectKey are the name and the project of the fork.
+	Name             string
+	TargetProjectKey string
+}
+
+// Fork creates a fork of the repository; the returned
+// repository reports the forked one as origin.
+func (s *serverRepoService) Fork(ctx context.Context, opts ForkRepoOpts) (*Repository, error) {
+	resp := &Repository{}
+
+	// A failed attempt may have created the fork.
+	check := func(ctx context.Context) (bool, error) {
+		all, err := s.list(ctx, opts.TargetProjectKey, true)
+		for i := range all {
+			if all[i].Name == opts.Name && all[i].Origin != nil && all[i].Origin.Slug == opts.RepoSlug {
+				*resp = all[i]
+				return true, nil
+			}
+		}
+		return false, err
+	}
+
+	req := s.api.newRequest(http.MethodPost, "/rest/api/1.0/projects/%s/repos/%s", opts.ProjectKey, opts.RepoSlug).
+		expect(201)
+	req.Builder.
+		BodyJSON(map[string]interface{}{
+			"name": opts.Name,
+			"project": map[string]string{
+				"key": opts.TargetProjectKey,
+			},
+		}).
+		ToJSON(resp)
+	err := s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type RefSyncStatus struct {
+	Available bool  `json:"available"`
+	Enabled   bool  `json:"enabled"`
+	LastSync  int64 `json:"lastSync,omitempty"`
+}
+
+// GetRefSync returns the ref synchronization status of a fork.
+func (s *serverRepoService) GetRefSync(ctx context.Context, opts GetRepoOpts) (*RefSyncStatus, error) {
+	resp := &RefSyncStatus{}
+
+	req := s.api.newRequest(http.MethodGet, "/rest/sync/latest/projects/%s/repos/%s", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SetRefSync enables or disables the ref synchronization of a fork
+// so that its branches automatically follow the origin ones.
+func (s *serverRepoService) SetRefSync(ctx context.Context, opts GetRepoOpts, enabled bool) (*RefSyncStatus, error) {
+	resp := &RefSyncStatus{}
+
+	// A failed attempt may have changed the synchronization.
+	check := func(ctx context.Context) (bool, error) {
+		sync, err := s.GetRefSync(ctx, opts)
+		if err != nil || sync == nil || sync.Enabled != enabled {
+			return false, err
+		}
+		*resp = *sync
+		return true, nil
+	}
+
+	req := s.api.newRequest(http.MethodPost, "/rest/sync/latest/projects/%s/repos/%s", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+	req.Builder.
+		BodyJSON(map[string]bool{
+			"enabled": enabled,
+		}).
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,80 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestForkRepo(t *testing.T) {
+	ctx := context.Background()
+	var got map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo"; req.URL.Path != want || req.Method != http.MethodPost {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte(`{"name":"demo-repo","slug":"demo-repo","project":{"key":"JXPF"},"origin":{"name":"demo-repo","slug":"demo-repo","project":{"key":"JXP"}}}`))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	res, err := NewClient(co).Repos().Fork(ctx, ForkRepoOpts{
+		ProjectKey:       "JXP",
+		RepoSlug:         "demo-repo",
+		Name:             "demo-repo",
+		TargetProjectKey: "JXPF",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prj, ok := got["project"].(map[string]interface{}); !ok || prj["key"] != "JXPF" {
+		t.Fatalf("unexpected target project: %v", got["project"])
+	}
+	if res.Origin == nil || res.Origin.Project.Key != "JXP" || res.Origin.Slug != "demo-repo" {
+		t.Fatalf("unexpected origin: %+v", res.Origin)
+	}
+}
+
+func TestSetRefSyncCheck(t *testing.T) {
+	ctx := context.Background()
+
+	// The synchronization is enabled, but the response is lost.
+	enabled, posts := false, 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			posts++
+			enabled = true
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(rw).Encode(RefSyncStatus{Available: true, Enabled: enabled})
+	}))
+	defer server.Close()
+
+	res, err := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Retry:      RetryOpts{BaseDelay: time.Millisecond},
+	}).Repos().SetRefSync(ctx, GetRepoOpts{ProjectKey: "JXPF", RepoSlug: "demo-repo"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Enabled || posts != 1 {
+		t.Fatalf("expected the synchronization enabled by the first attempt, got %+v after %d attempts", res, posts)
+	}
+}
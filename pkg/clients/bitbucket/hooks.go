@@ -0,0 +1,158 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	HookScopeProject    = "PROJECT"
+	HookScopeRepository = "REPOSITORY"
+)
+
+type RepositoryHook struct {
+	Details struct {
+		Key         string `json:"key"`
+		Name        string `json:"name"`
+		Type        string `json:"type,omitempty"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version,omitempty"`
+	} `json:"details"`
+	Enabled    bool `json:"enabled"`
+	Configured bool `json:"configured"`
+	Scope      struct {
+		Type       string `json:"type"`
+		ResourceId int    `json:"resourceId"`
+	} `json:"scope"`
+}
+
+// HookService provides methods for managing repository hooks
+// at project or repository level.
+type HookService struct {
+	api *api
+}
+
+type HookOpts struct {
+	ProjectKey string
+	// RepoSlug is optional: when empty the hook is managed at project level.
+	RepoSlug string
+	HookKey  string
+}
+
+// route returns the path format of the hook and its args.
+func (o HookOpts) route() (string, []interface{}) {
+	if len(o.RepoSlug) == 0 {
+		return "/rest/api/1.0/projects/%s/settings/hooks/%s", []interface{}{o.ProjectKey, o.HookKey}
+	}
+	return "/rest/api/1.0/projects/%s/repos/%s/settings/hooks/%s", []interface{}{o.ProjectKey, o.RepoSlug, o.HookKey}
+}
+
+// Get returns the hook state or nil if the hook is not installed.
+func (s *HookService) Get(ctx context.Context, opts HookOpts) (*RepositoryHook, error) {
+	resp := &RepositoryHook{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route, args...).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Enable enables the hook for the project or repository.
+func (s *HookService) Enable(ctx context.Context, opts HookOpts) (*RepositoryHook, error) {
+	return s.toggle(ctx, opts, http.MethodPut)
+}
+
+// Disable disables the hook for the project or repository.
+func (s *HookService) Disable(ctx context.Context, opts HookOpts) (*RepositoryHook, error) {
+	return s.toggle(ctx, opts, http.MethodDelete)
+}
+
+func (s *HookService) toggle(ctx context.Context, opts HookOpts, method string) (*RepositoryHook, error) {
+	resp := &RepositoryHook{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(method, route+"/enabled", args...).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Inherit removes the repository level configuration of the hook,
+// so that the project one applies again.
+func (s *HookService) Inherit(ctx context.Context, opts HookOpts) error {
+	if len(opts.RepoSlug) == 0 {
+		return fmt.Errorf("hook '%s' inheritance requires a repository", opts.HookKey)
+	}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodDelete, route, args...).
+		expect(200, 204)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetSettings returns the raw JSON settings of the hook (nil if not configured).
+func (s *HookService) GetSettings(ctx context.Context, opts HookOpts) (json.RawMessage, error) {
+	var res string
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route+"/settings", args...).
+		expect(200, 204)
+	req.Builder.ToString(&res)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	return json.RawMessage(res), nil
+}
+
+// SetSettings replaces the settings of the hook.
+func (s *HookService) SetSettings(ctx context.Context, opts HookOpts, settings json.RawMessage) error {
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPut, route+"/settings", args...).
+		expect(200, 204)
+	req.Builder.BodyJSON(settings)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
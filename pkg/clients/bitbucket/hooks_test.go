@@ -0,0 +1,54 @@
+package bitbucket
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHookSettings(t *testing.T) {
+	ctx := context.Background()
+	var gotMethod, gotPath, gotBody string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotMethod, gotPath = req.Method, req.URL.Path
+		dat, _ := ioutil.ReadAll(req.Body)
+		gotBody = string(dat)
+		if req.Method == http.MethodGet {
+			rw.Write([]byte(`{"references":"refs/heads/main"}`))
+		}
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	opts := HookOpts{ProjectKey: "JXP", HookKey: "com.atlassian.bitbucket.server.bitbucket-bundled-hooks:force-push-hook"}
+
+	err := NewClient(co).Hooks().SetSettings(ctx, opts, []byte(`{"references":"refs/heads/main"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/rest/api/1.0/projects/JXP/settings/hooks/" + opts.HookKey + "/settings"; gotPath != want {
+		t.Fatalf("expecting path [%s], got [%s]", want, gotPath)
+	}
+	if gotMethod != http.MethodPut || gotBody != `{"references":"refs/heads/main"}` {
+		t.Fatalf("unexpected request: %s %s", gotMethod, gotBody)
+	}
+
+	opts.RepoSlug = "demo-repo"
+	res, err := NewClient(co).Hooks().GetSettings(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/rest/api/1.0/projects/JXP/repos/demo-repo/settings/hooks/" + opts.HookKey + "/settings"; gotPath != want {
+		t.Fatalf("expecting path [%s], got [%s]", want, gotPath)
+	}
+	if string(res) != `{"references":"refs/heads/main"}` {
+		t.Fatalf("unexpected settings: %s", res)
+	}
+}
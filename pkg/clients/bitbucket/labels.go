@@ -0,0 +1,59 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+)
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+// Labels returns the names of the labels of the repository, all of them
+// since the markers are looked up among them.
+func (s *serverRepoService) Labels(ctx context.Context, opts GetRepoOpts) ([]string, error) {
+	req := s.api.newRequest(http.MethodGet, "/rest/api/1.0/projects/%s/repos/%s/labels", opts.ProjectKey, opts.RepoSlug).
+		expect(200).
+		unbounded()
+
+	labels := []Label{}
+	err := s.api.serverPages(req).All(ctx, &labels)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]string, 0, len(labels))
+	for _, el := range labels {
+		all = append(all, el.Name)
+	}
+
+	return all, nil
+}
+
+// AddLabel applies a label to the repository; label names
+// are lowercase and may contain letters, digits and dashes.
+func (s *serverRepoService) AddLabel(ctx context.Context, opts GetRepoOpts, name string) error {
+	// A failed attempt may have applied the label.
+	check := func(ctx context.Context) (bool, error) {
+		labels, err := s.Labels(ctx, opts)
+		for _, el := range labels {
+			if el == name {
+				return true, nil
+			}
+		}
+		return false, err
+	}
+
+	req := s.api.newRequest(http.MethodPost, "/rest/api/1.0/projects/%s/repos/%s/labels", opts.ProjectKey, opts.RepoSlug).
+		expect(200)
+	req.Builder.
+		BodyJSON(&Label{Name: name}).
+		ToJSON(&Label{})
+
+	err := s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRepoLabels(t *testing.T) {
+	ctx := context.Background()
+	labels := []Label{{Name: "team-a"}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo/labels"; req.URL.Path != want {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodPost:
+			el := Label{}
+			if err := json.NewDecoder(req.Body).Decode(&el); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			labels = append(labels, el)
+			json.NewEncoder(rw).Encode(el)
+		default:
+			json.NewEncoder(rw).Encode(map[string]interface{}{
+				"values":     labels,
+				"isLastPage": true,
+			})
+		}
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	repos := NewClient(co).Repos()
+	opts := GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	if err := repos.AddLabel(ctx, opts, "krateo-abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repos.Labels(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"team-a", "krateo-abc"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
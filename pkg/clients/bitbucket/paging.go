@@ -0,0 +1,193 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// DefaultPageLimit is the default number of items requested per page.
+const DefaultPageLimit = 100
+
+// PageOpts controls how the list calls walk the pages of the results.
+type PageOpts struct {
+	// Limit is the number of items requested per page (default: DefaultPageLimit);
+	// the server may return less.
+	Limit int
+	// MaxItems stops the listings once as many items are returned; zero means
+	// no maximum. The lookups, i.e. finding an item or checking whether a
+	// failed call took effect, walk all the pages anyway.
+	MaxItems int
+}
+
+func (o PageOpts) limit() int {
+	if o.Limit > 0 {
+		return o.Limit
+	}
+	return DefaultPageLimit
+}
+
+// pageOpts returns the paging options of the list request.
+func (a *api) pageOpts(req *Request) PageOpts {
+	res := a.paging
+	if req.lookup {
+		res.MaxItems = 0
+	}
+	return res
+}
+
+// page is a page of results with the cursor of the next one;
+// an empty cursor marks the last page.
+type page struct {
+	values []json.RawMessage
+	next   string
+}
+
+// pageFunc fetches the page at the cursor; the first page has an empty cursor.
+type pageFunc func(ctx context.Context, cursor string, limit int) (*page, error)
+
+// Iterator walks the items of a paged API fetching the pages on demand,
+// so that callers stopping early spare the requests of the pages left.
+//
+//	for it.Next(ctx) {
+//		var el Repository
+//		if err := it.Decode(&el); err != nil {
+//			return err
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator struct {
+	fetch  pageFunc
+	opts   PageOpts
+	values []json.RawMessage
+	cursor string
+	last   bool
+	count  int
+	cur    json.RawMessage
+	err    error
+}
+
+func newIterator(fetch pageFunc, opts PageOpts) *Iterator {
+	return &Iterator{fetch: fetch, opts: opts}
+}
+
+// Next advances to the next item, fetching the next page when the current one
+// is consumed; it returns false when there are no more items or on error.
+func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil || (it.opts.MaxItems > 0 && it.count >= it.opts.MaxItems) {
+		return false
+	}
+
+	for len(it.values) == 0 {
+		if it.last {
+			return false
+		}
+
+		p, err := it.fetch(ctx, it.cursor, it.opts.limit())
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.values = p.values
+		// A cursor not moving forward would loop forever.
+		it.last = len(p.next) == 0 || p.next == it.cursor
+		it.cursor = p.next
+	}
+
+	it.cur, it.values = it.values[0], it.values[1:]
+	it.count++
+	return true
+}
+
+// Decode stores the current item in the value pointed by v.
+func (it *Iterator) Decode(v interface{}) error {
+	return json.Unmarshal(it.cur, v)
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// All decodes the items left in the slice pointed by dst, replacing its content.
+func (it *Iterator) All(ctx context.Context, dst interface{}) error {
+	buf := bytes.NewBufferString("[")
+	for n := 0; it.Next(ctx); n++ {
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(it.cur)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+
+	return json.Unmarshal(buf.Bytes(), dst)
+}
+
+// serverPages returns an iterator over a Bitbucket Server paged API; req
+// requests the first page and is cloned adding the start and limit params.
+func (a *api) serverPages(req *Request) *Iterator {
+	return newIterator(func(ctx context.Context, cursor string, limit int) (*page, error) {
+		start, _ := strconv.Atoi(cursor)
+
+		res := struct {
+			Values        []json.RawMessage `json:"values,omitempty"`
+			IsLastPage    bool              `json:"isLastPage"`
+			NextPageStart int               `json:"nextPageStart"`
+		}{}
+
+		r := req.clone()
+		r.Builder.
+			ParamInt("start", start).
+			ParamInt("limit", limit).
+			ToJSON(&res)
+		if err := a.do(ctx, r); err != nil {
+			return nil, err
+		}
+
+		p := &page{values: res.Values}
+		if !res.IsLastPage && res.NextPageStart > start {
+			p.next = strconv.Itoa(res.NextPageStart)
+		}
+		return p, nil
+	}, a.pageOpts(req))
+}
+
+// cloudPages returns an iterator over a Bitbucket Cloud paged API; req
+// requests the first page and is cloned adding the pagelen and page params.
+// The page param is taken from the next link, that differs from the
+// first page request only by it.
+func (a *api) cloudPages(req *Request) *Iterator {
+	return newIterator(func(ctx context.Context, cursor string, limit int) (*page, error) {
+		res := struct {
+			Values []json.RawMessage `json:"values,omitempty"`
+			Next   string            `json:"next,omitempty"`
+		}{}
+
+		r := req.clone()
+		r.Builder.ParamInt("pagelen", limit).ToJSON(&res)
+		if len(cursor) > 0 {
+			r.Builder.Param("page", cursor)
+		}
+		if err := a.do(ctx, r); err != nil {
+			return nil, err
+		}
+
+		p := &page{values: res.Values}
+		if len(res.Next) > 0 {
+			next, err := url.Parse(res.Next)
+			if err != nil {
+				return nil, err
+			}
+			p.next = next.Query().Get("page")
+		}
+		return p, nil
+	}, a.pageOpts(req))
+}
@@ -0,0 +1,221 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// userPermissionsServer serves the user permissions of demo-repo from the
+// multi-page fixtures, recording the start of the pages requested.
+func userPermissionsServer(t *testing.T, starts *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/rest/api/1.0/projects/JXP/repos/demo-repo/permissions/users" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.URL.Query().Get("limit") != "2" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		start := req.URL.Query().Get("start")
+		*starts = append(*starts, start)
+
+		dat, err := ioutil.ReadFile(fmt.Sprintf("../../../testdata/user-permissions-page-%s.json", start))
+		if err != nil {
+			t.Error(err)
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write(dat)
+	}))
+}
+
+func TestServerPages(t *testing.T) {
+	ctx := context.Background()
+	starts := []string{}
+	server := userPermissionsServer(t, &starts)
+	defer server.Close()
+
+	perms := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Paging:     PageOpts{Limit: 2},
+	}).Permissions()
+
+	all, err := perms.ListUserPermissions(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, el := range all {
+		names = append(names, el.User.Name)
+	}
+	if want := []string{"alice", "bobby", "bob", "carol", "dave"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	if want := []string{"0", "2", "4"}; !reflect.DeepEqual(starts, want) {
+		t.Fatalf("expected the pages %v, got %v", want, starts)
+	}
+}
+
+func TestServerPagesEarlyTermination(t *testing.T) {
+	ctx := context.Background()
+	starts := []string{}
+	server := userPermissionsServer(t, &starts)
+	defer server.Close()
+
+	perms := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Paging:     PageOpts{Limit: 2},
+	}).Permissions()
+
+	// bobby, on the first page, matches the filter too.
+	res, err := perms.GetUserPermissions(ctx, UserPermissionOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", User: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.User.Name != "bob" || res.Permission != "REPO_WRITE" {
+		t.Fatalf("unexpected permission: %+v", res)
+	}
+	if want := []string{"0", "2"}; !reflect.DeepEqual(starts, want) {
+		t.Fatalf("expected the pages %v, got %v", want, starts)
+	}
+}
+
+func TestServerPagesMaxItems(t *testing.T) {
+	ctx := context.Background()
+	starts := []string{}
+	server := userPermissionsServer(t, &starts)
+	defer server.Close()
+
+	perms := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Paging:     PageOpts{Limit: 2, MaxItems: 3},
+	}).Permissions()
+
+	all, err := perms.ListUserPermissions(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[2].User.Name != "bob" {
+		t.Fatalf("unexpected permissions: %+v", all)
+	}
+	if want := []string{"0", "2"}; !reflect.DeepEqual(starts, want) {
+		t.Fatalf("expected the pages %v, got %v", want, starts)
+	}
+}
+
+func TestServerPagesLookup(t *testing.T) {
+	ctx := context.Background()
+	starts := []string{}
+	server := userPermissionsServer(t, &starts)
+	defer server.Close()
+
+	perms := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Paging:     PageOpts{Limit: 2, MaxItems: 1},
+	}).Permissions()
+
+	// The lookups are not truncated.
+	res, err := perms.GetUserPermissions(ctx, UserPermissionOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", User: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.User.Name != "bob" {
+		t.Fatalf("unexpected permission: %+v", res)
+	}
+	if want := []string{"0", "2"}; !reflect.DeepEqual(starts, want) {
+		t.Fatalf("expected the pages %v, got %v", want, starts)
+	}
+}
+
+func TestCloudPages(t *testing.T) {
+	ctx := context.Background()
+	pages := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if req.URL.Path != "/2.0/repositories/acme" || q.Get("q") != `project.key="JXP"` || q.Get("pagelen") != "2" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pages = append(pages, q.Get("page"))
+		fn := "../../../testdata/cloud-repos-page-1.json"
+		if q.Get("page") == "AbCdEf" {
+			fn = "../../../testdata/cloud-repos-page-2.json"
+		}
+		dat, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Error(err)
+		}
+		rw.Write(dat)
+	}))
+	defer server.Close()
+
+	repos := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Flavor:     FlavorCloud,
+		Workspace:  "acme",
+		Paging:     PageOpts{Limit: 2},
+	}).Repos()
+
+	all, err := repos.List(ctx, "JXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slugs := []string{}
+	for _, el := range all {
+		slugs = append(slugs, el.Slug)
+	}
+	if want := []string{"demo-repo", "other-repo", "last-repo"}; !reflect.DeepEqual(slugs, want) {
+		t.Fatalf("expected %v, got %v", want, slugs)
+	}
+	if want := []string{"", "AbCdEf"}; !reflect.DeepEqual(pages, want) {
+		t.Fatalf("expected the pages %v, got %v", want, pages)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	ctx := context.Background()
+
+	// The second page does not move the cursor forward.
+	calls := 0
+	it := newIterator(func(ctx context.Context, cursor string, limit int) (*page, error) {
+		calls++
+		if limit != DefaultPageLimit {
+			t.Fatalf("expected the default limit, got %d", limit)
+		}
+		return &page{values: []json.RawMessage{json.RawMessage(`"` + cursor + `"`)}, next: "x"}, nil
+	}, PageOpts{})
+
+	all := []string{}
+	if err := it.All(ctx, &all); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "x"}; !reflect.DeepEqual(all, want) || calls != 2 {
+		t.Fatalf("expected %v in 2 calls, got %v in %d", want, all, calls)
+	}
+
+	boom := errors.New("boom")
+	it = newIterator(func(ctx context.Context, cursor string, limit int) (*page, error) {
+		return nil, boom
+	}, PageOpts{})
+	if it.Next(ctx) || !errors.Is(it.Err(), boom) {
+		t.Fatalf("expected the iteration to stop with the error, got %v", it.Err())
+	}
+}
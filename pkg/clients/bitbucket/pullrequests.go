@@ -0,0 +1,262 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	PullRequestStateOpen     = "OPEN"
+	PullRequestStateMerged   = "MERGED"
+	PullRequestStateDeclined = "DECLINED"
+)
+
+type PullRequestRef struct {
+	Id           string `json:"id"`
+	DisplayId    string `json:"displayId,omitempty"`
+	LatestCommit string `json:"latestCommit,omitempty"`
+	Repository   struct {
+		Slug    string `json:"slug"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"repository"`
+}
+
+type PullRequestParticipant struct {
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+	Approved bool   `json:"approved,omitempty"`
+	Status   string `json:"status,omitempty"`
+}
+
+type PullRequest struct {
+	Id          int                      `json:"id,omitempty"`
+	Version     int                      `json:"version"`
+	Title       string                   `json:"title"`
+	Description string                   `json:"description"`
+	State       string                   `json:"state,omitempty"`
+	FromRef     PullRequestRef           `json:"fromRef"`
+	ToRef       PullRequestRef           `json:"toRef"`
+	Reviewers   []PullRequestParticipant `json:"reviewers"`
+	Links       struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self,omitempty"`
+	} `json:"links,omitempty"`
+}
+
+// URL returns the web address of the pull request.
+func (pr *PullRequest) URL() string {
+	if len(pr.Links.Self) == 0 {
+		return ""
+	}
+	return pr.Links.Self[0].Href
+}
+
+type MergeStatus struct {
+	CanMerge   bool   `json:"canMerge"`
+	Conflicted bool   `json:"conflicted"`
+	Outcome    string `json:"outcome,omitempty"`
+	Vetoes     []struct {
+		SummaryMessage  string `json:"summaryMessage"`
+		DetailedMessage string `json:"detailedMessage,omitempty"`
+	} `json:"vetoes,omitempty"`
+}
+
+type BuildStats struct {
+	Successful int `json:"successful"`
+	InProgress int `json:"inProgress"`
+	Failed     int `json:"failed"`
+}
+
+// PullRequestService provides methods for managing pull requests.
+type PullRequestService struct {
+	api *api
+}
+
+type PullRequestOpts struct {
+	ProjectKey string
+	RepoSlug   string
+}
+
+// route returns the path format of the pull requests and its args.
+func (o PullRequestOpts) route() (string, []interface{}) {
+	return "/rest/api/1.0/projects/%s/repos/%s/pull-requests", []interface{}{o.ProjectKey, o.RepoSlug}
+}
+
+// NewPullRequestRef returns a reference to a branch of the repository.
+func NewPullRequestRef(opts PullRequestOpts, id string) PullRequestRef {
+	res := PullRequestRef{Id: id}
+	res.Repository.Slug = opts.RepoSlug
+	res.Repository.Project.Key = opts.ProjectKey
+	return res
+}
+
+// Get returns the pull request or nil if it does not exists.
+func (s *PullRequestService) Get(ctx context.Context, opts PullRequestOpts, id int) (*PullRequest, error) {
+	resp := &PullRequest{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route+"/%d", append(args, id)...).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Create opens a new pull request; it fails with ErrConflict if a pull
+// request between the same refs is already open, see Find. A description
+// unique to the pull request tells apart the one a failed attempt opened.
+func (s *PullRequestService) Create(ctx context.Context, opts PullRequestOpts, pr PullRequest) (*PullRequest, error) {
+	resp := &PullRequest{}
+
+	// A failed attempt may have opened the pull request: the open one
+	// between the same refs, with the same title and description.
+	check := func(ctx context.Context) (bool, error) {
+		res, err := s.Find(ctx, opts, pr.FromRef.Id, pr.ToRef.Id)
+		if res != nil && res.Title == pr.Title && res.Description == pr.Description {
+			*resp = *res
+			return true, nil
+		}
+		return false, err
+	}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPost, route, args...).
+		expect(200, 201)
+	req.Builder.
+		BodyJSON(pr).
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Find returns the open pull request from a ref to another one, or nil if none.
+func (s *PullRequestService) Find(ctx context.Context, opts PullRequestOpts, fromRef, toRef string) (*PullRequest, error) {
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route, args...).
+		expect(200).
+		unbounded()
+	req.Builder.
+		Param("at", fromRef).
+		Param("direction", "OUTGOING").
+		Param("state", PullRequestStateOpen)
+
+	it := s.api.serverPages(req)
+	for it.Next(ctx) {
+		res := &PullRequest{}
+		if err := it.Decode(res); err != nil {
+			return nil, err
+		}
+		if res.FromRef.Id == fromRef && res.ToRef.Id == toRef {
+			return res, nil
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// Update changes title, description, reviewers and target branch of the pull
+// request, the reviewers not listed being removed; pr.Version must match the
+// current version.
+func (s *PullRequestService) Update(ctx context.Context, opts PullRequestOpts, pr PullRequest) (*PullRequest, error) {
+	resp := &PullRequest{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPut, route+"/%d", append(args, pr.Id)...).
+		expect(200)
+	req.Builder.
+		BodyJSON(pr).
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// MergeStatus tests whether the pull request can be merged.
+func (s *PullRequestService) MergeStatus(ctx context.Context, opts PullRequestOpts, id int) (*MergeStatus, error) {
+	resp := &MergeStatus{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route+"/%d/merge", append(args, id)...).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Merge merges the pull request at the given version.
+func (s *PullRequestService) Merge(ctx context.Context, opts PullRequestOpts, id, version int) (*PullRequest, error) {
+	return s.transition(ctx, opts, id, version, "merge")
+}
+
+// Decline declines the pull request at the given version.
+func (s *PullRequestService) Decline(ctx context.Context, opts PullRequestOpts, id, version int) (*PullRequest, error) {
+	return s.transition(ctx, opts, id, version, "decline")
+}
+
+func (s *PullRequestService) transition(ctx context.Context, opts PullRequestOpts, id, version int, action string) (*PullRequest, error) {
+	resp := &PullRequest{}
+
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPost, route+"/%d/%s", append(args, id, action)...).
+		expect(200)
+	req.Builder.
+		ParamInt("version", version).
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// BuildStats returns the build results reported for a commit.
+func (s *PullRequestService) BuildStats(ctx context.Context, commitId string) (*BuildStats, error) {
+	resp := &BuildStats{}
+
+	req := s.api.newRequest(http.MethodGet, "/rest/build-status/1.0/commits/stats/%s", commitId).
+		expect(200)
+	req.Builder.ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
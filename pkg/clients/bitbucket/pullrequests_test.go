@@ -0,0 +1,87 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPullRequestMerge(t *testing.T) {
+	ctx := context.Background()
+	var version string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo/pull-requests/7/merge"; req.URL.Path != want || req.Method != http.MethodPost {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		version = req.URL.Query().Get("version")
+		rw.Write([]byte(`{"id":7,"version":3,"state":"MERGED","links":{"self":[{"href":"https://bitbucket.example.com/projects/JXP/repos/demo-repo/pull-requests/7"}]}}`))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	res, err := NewClient(co).PullRequests().Merge(ctx, PullRequestOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}, 7, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != "2" {
+		t.Fatalf("expected version '2', got: '%s'", version)
+	}
+	if res.State != PullRequestStateMerged {
+		t.Fatalf("expected state '%s', got: '%s'", PullRequestStateMerged, res.State)
+	}
+	if want := "https://bitbucket.example.com/projects/JXP/repos/demo-repo/pull-requests/7"; res.URL() != want {
+		t.Fatalf("expected url '%s', got: '%s'", want, res.URL())
+	}
+}
+
+func TestPullRequestFind(t *testing.T) {
+	ctx := context.Background()
+	var query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/api/1.0/projects/JXP/repos/demo-repo/pull-requests"; req.URL.Path != want || req.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := req.URL.Query()
+		query = q.Get("at") + " " + q.Get("direction") + " " + q.Get("state")
+		rw.Write([]byte(`{"values":[
+			{"id":3,"state":"OPEN","fromRef":{"id":"refs/heads/feature"},"toRef":{"id":"refs/heads/release"}},
+			{"id":4,"state":"OPEN","fromRef":{"id":"refs/heads/feature"},"toRef":{"id":"refs/heads/main"}}
+		],"isLastPage":true}`))
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+	opts := PullRequestOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	res, err := NewClient(co).PullRequests().Find(ctx, opts, "refs/heads/feature", "refs/heads/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Id != 4 {
+		t.Fatalf("expected pull request 4, got: %+v", res)
+	}
+	if want := "refs/heads/feature OUTGOING OPEN"; query != want {
+		t.Fatalf("expected query '%s', got: '%s'", want, query)
+	}
+
+	res, err = NewClient(co).PullRequests().Find(ctx, opts, "refs/heads/feature", "refs/heads/develop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != nil {
+		t.Fatalf("expected nil, got: %+v", res)
+	}
+}
@@ -0,0 +1,289 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// Request is a Bitbucket call going through the middlewares of the client.
+// Builder holds what is specific to the endpoint, i.e. the params, the
+// body and the response handler; the client adds the base URL, the HTTP
+// client, the credentials and the validation of the response.
+type Request struct {
+	Method string
+	// Route is the path of the endpoint before formatting, i.e.
+	// "/rest/api/1.0/projects/%s/repos/%s", with a bounded cardinality.
+	Route string
+	Path  string
+	// Accept lists the statuses of the successful responses.
+	Accept  []int
+	Builder *requests.Builder
+
+	// status is shared by the clones of the request.
+	status *int
+	check  checkFunc
+	stream bool
+	lookup bool
+}
+
+// Status returns the status of the last response, zero if none was received.
+func (r *Request) Status() int {
+	return *r.status
+}
+
+// expect sets the statuses of the successful responses.
+func (r *Request) expect(statuses ...int) *Request {
+	r.Accept = statuses
+	return r
+}
+
+// checking sets the check retrying the non-idempotent request on every
+// transient failure, if it reports the failed attempt had no effect.
+func (r *Request) checking(check checkFunc) *Request {
+	r.check = check
+	return r
+}
+
+// streaming marks the request writing its response body as it is
+// received: it is not retried, since a failed attempt may have written part
+// of the body already, nor bounded by the call timeout, but only by the
+// context it is made with.
+func (r *Request) streaming() *Request {
+	r.stream = true
+	return r
+}
+
+// unbounded marks the list request of a lookup, that must see every item:
+// it walks all the pages, whatever PageOpts.MaxItems.
+func (r *Request) unbounded() *Request {
+	r.lookup = true
+	return r
+}
+
+// clone returns a copy of the request, whose builder can be changed
+// without affecting the one of the original request; the middlewares
+// changing the builder are given a clone.
+func (r *Request) clone() *Request {
+	res := *r
+	res.Builder = r.Builder.Clone()
+	return &res
+}
+
+// Handler sends a request to Bitbucket.
+type Handler func(ctx context.Context, req *Request) error
+
+// Middleware wraps a Handler adding a behavior shared by all the calls.
+type Middleware func(next Handler) Handler
+
+// chain returns the handler calling the middlewares in order, the first
+// being the outermost, before the given one.
+func chain(handler Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
+
+// api sends the requests of the services of a client.
+type api struct {
+	baseURL string
+	client  *http.Client
+	handler Handler
+	paging  PageOpts
+}
+
+// newAPI returns the api sending the requests through the middlewares of
+// the options, then retrying them, bounding their attempts, adding the
+// credentials and decoding the errors of the responses.
+func newAPI(opts *ClientOpts) *api {
+	timeout := opts.CallTimeout
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+
+	auth := opts.Auth
+	if auth == nil {
+		if len(opts.Username) > 0 {
+			auth = BasicAuth(opts.Username, opts.Token)
+		} else {
+			auth = BearerToken(opts.Token)
+		}
+	}
+
+	mws := append([]Middleware{}, opts.Middlewares...)
+	mws = append(mws,
+		Retrying(opts.Retry),
+		Timeout(timeout),
+		Authenticating(auth),
+		DecodingErrors(),
+	)
+
+	return &api{
+		baseURL: opts.ApiBaseUrl,
+		client:  opts.HttpClient,
+		handler: chain(send, mws...),
+		paging:  opts.Paging,
+	}
+}
+
+// newRequest returns a request to the path formatted with the args.
+func (a *api) newRequest(method, route string, args ...interface{}) *Request {
+	path := fmt.Sprintf(route, args...)
+	return a.request(method, route, path, requests.URL(a.baseURL).Path(path))
+}
+
+// newEscapedRequest returns a request to the path formatted with the args
+// escaped with url.PathEscape, i.e. branches and file paths whose slashes
+// are not all separators; the path is sent as it is.
+func (a *api) newEscapedRequest(method, route string, args ...interface{}) *Request {
+	path := fmt.Sprintf(route, args...)
+
+	// The path replaces the one of the base URL, as Builder.Path does.
+	base := a.baseURL
+	if u, err := url.Parse(a.baseURL); err == nil {
+		base = (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+	}
+	return a.request(method, route, path, requests.URL(base+path))
+}
+
+// request returns a request to the path with the builder of its URL.
+func (a *api) request(method, route, path string, b *requests.Builder) *Request {
+	status := new(int)
+	return &Request{
+		Method: method,
+		Route:  route,
+		Path:   path,
+		// The status is recorded before validating the response.
+		Builder: b.
+			Method(method).
+			Client(a.client).
+			AddValidator(func(res *http.Response) error {
+				*status = res.StatusCode
+				return nil
+			}),
+		status: status,
+	}
+}
+
+// do sends the request through the middlewares.
+func (a *api) do(ctx context.Context, req *Request) error {
+	return a.handler(ctx, req.clone())
+}
+
+// send is the innermost handler, sending the request once.
+func send(ctx context.Context, req *Request) error {
+	r, err := req.Builder.Request(ctx)
+	if err != nil {
+		return err
+	}
+	return req.Builder.Do(r)
+}
+
+// DecodingErrors fails the requests whose response has not an accepted
+// status with the error reported by Bitbucket, see ErrorHandler.
+func DecodingErrors() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			req.Builder.AddValidator(ErrorHandler(req.Accept...))
+			return next(ctx, req)
+		}
+	}
+}
+
+// Timeout bounds the requests, if positive, within the deadline of their
+// context; the streaming requests are bounded by their context only.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			if d <= 0 || req.stream {
+				return next(ctx, req)
+			}
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, req)
+		}
+	}
+}
+
+// Limiter blocks until a request is allowed or the context is done;
+// it is implemented by golang.org/x/time/rate.Limiter.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// RateLimiting waits for the limiter to allow the requests.
+func RateLimiting(l Limiter) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			if err := l.Wait(ctx); err != nil {
+				return err
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// Logging logs the requests with their outcome and duration;
+// log is i.e. the Debug method of a logging.Logger.
+func Logging(log func(msg string, keysAndValues ...interface{})) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			start := time.Now()
+			err := next(ctx, req)
+
+			kv := []interface{}{"method", req.Method, "path", req.Path,
+				"status", req.Status(), "duration", time.Since(start).String()}
+			if err != nil {
+				kv = append(kv, "error", err.Error())
+			}
+			log("Bitbucket call", kv...)
+			return err
+		}
+	}
+}
+
+// CallStats describes a completed request.
+type CallStats struct {
+	Method   string
+	Route    string
+	Status   int
+	Duration time.Duration
+	Err      error
+}
+
+// Instrumenting reports the completed requests to observe, i.e. to collect metrics.
+func Instrumenting(observe func(CallStats)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			start := time.Now()
+			err := next(ctx, req)
+
+			observe(CallStats{
+				Method:   req.Method,
+				Route:    req.Route,
+				Status:   req.Status(),
+				Duration: time.Since(start),
+				Err:      err,
+			})
+			return err
+		}
+	}
+}
+
+// Tracing calls start before every request, i.e. to open a span, and the
+// function it returns with the outcome of the request once completed.
+func Tracing(start func(ctx context.Context, req *Request) (context.Context, func(err error))) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			ctx, end := start(ctx, req)
+			err := next(ctx, req)
+			end(err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type countingLimiter struct {
+	waits int
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return nil
+}
+
+func TestMiddlewares(t *testing.T) {
+	ctx := context.Background()
+
+	auths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		auths = append(auths, req.Header.Get("Authorization"))
+		if len(auths) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte(`{"slug":"demo-repo","project":{"key":"JXP"}}`))
+	}))
+	defer server.Close()
+
+	order := []string{}
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) error {
+				order = append(order, name)
+				return next(ctx, req)
+			}
+		}
+	}
+
+	stats := []CallStats{}
+	limiter := &countingLimiter{}
+	repos := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Token:      "secret",
+		Retry:      RetryOpts{BaseDelay: time.Millisecond},
+		Middlewares: []Middleware{
+			trace("outer"),
+			Instrumenting(func(st CallStats) { stats = append(stats, st) }),
+			RateLimiting(limiter),
+			trace("inner"),
+		},
+	}).Repos()
+
+	res, err := repos.Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Slug != "demo-repo" {
+		t.Fatalf("unexpected repo: %+v", res)
+	}
+
+	// The middlewares of the options wrap the call, retries included.
+	if want := []string{"outer", "inner"}; !reflect.DeepEqual(order, want) || limiter.waits != 1 {
+		t.Fatalf("expected the middlewares %v called once, got %v (%d waits)", want, order, limiter.waits)
+	}
+	if want := []string{"Bearer secret", "Bearer secret"}; !reflect.DeepEqual(auths, want) {
+		t.Fatalf("expected the credentials on every attempt, got %v", auths)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected the stats of one call, got %+v", stats)
+	}
+	if st := stats[0]; st.Method != http.MethodGet || st.Route != "/rest/api/1.0/projects/%s/repos/%s" || st.Status != 200 || st.Err != nil {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestMiddlewaresFailure(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "jdoe" || pass != "secret" {
+			t.Errorf("expected basic auth, got %q", req.Header.Get("Authorization"))
+		}
+		rw.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	spans := []error{}
+	logged := []interface{}{}
+	hooks := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Username:   "jdoe",
+		Token:      "secret",
+		Middlewares: []Middleware{
+			Tracing(func(ctx context.Context, req *Request) (context.Context, func(error)) {
+				return ctx, func(err error) { spans = append(spans, err) }
+			}),
+			Logging(func(msg string, kv ...interface{}) { logged = kv }),
+		},
+	}).Hooks()
+
+	_, err := hooks.Enable(ctx, HookOpts{ProjectKey: "JXP", HookKey: "force-push"})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected a forbidden error, got %v", err)
+	}
+	if len(spans) != 1 || !errors.Is(spans[0], ErrForbidden) {
+		t.Fatalf("expected the span ended with the error, got %v", spans)
+	}
+	if len(logged) < 6 || logged[3] != "/rest/api/1.0/projects/JXP/settings/hooks/force-push/enabled" || logged[5] != http.StatusForbidden {
+		t.Fatalf("unexpected log: %v", logged)
+	}
+}
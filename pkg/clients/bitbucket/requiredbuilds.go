@@ -0,0 +1,213 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	RefMatcherAnyRef   = "ANY_REF"
+	RefMatcherAnyRefId = "ANY_REF_MATCHER_ID"
+)
+
+type RefMatcher struct {
+	Id        string `json:"id"`
+	DisplayId string `json:"displayId,omitempty"`
+	Type      struct {
+		Id   string `json:"id"`
+		Name string `json:"name,omitempty"`
+	} `json:"type"`
+}
+
+// NewRefMatcher returns a ref matcher of the specified type.
+func NewRefMatcher(typ, id string) *RefMatcher {
+	if typ == RefMatcherAnyRef && len(id) == 0 {
+		id = RefMatcherAnyRefId
+	}
+
+	res := &RefMatcher{Id: id}
+	res.Type.Id = typ
+	return res
+}
+
+type RequiredBuildCondition struct {
+	Id               int         `json:"id,omitempty"`
+	BuildParentKeys  []string    `json:"buildParentKeys"`
+	RefMatcher       *RefMatcher `json:"refMatcher"`
+	SourceRefMatcher *RefMatcher `json:"sourceRefMatcher,omitempty"`
+	ExemptRefMatcher *RefMatcher `json:"exemptRefMatcher,omitempty"`
+}
+
+// RequiredBuildsService provides methods for managing
+// the required builds merge checks.
+type RequiredBuildsService struct {
+	api *api
+}
+
+type RequiredBuildsOpts struct {
+	ProjectKey string
+	// RepoSlug is optional: when empty the conditions are managed at project level.
+	RepoSlug string
+}
+
+// route returns the path format of the project or repository and its args;
+// the conditions are listed at "/conditions", while a condition is created
+// at "/condition" and changed at "/condition/{id}".
+func (o RequiredBuildsOpts) route() (string, []interface{}) {
+	if len(o.RepoSlug) == 0 {
+		return "/rest/required-builds/latest/projects/%s", []interface{}{o.ProjectKey}
+	}
+	return "/rest/required-builds/latest/projects/%s/repos/%s", []interface{}{o.ProjectKey, o.RepoSlug}
+}
+
+// List returns all the required builds conditions.
+func (s *RequiredBuildsService) List(ctx context.Context, opts RequiredBuildsOpts) ([]RequiredBuildCondition, error) {
+	return s.list(ctx, opts, false)
+}
+
+// list returns the required builds conditions, all of them for a lookup.
+func (s *RequiredBuildsService) list(ctx context.Context, opts RequiredBuildsOpts, lookup bool) ([]RequiredBuildCondition, error) {
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodGet, route+"/conditions", args...).
+		expect(200)
+	if lookup {
+		req.unbounded()
+	}
+
+	all := []RequiredBuildCondition{}
+	err := s.api.serverPages(req).All(ctx, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// Get returns the required builds condition or nil if not found.
+func (s *RequiredBuildsService) Get(ctx context.Context, opts RequiredBuildsOpts, id int) (*RequiredBuildCondition, error) {
+	all, err := s.list(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		if all[i].Id == id {
+			return &all[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Create adds a new required builds condition; since Bitbucket accepts
+// duplicates, it fails with ErrConflict if an identical condition exists.
+func (s *RequiredBuildsService) Create(ctx context.Context, opts RequiredBuildsOpts, cond RequiredBuildCondition) (*RequiredBuildCondition, error) {
+	resp := &RequiredBuildCondition{}
+
+	all, err := s.list(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[int]bool{}
+	for i := range all {
+		if IsSameCondition(&all[i], &cond) {
+			return nil, fmt.Errorf("identical required builds condition '%d' exists: %w", all[i].Id, ErrConflict)
+		}
+		existing[all[i].Id] = true
+	}
+
+	// A failed attempt may have created the condition: it is the
+	// identical one that did not exist before.
+	check := func(ctx context.Context) (bool, error) {
+		all, err := s.list(ctx, opts, true)
+		for i := range all {
+			if !existing[all[i].Id] && IsSameCondition(&all[i], &cond) {
+				*resp = all[i]
+				return true, nil
+			}
+		}
+		return false, err
+	}
+
+	cond.Id = 0
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPost, route+"/condition", args...).
+		expect(200, 201)
+	req.Builder.
+		BodyJSON(cond).
+		ToJSON(resp)
+
+	err = s.api.do(ctx, req.checking(check))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Update replaces the required builds condition identified by id.
+func (s *RequiredBuildsService) Update(ctx context.Context, opts RequiredBuildsOpts, id int, cond RequiredBuildCondition) (*RequiredBuildCondition, error) {
+	resp := &RequiredBuildCondition{}
+
+	cond.Id = 0
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodPut, route+"/condition/%d", append(args, id)...).
+		expect(200)
+	req.Builder.
+		BodyJSON(cond).
+		ToJSON(resp)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Delete removes the required builds condition identified by id.
+func (s *RequiredBuildsService) Delete(ctx context.Context, opts RequiredBuildsOpts, id int) error {
+	route, args := opts.route()
+	req := s.api.newRequest(http.MethodDelete, route+"/condition/%d", append(args, id)...).
+		expect(200, 202, 204)
+
+	err := s.api.do(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// IsSameCondition reports whether the conditions require the same builds
+// on the same branches, whatever their identifiers and the keys order.
+func IsSameCondition(a, b *RequiredBuildCondition) bool {
+	if len(a.BuildParentKeys) != len(b.BuildParentKeys) {
+		return false
+	}
+	keys := map[string]bool{}
+	for _, el := range a.BuildParentKeys {
+		keys[el] = true
+	}
+	for _, el := range b.BuildParentKeys {
+		if !keys[el] {
+			return false
+		}
+	}
+
+	return isSameRefMatcher(a.RefMatcher, b.RefMatcher) &&
+		isSameRefMatcher(a.SourceRefMatcher, b.SourceRefMatcher) &&
+		isSameRefMatcher(a.ExemptRefMatcher, b.ExemptRefMatcher)
+}
+
+func isSameRefMatcher(a, b *RefMatcher) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Id == b.Id && a.Type.Id == b.Type.Id
+}
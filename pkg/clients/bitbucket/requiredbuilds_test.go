@@ -0,0 +1,130 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRequiredBuildsGet(t *testing.T) {
+	ctx := context.Background()
+	dat, err := ioutil.ReadFile("../../../testdata/required-builds-list.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if want := "/rest/required-builds/latest/projects/JXP/repos/demo-repo/conditions"; req.URL.Path != want {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write(dat)
+	}))
+	defer server.Close()
+
+	co := &ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}
+
+	res, err := NewClient(co).RequiredBuilds().Get(ctx, RequiredBuildsOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil {
+		t.Fatalf("expecting condition 17, got nil")
+	}
+	if res.RefMatcher.Type.Id != "BRANCH" || res.ExemptRefMatcher.Id != "hotfix/*" {
+		t.Fatalf("unexpected condition: %+v", res)
+	}
+
+	res, err = NewClient(co).RequiredBuilds().Get(ctx, RequiredBuildsOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != nil {
+		t.Fatalf("expecting nil, got %+v", res)
+	}
+}
+
+func TestRequiredBuildsRoutes(t *testing.T) {
+	ctx := context.Background()
+
+	calls := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls = append(calls, req.Method+" "+req.URL.Path)
+		switch req.Method {
+		case http.MethodGet:
+			rw.Write([]byte(`{"values": [], "isLastPage": true}`))
+		case http.MethodDelete:
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			rw.Write([]byte(`{"id": 17}`))
+		}
+	}))
+	defer server.Close()
+
+	svc := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}).RequiredBuilds()
+
+	cond := RequiredBuildCondition{
+		BuildParentKeys: []string{"build"},
+		RefMatcher:      NewRefMatcher(RefMatcherAnyRef, ""),
+	}
+	opts := RequiredBuildsOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	if _, err := svc.Create(ctx, opts, cond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Update(ctx, opts, 17, cond); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, RequiredBuildsOpts{ProjectKey: "JXP"}, 17); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /rest/required-builds/latest/projects/JXP/repos/demo-repo/conditions",
+		"POST /rest/required-builds/latest/projects/JXP/repos/demo-repo/condition",
+		"PUT /rest/required-builds/latest/projects/JXP/repos/demo-repo/condition/17",
+		"DELETE /rest/required-builds/latest/projects/JXP/condition/17",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("expecting calls %v, got %v", want, calls)
+	}
+}
+
+func TestRequiredBuildsCreateExisting(t *testing.T) {
+	ctx := context.Background()
+
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			rw.Write([]byte(`{"values": [{"id": 17, "buildParentKeys": ["test", "build"], "refMatcher": {"id": "ANY_REF_MATCHER_ID", "type": {"id": "ANY_REF"}}}], "isLastPage": true}`))
+		default:
+			posts++
+			rw.Write([]byte(`{"id": 18}`))
+		}
+	}))
+	defer server.Close()
+
+	svc := NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+	}).RequiredBuilds()
+
+	_, err := svc.Create(ctx, RequiredBuildsOpts{ProjectKey: "JXP"}, RequiredBuildCondition{
+		BuildParentKeys: []string{"build", "test"},
+		RefMatcher:      NewRefMatcher(RefMatcherAnyRef, ""),
+	})
+	if !errors.Is(err, ErrConflict) || posts != 0 {
+		t.Fatalf("expecting a conflict with the existing condition without posting, got %v after %d posts", err, posts)
+	}
+}
@@ -0,0 +1,226 @@
+package bitbucket
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Retry defaults.
+const (
+	DefaultMaxAttempts    = 4
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryOpts controls the retries of the calls failing for transient reasons:
+// throttling (429), unavailability (502, 503, 504) and network errors.
+//
+// Idempotent calls are retried on every transient failure; the others (POST)
+// only when throttled, since Bitbucket did not process the request, unless
+// they check, before retrying, whether the failed attempt took effect.
+type RetryOpts struct {
+	// MaxAttempts bounds the attempts of a call, the first included
+	// (default: DefaultMaxAttempts); 1 disables the retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled at each
+	// following one and jittered (default: DefaultRetryBaseDelay).
+	BaseDelay time.Duration
+	// MaxDelay bounds the delay before a retry (default: DefaultRetryMaxDelay);
+	// a call whose Retry-After exceeds it fails without being retried.
+	MaxDelay time.Duration
+	// OnRetry, if set, is called before waiting to retry a call.
+	OnRetry func(RetryEvent)
+}
+
+func (o RetryOpts) withDefaults() RetryOpts {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = DefaultRetryBaseDelay
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = DefaultRetryMaxDelay
+	}
+	return o
+}
+
+// RetryEvent describes a failed attempt of a call about to be retried.
+type RetryEvent struct {
+	Method string
+	Path   string
+	// Attempt is the number of the failed attempt, starting from 1.
+	Attempt     int
+	MaxAttempts int
+	// Delay is the time waited before the next attempt.
+	Delay time.Duration
+	// Throttled reports whether Bitbucket rate limited the call.
+	Throttled bool
+	Err       error
+}
+
+// checkFunc reports whether a failed attempt of a call took effect anyway,
+// in which case the call is not retried.
+type checkFunc func(ctx context.Context) (bool, error)
+
+// Retrying retries the requests failing for transient reasons, sending
+// every attempt with a clone of the request; the streaming requests are
+// never retried.
+func Retrying(opts RetryOpts) Middleware {
+	opts = opts.withDefaults()
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) error {
+			if req.stream {
+				return next(ctx, req)
+			}
+
+			for attempt := 1; ; attempt++ {
+				err := next(ctx, req.clone())
+				if err == nil || ctx.Err() != nil || attempt >= opts.MaxAttempts {
+					return err
+				}
+
+				throttled, transient := isTransient(err)
+				if !transient || (!throttled && !isIdempotent(req.Method) && req.check == nil) {
+					return err
+				}
+
+				delay, ok := opts.delay(attempt, err)
+				if !ok {
+					return err
+				}
+
+				if opts.OnRetry != nil {
+					opts.OnRetry(RetryEvent{
+						Method:      req.Method,
+						Path:        req.Path,
+						Attempt:     attempt,
+						MaxAttempts: opts.MaxAttempts,
+						Delay:       delay,
+						Throttled:   throttled,
+						Err:         err,
+					})
+				}
+
+				t := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return err
+				case <-t.C:
+				}
+
+				if !throttled && !isIdempotent(req.Method) {
+					done, cerr := req.check(ctx)
+					if cerr != nil {
+						return err
+					}
+					if done {
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// delay returns the jittered exponential delay before retrying the failed
+// attempt, or the Retry-After of the error if longer; it reports false
+// when the Retry-After exceeds the maximum delay.
+func (o RetryOpts) delay(attempt int, err error) (time.Duration, bool) {
+	res := o.BaseDelay << (attempt - 1)
+	if res <= 0 || res > o.MaxDelay {
+		res = o.MaxDelay
+	}
+	res = res/2 + time.Duration(rand.Int63n(int64(res/2)+1))
+
+	var e StatusError
+	if errors.As(err, &e) && e.RetryAfter > res {
+		if e.RetryAfter > o.MaxDelay {
+			return 0, false
+		}
+		res = e.RetryAfter
+	}
+	return res, true
+}
+
+// isTransient reports whether the error may not occur again retrying the
+// call, and whether it is due to throttling.
+func isTransient(err error) (throttled, transient bool) {
+	var e StatusError
+	if errors.As(err, &e) {
+		switch e.Code {
+		case http.StatusTooManyRequests:
+			return true, true
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return false, true
+		}
+		return false, false
+	}
+
+	// Invalid certificates and TLS handshakes fail again.
+	if isTLSError(err) {
+		return false, false
+	}
+
+	// Timeouts of the attempts and network errors; the other url.Errors, such as
+	// an invalid URL or a redirect refused, fail again.
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		return false, false
+	}
+	var oe *net.OpError
+	return false, ue.Timeout() || errors.As(ue.Err, &oe) ||
+		errors.Is(ue.Err, io.EOF) || errors.Is(ue.Err, io.ErrUnexpectedEOF)
+}
+
+// isTLSError tells whether the error comes from the verification of the
+// server certificate or from a server not speaking TLS.
+func isTLSError(err error) bool {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalid          x509.CertificateInvalidError
+		hostname         x509.HostnameError
+		systemRoots      x509.SystemRootsError
+		recordHeader     tls.RecordHeaderError
+	)
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalid) ||
+		errors.As(err, &hostname) || errors.As(err, &systemRoots) ||
+		errors.As(err, &recordHeader)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header, either a number
+// of seconds or a date; it returns zero if missing or invalid.
+func retryAfter(h http.Header) time.Duration {
+	val := h.Get("Retry-After")
+	if len(val) == 0 {
+		return 0
+	}
+	if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
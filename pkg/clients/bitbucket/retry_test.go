@@ -0,0 +1,253 @@
+package bitbucket
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRetryingClient(server *httptest.Server, events *[]RetryEvent) Interface {
+	return NewClient(&ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Retry: RetryOpts{
+			BaseDelay: time.Millisecond,
+			MaxDelay:  2 * time.Second,
+			OnRetry: func(ev RetryEvent) {
+				*events = append(*events, ev)
+			},
+		},
+	})
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			rw.Write([]byte(`{"errors":[{"message":"Rate limit exceeded"}]}`))
+		default:
+			rw.Write([]byte(`{"slug":"demo-repo","project":{"key":"JXP"}}`))
+		}
+	}))
+	defer server.Close()
+
+	events := []RetryEvent{}
+	res, err := newRetryingClient(server, &events).Repos().Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Slug != "demo-repo" || calls != 3 {
+		t.Fatalf("expected the repo at the third attempt, got %+v at the attempt %d", res, calls)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 retries, got %+v", events)
+	}
+	if ev := events[0]; ev.Throttled || ev.Attempt != 1 || ev.Method != http.MethodGet || ev.Path != "/rest/api/1.0/projects/JXP/repos/demo-repo" {
+		t.Fatalf("unexpected retry: %+v", ev)
+	}
+	if ev := events[1]; !ev.Throttled || ev.Attempt != 2 || ev.Delay != time.Second || ev.Err.Error() != "Rate limit exceeded" {
+		t.Fatalf("unexpected retry: %+v", ev)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		status     int
+		retryAfter string
+		calls      int
+	}{
+		"MaxAttempts":       {status: http.StatusBadGateway, calls: DefaultMaxAttempts},
+		"NotTransient":      {status: http.StatusBadRequest, calls: 1},
+		"RetryAfterTooLong": {status: http.StatusTooManyRequests, retryAfter: "60", calls: 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				calls++
+				if len(tc.retryAfter) > 0 {
+					rw.Header().Set("Retry-After", tc.retryAfter)
+				}
+				rw.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			events := []RetryEvent{}
+			_, err := newRetryingClient(server, &events).Repos().Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if calls != tc.calls || len(events) != tc.calls-1 {
+				t.Fatalf("expected %d attempts, got %d (%d retries)", tc.calls, calls, len(events))
+			}
+		})
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	// Pull requests merging is retried only when throttled.
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		posts++
+		if posts == 1 {
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	events := []RetryEvent{}
+	_, err := newRetryingClient(server, &events).PullRequests().Merge(ctx, PullRequestOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}, 1, 0)
+	if err == nil || posts != 2 || len(events) != 1 {
+		t.Fatalf("expected a failure at the second attempt, got %v at the attempt %d", err, posts)
+	}
+}
+
+func TestRetryCheckBeforeRetry(t *testing.T) {
+	ctx := context.Background()
+
+	// The repository is created, but the response is lost.
+	posts, created := 0, false
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost:
+			posts++
+			created = true
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		case http.MethodGet:
+			if !created {
+				rw.Write([]byte(`{"isLastPage":true,"values":[]}`))
+				return
+			}
+			rw.Write([]byte(`{"isLastPage":true,"values":[{"slug":"demo-repo","name":"demo-repo","project":{"key":"JXP"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	events := []RetryEvent{}
+	res, err := newRetryingClient(server, &events).Repos().Create(ctx, CreateRepoOpts{Name: "demo-repo", ProjectKey: "JXP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Slug != "demo-repo" || posts != 1 {
+		t.Fatalf("expected the repo created by the first attempt, got %+v after %d attempts", res, posts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[string]struct {
+		val  string
+		want time.Duration
+	}{
+		"Missing": {val: "", want: 0},
+		"Seconds": {val: "3", want: 3 * time.Second},
+		"Invalid": {val: "soon", want: 0},
+		"Past":    {val: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("Retry-After", tc.val)
+			if got := retryAfter(h); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	h := http.Header{}
+	h.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if got := retryAfter(h); got <= 50*time.Second || got > time.Minute {
+		t.Fatalf("expected about a minute, got %v", got)
+	}
+}
+
+func TestRetryTLSErrors(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+	})
+
+	// The certificate of the server is not trusted.
+	untrusted := httptest.NewUnstartedServer(handler)
+	untrusted.Config.ErrorLog = log.New(io.Discard, "", 0)
+	untrusted.StartTLS()
+	defer untrusted.Close()
+
+	// The server does not speak TLS.
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+
+	for name, apiUrl := range map[string]string{
+		"UnknownAuthority": untrusted.URL,
+		"RecordHeader":     strings.Replace(plain.URL, "http:", "https:", 1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			events := []RetryEvent{}
+			_, err := NewClient(&ClientOpts{
+				ApiBaseUrl: apiUrl,
+				Retry: RetryOpts{
+					BaseDelay: time.Millisecond,
+					OnRetry: func(ev RetryEvent) {
+						events = append(events, ev)
+					},
+				},
+			}).Repos().Get(ctx, GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+			if err == nil || len(events) != 0 {
+				t.Fatalf("expected a failure without retries, got %v after %d retries", err, len(events))
+			}
+		})
+	}
+
+	if calls != 0 {
+		t.Fatalf("expected no call served, got %d", calls)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := map[string]struct {
+		err       error
+		transient bool
+	}{
+		"Timeout":           {err: &url.Error{Op: "Get", URL: "/", Err: context.DeadlineExceeded}, transient: true},
+		"ConnectionRefused": {err: &url.Error{Op: "Get", URL: "/", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, transient: true},
+		"UnexpectedEOF":     {err: &url.Error{Op: "Get", URL: "/", Err: io.ErrUnexpectedEOF}, transient: true},
+		"UnsupportedScheme": {err: &url.Error{Op: "Get", URL: "/", Err: errors.New(`unsupported protocol scheme ""`)}},
+		"Hostname":          {err: &url.Error{Op: "Get", URL: "/", Err: x509.HostnameError{Host: "bitbucket.example.com"}}},
+		"Other":             {err: errors.New("cannot decode")},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, transient := isTransient(tc.err); transient != tc.transient {
+				t.Fatalf("expected transient %t, got %t", tc.transient, transient)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package bitbucket
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/carlmjohnson/requests"
 )
@@ -15,11 +18,20 @@ type BitbucketError struct {
 		Message       string `json:"message,omitempty"`
 		ExceptionName string `json:"exceptionName,omitempty"`
 	} `json:"errors,omitempty"`
+	// Detail is the error reported by Bitbucket Cloud.
+	Detail *struct {
+		Message string              `json:"message,omitempty"`
+		Fields  map[string][]string `json:"fields,omitempty"`
+	} `json:"error,omitempty"`
 }
 
 type StatusError struct {
 	Code  int
 	Inner error
+	// ExceptionName is the exception reported by Bitbucket Server, if any.
+	ExceptionName string
+	// RetryAfter is the delay Bitbucket asks to wait before retrying, if any.
+	RetryAfter time.Duration
 }
 
 func (e StatusError) Error() string {
@@ -44,24 +56,58 @@ func ErrorHandler(acceptStatuses ...int) requests.ResponseHandler {
 			}
 		}
 
-		if res.Body == nil {
-			return StatusError{Code: res.StatusCode}
-		}
+		err := statusError(res)
+		err.RetryAfter = retryAfter(res.Header)
+		return err
+	}
+}
 
-		data, err := io.ReadAll(res.Body)
-		if err != nil {
-			return StatusError{Code: res.StatusCode, Inner: err}
-		}
+// statusError returns the error of the response, with the message reported by Bitbucket.
+func statusError(res *http.Response) StatusError {
+	if res.Body == nil {
+		return StatusError{Code: res.StatusCode}
+	}
 
-		var ex BitbucketError
-		if err = json.Unmarshal(data, &ex); err != nil {
-			return StatusError{Code: res.StatusCode, Inner: err}
-		}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return StatusError{Code: res.StatusCode, Inner: err}
+	}
 
-		if len(ex.Errors) == 0 {
-			return StatusError{Code: res.StatusCode}
+	var ex BitbucketError
+	if err = json.Unmarshal(data, &ex); err != nil {
+		return StatusError{Code: res.StatusCode, Inner: err}
+	}
+
+	se := StatusError{Code: res.StatusCode}
+	verr := &ValidationError{}
+	switch {
+	case len(ex.Errors) > 0:
+		se.ExceptionName = ex.Errors[0].ExceptionName
+		for _, el := range ex.Errors {
+			verr.Fields = append(verr.Fields, FieldError{Field: el.Context, Message: el.Message})
+		}
+	case ex.Detail != nil && len(ex.Detail.Message) > 0:
+		verr.Fields = append(verr.Fields, FieldError{Message: ex.Detail.Message})
+		fields := make([]string, 0, len(ex.Detail.Fields))
+		for field := range ex.Detail.Fields {
+			fields = append(fields, field)
 		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			for _, msg := range ex.Detail.Fields[field] {
+				verr.Fields = append(verr.Fields, FieldError{Field: field, Message: msg})
+			}
+		}
+	default:
+		return se
+	}
 
-		return StatusError{Code: res.StatusCode, Inner: fmt.Errorf(ex.Errors[0].Message)}
+	// The first message is the error of the call, the others
+	// are kept only for the fields rejected by the validation.
+	if errors.Is(se, ErrValidation) {
+		se.Inner = verr
+	} else {
+		se.Inner = errors.New(verr.Fields[0].Message)
 	}
+	return se
 }
@@ -0,0 +1,241 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// clientOpts caches the client options built from the ProviderConfigs by UID,
+// so that their HTTP clients, and the connections they keep alive, are reused
+// across the reconciliations; see ConfigVersion for when they are rebuilt,
+// and DropClientOpts for when they are dropped.
+var clientOpts = struct {
+	sync.Mutex
+	entries map[types.UID]cachedOpts
+}{entries: map[types.UID]cachedOpts{}}
+
+type cachedOpts struct {
+	name    string
+	version string
+	opts    *bitbucket.ClientOpts
+}
+
+// close closes the idle connections of the HTTP client of the dropped
+// options; the calls in flight are not affected.
+func (e cachedOpts) close() {
+	if cli := e.opts.HttpClient; cli != nil {
+		cli.CloseIdleConnections()
+	}
+}
+
+// ConfigVersion returns the version of the spec and of the credentials of the
+// ProviderConfig the client options are built from: its generation, which
+// unlike the resource version is not changed by the updates of the status,
+// the resource version of the credentials Secret or the modification time
+// of the credentials file, and the resource versions of the TLS certificates
+// and of the proxy credentials.
+func ConfigVersion(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (string, error) {
+	creds := pc.Spec.Credentials
+	res := fmt.Sprintf("%d", pc.GetGeneration())
+
+	switch creds.Source {
+	case xpv1.CredentialsSourceSecret:
+		if ref := creds.SecretRef; ref != nil {
+			s := &corev1.Secret{}
+			if err := k.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+				return "", errors.Wrapf(err, "cannot get %s secret", ref.Name)
+			}
+			res = fmt.Sprintf("%s/%s", res, s.GetResourceVersion())
+		}
+	case xpv1.CredentialsSourceFilesystem:
+		if fs := creds.Fs; fs != nil {
+			fi, err := os.Stat(fs.Path)
+			if err != nil {
+				return "", errors.Wrap(err, "cannot read Filesystem credentials")
+			}
+			res = fmt.Sprintf("%s/%d-%d", res, fi.ModTime().UnixNano(), fi.Size())
+		}
+	}
+
+	// The TLS certificates and the proxy credentials are rotated as well.
+	refs := []client.Object{}
+	if t := pc.Spec.TLS; t != nil {
+		for _, ref := range []*xpv1.SecretKeySelector{t.ClientCertSecretRef, t.ClientKeySecretRef} {
+			if ref != nil {
+				refs = append(refs, secretRef(ref))
+			}
+		}
+		if ca := t.CA; ca != nil {
+			switch {
+			case ca.SecretRef != nil:
+				refs = append(refs, secretRef(ca.SecretRef))
+			case ca.ConfigMapRef != nil:
+				cm := &corev1.ConfigMap{}
+				cm.SetNamespace(ca.ConfigMapRef.Namespace)
+				cm.SetName(ca.ConfigMapRef.Name)
+				refs = append(refs, cm)
+			}
+		}
+	}
+	if t := pc.Spec.Transport; t != nil && t.Proxy != nil && t.Proxy.CredentialsSecretRef != nil {
+		refs = append(refs, secretRef(t.Proxy.CredentialsSecretRef))
+	}
+
+	for _, obj := range refs {
+		if err := k.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			return "", errors.Wrapf(err, "cannot get %s", obj.GetName())
+		}
+		res = fmt.Sprintf("%s/%s", res, obj.GetResourceVersion())
+	}
+
+	return res, nil
+}
+
+func secretRef(ref *xpv1.SecretKeySelector) client.Object {
+	res := &corev1.Secret{}
+	res.SetNamespace(ref.Namespace)
+	res.SetName(ref.Name)
+	return res
+}
+
+// cachedClientOpts returns a copy of the client options of the ProviderConfig,
+// building them again when its version changed.
+func cachedClientOpts(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (*bitbucket.ClientOpts, error) {
+	version, err := ConfigVersion(ctx, k, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts.Lock()
+	e, ok := clientOpts.entries[pc.GetUID()]
+	clientOpts.Unlock()
+
+	if !ok || e.version != version {
+		opts, err := newClientOpts(ctx, k, pc)
+		if err != nil {
+			return nil, err
+		}
+
+		e = cachedOpts{name: pc.GetName(), version: version, opts: opts}
+		clientOpts.Lock()
+		old, replaced := clientOpts.entries[pc.GetUID()]
+		clientOpts.entries[pc.GetUID()] = e
+		clientOpts.Unlock()
+
+		if replaced {
+			old.close()
+		}
+	}
+
+	// The copy is changed by the managed resources, i.e. with Instrument.
+	res := *e.opts
+	res.Middlewares = append([]bitbucket.Middleware{}, e.opts.Middlewares...)
+	return &res, nil
+}
+
+// InvalidateClientOpts drops the client options of the ProviderConfig if
+// built from a version other than the given one; it reports whether they were.
+func InvalidateClientOpts(uid types.UID, version string) bool {
+	clientOpts.Lock()
+	defer clientOpts.Unlock()
+
+	e, ok := clientOpts.entries[uid]
+	if !ok || e.version == version {
+		return false
+	}
+	delete(clientOpts.entries, uid)
+	e.close()
+	return true
+}
+
+// DropClientOpts drops the client options of the ProviderConfigs with the
+// name other than the one with the given UID, if any: the deleted ones, or
+// the ones replaced by a ProviderConfig with the same name; it returns how
+// many were dropped.
+func DropClientOpts(name string, uid types.UID) int {
+	clientOpts.Lock()
+	defer clientOpts.Unlock()
+
+	res := 0
+	for k, e := range clientOpts.entries {
+		if e.name != name || k == uid {
+			continue
+		}
+		delete(clientOpts.entries, k)
+		e.close()
+		res++
+	}
+	return res
+}
+
+// requeueBuffer is the size of the channels of the requeued managed resources.
+const requeueBuffer = 1024
+
+// requeues are the channels of the managed resources to requeue, by kind.
+var requeues = struct {
+	sync.Mutex
+	chans map[schema.GroupVersionKind]chan event.GenericEvent
+}{chans: map[schema.GroupVersionKind]chan event.GenericEvent{}}
+
+func requeueChan(gvk schema.GroupVersionKind) chan event.GenericEvent {
+	requeues.Lock()
+	defer requeues.Unlock()
+
+	ch, ok := requeues.chans[gvk]
+	if !ok {
+		ch = make(chan event.GenericEvent, requeueBuffer)
+		requeues.chans[gvk] = ch
+	}
+	return ch
+}
+
+// Requeues returns the source of the managed resources of the kind requeued
+// by RequeueUsers; their controller watches it with an EnqueueRequestForObject.
+func Requeues(gvk schema.GroupVersionKind) source.Source {
+	return &source.Channel{Source: requeueChan(gvk)}
+}
+
+// RequeueUsers requeues the managed resources using the ProviderConfig,
+// i.e. to connect again with its rotated credentials.
+func RequeueUsers(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) error {
+	l := &v1alpha1.ProviderConfigUsageList{}
+	if err := k.List(ctx, l, client.MatchingLabels{xpv1.LabelKeyProviderName: pc.GetName()}); err != nil {
+		return errors.Wrap(err, "cannot list ProviderConfig usages")
+	}
+
+	for _, u := range l.Items {
+		ref := u.GetResourceReference()
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetName(ref.Name)
+
+		requeues.Lock()
+		ch, ok := requeues.chans[gvk]
+		requeues.Unlock()
+		if !ok {
+			continue
+		}
+
+		select {
+		case ch <- event.GenericEvent{Object: obj}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
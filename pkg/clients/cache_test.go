@@ -0,0 +1,130 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+)
+
+func TestClientOptsCache(t *testing.T) {
+	ctx := context.Background()
+
+	token, rv := "token-1", "1"
+	kube := &test.MockClient{
+		MockGet: func(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+			s := obj.(*corev1.Secret)
+			s.Data = map[string][]byte{"token": []byte(token)}
+			s.SetResourceVersion(rv)
+			return nil
+		},
+	}
+
+	pc := &v1alpha1.ProviderConfig{}
+	pc.SetName("default")
+	pc.SetUID(types.UID("cache-test"))
+	pc.SetGeneration(1)
+	pc.Spec.ApiUrl = "https://bitbucket.example.com"
+	pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+	pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "token"}
+
+	first, err := ClientOptsFromProviderConfig(ctx, kube, pc)
+	assert.NoError(t, err)
+	// The calls are bounded by their context, not by the HTTP client.
+	assert.Equal(t, defaultConnectTimeout+defaultResponseTimeout, first.CallTimeout)
+	assert.Zero(t, first.HttpClient.Timeout)
+
+	// The HTTP client is reused, the options are not shared.
+	second, err := ClientOptsFromProviderConfig(ctx, kube, pc)
+	assert.NoError(t, err)
+	assert.Same(t, first.HttpClient, second.HttpClient)
+	assert.NotSame(t, first, second)
+
+	version, err := ConfigVersion(ctx, kube, pc)
+	assert.NoError(t, err)
+	assert.Equal(t, "1/1", version)
+	assert.False(t, InvalidateClientOpts(pc.GetUID(), version))
+
+	// The rotated secret rebuilds the client.
+	token, rv = "token-2", "2"
+	version, err = ConfigVersion(ctx, kube, pc)
+	assert.NoError(t, err)
+	assert.True(t, InvalidateClientOpts(pc.GetUID(), version))
+
+	third, err := ClientOptsFromProviderConfig(ctx, kube, pc)
+	assert.NoError(t, err)
+	assert.NotSame(t, first.HttpClient, third.HttpClient)
+	assert.Equal(t, "token-2", third.Token)
+}
+
+func TestRequeueUsers(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "test.krateo.io", Version: "v1", Kind: "Widget"}
+	Requeues(gvk)
+
+	kube := &test.MockClient{
+		MockList: func(ctx context.Context, obj client.ObjectList, opts ...client.ListOption) error {
+			l := obj.(*v1alpha1.ProviderConfigUsageList)
+			for _, kind := range []string{"Widget", "Gadget"} {
+				u := v1alpha1.ProviderConfigUsage{}
+				u.SetResourceReference(xpv1.TypedReference{APIVersion: "test.krateo.io/v1", Kind: kind, Name: "demo"})
+				l.Items = append(l.Items, u)
+			}
+			return nil
+		},
+	}
+
+	pc := &v1alpha1.ProviderConfig{}
+	pc.SetName("default")
+	assert.NoError(t, RequeueUsers(context.Background(), kube, pc))
+
+	// Only the kinds whose controller watches the requeues are requeued.
+	ev := <-requeueChan(gvk)
+	assert.Equal(t, "demo", ev.Object.GetName())
+	assert.Equal(t, gvk, ev.Object.GetObjectKind().GroupVersionKind())
+	assert.NotContains(t, requeues.chans, schema.GroupVersionKind{Group: "test.krateo.io", Version: "v1", Kind: "Gadget"})
+}
+
+func TestDropClientOpts(t *testing.T) {
+	ctx := context.Background()
+	kube := &test.MockClient{
+		MockGet: func(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+			s := obj.(*corev1.Secret)
+			s.Data = map[string][]byte{"token": []byte("token")}
+			s.SetResourceVersion("1")
+			return nil
+		},
+	}
+
+	newPC := func(uid string) *v1alpha1.ProviderConfig {
+		pc := &v1alpha1.ProviderConfig{}
+		pc.SetName("drop-test")
+		pc.SetUID(types.UID(uid))
+		pc.Spec.ApiUrl = "https://bitbucket.example.com"
+		pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+		pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "token"}
+		return pc
+	}
+
+	first, err := ClientOptsFromProviderConfig(ctx, kube, newPC("drop-test-1"))
+	assert.NoError(t, err)
+
+	// The ProviderConfig created again with the same name drops the first one.
+	_, err = ClientOptsFromProviderConfig(ctx, kube, newPC("drop-test-2"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, DropClientOpts("drop-test", "drop-test-2"))
+	assert.Equal(t, 0, DropClientOpts("drop-test", "drop-test-2"))
+
+	// The deleted ProviderConfig is dropped, its client is not reused.
+	assert.Equal(t, 1, DropClientOpts("drop-test", ""))
+	again, err := ClientOptsFromProviderConfig(ctx, kube, newPC("drop-test-1"))
+	assert.NoError(t, err)
+	assert.NotSame(t, first.HttpClient, again.HttpClient)
+}
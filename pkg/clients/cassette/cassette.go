@@ -0,0 +1,315 @@
+// Package cassette records Bitbucket requests and responses into cassette files,
+// with the credentials scrubbed, and replays them to test the client offline.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Redacted replaces the scrubbed values.
+const Redacted = "REDACTED"
+
+// Host replaces the host of the recorded server.
+const Host = "bitbucket.example.com"
+
+// Cassette modes.
+const (
+	ModeRecord = "Record"
+	ModeReplay = "Replay"
+)
+
+// sensitiveHeaders are redacted from requests and responses.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Ausername",
+	"X-Auserid",
+	"X-Asessionid",
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Body is a payload; it is stored as a string when it is valid UTF-8, base64 encoded otherwise.
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(dat []byte) error {
+	var s string
+	if err := json.Unmarshal(dat, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+
+	var enc struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(dat, &enc); err != nil {
+		return err
+	}
+	res, err := base64.StdEncoding.DecodeString(enc.Base64)
+	if err != nil {
+		return err
+	}
+	*b = res
+	return nil
+}
+
+// Interaction is a request with its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is a sequence of interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Load reads a cassette file; a missing file is an empty cassette.
+func Load(path string) (*Cassette, error) {
+	dat, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Cassette{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Cassette{}
+	if err := json.Unmarshal(dat, res); err != nil {
+		return nil, fmt.Errorf("cannot decode cassette '%s': %w", path, err)
+	}
+	return res, nil
+}
+
+// Save writes the cassette file, creating its directory if missing.
+func (c *Cassette) Save(path string) error {
+	dat, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(dat, '\n'), 0o644)
+}
+
+// uri returns the path and the query of the request, that interactions are matched with.
+func uri(req *http.Request) string {
+	res := req.URL.EscapedPath()
+	if q := req.URL.Query(); len(q) > 0 {
+		res += "?" + q.Encode()
+	}
+	return res
+}
+
+// fileLocks serializes the appends to the cassette files, since
+// the recorders of a file may be many (i.e. one per client).
+var fileLocks sync.Map
+
+// appendTo appends the interaction to the cassette file.
+func appendTo(path string, in Interaction) error {
+	mu, _ := fileLocks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	c, err := Load(path)
+	if err != nil {
+		return err
+	}
+	c.Interactions = append(c.Interactions, in)
+	return c.Save(path)
+}
+
+// Recorder is an http.RoundTripper saving every interaction to the cassette file.
+type Recorder struct {
+	next    http.RoundTripper
+	path    string
+	secrets []string
+}
+
+// NewRecorder returns a Recorder sending the requests to the next RoundTripper
+// and appending the interactions to the cassette file; the secrets, besides the
+// credentials headers, are redacted.
+func NewRecorder(path string, next http.RoundTripper, secrets ...string) (*Recorder, error) {
+	if _, err := Load(path); err != nil {
+		return nil, err
+	}
+
+	res := &Recorder{next: next, path: path}
+	for _, el := range secrets {
+		if len(el) > 0 {
+			res.secrets = append(res.secrets, el)
+		}
+	}
+	return res, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	s := r.scrubber(req)
+	in := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URI:    s.Replace(uri(req)),
+			Header: s.header(req.Header),
+			Body:   Body(s.Replace(string(reqBody))),
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: s.header(resp.Header),
+			Body:   Body(s.Replace(string(respBody))),
+		},
+	}
+
+	if err := appendTo(r.path, in); err != nil {
+		return nil, fmt.Errorf("cannot save cassette: %w", err)
+	}
+
+	return resp, nil
+}
+
+type scrubber struct {
+	*strings.Replacer
+}
+
+// scrubber returns a scrubber replacing the secrets and the request host.
+func (r *Recorder) scrubber(req *http.Request) scrubber {
+	pairs := []string{}
+	for _, el := range r.secrets {
+		pairs = append(pairs, el, Redacted)
+	}
+	if len(req.URL.Host) > 0 {
+		pairs = append(pairs, req.URL.Host, Host)
+	}
+	return scrubber{strings.NewReplacer(pairs...)}
+}
+
+func (s scrubber) header(h http.Header) http.Header {
+	res := http.Header{}
+	for k, vals := range h {
+		for _, v := range vals {
+			res.Add(k, s.Replace(v))
+		}
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := res[k]; ok {
+			res.Set(k, Redacted)
+		}
+	}
+	return res
+}
+
+// Replayer is an http.RoundTripper answering with the interactions of a cassette.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer returns a Replayer of the cassette file.
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Interactions) == 0 {
+		return nil, fmt.Errorf("cassette '%s' has no interactions", path)
+	}
+	return &Replayer{cassette: c, used: make([]bool, len(c.Interactions))}, nil
+}
+
+// RoundTrip answers with the first unused interaction having the request method
+// and URI, in recording order; once all are used the last one is repeated.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	want := uri(req)
+	found := -1
+	for i, el := range r.cassette.Interactions {
+		if el.Request.Method != req.Method || el.Request.URI != want {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("no interaction recorded for %s %s", req.Method, want)
+	}
+	r.used[found] = true
+
+	rec := r.cassette.Interactions[found].Response
+	header := rec.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
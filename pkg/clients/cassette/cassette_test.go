@@ -0,0 +1,86 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-AUSERNAME", "jdoe")
+		rw.Header().Set("Set-Cookie", "BITBUCKETSESSIONID=abc")
+		rw.Header().Set("Content-Type", "application/json")
+		if req.Method == http.MethodPost {
+			rw.WriteHeader(http.StatusCreated)
+		}
+		rw.Write([]byte(`{"self":"http://` + req.Host + `/projects/JXP","echo":"s3cr3t","path":"` + req.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+
+	rec, err := NewRecorder(path, http.DefaultTransport, "s3cr3t")
+	assert.NoError(t, err)
+	cli := &http.Client{Transport: rec}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/rest/api/1.0/projects/JXP?limit=1&start=0", nil)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	resp, err := cli.Do(req)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "s3cr3t", "the recorded response is left untouched")
+
+	resp, err = cli.Post(server.URL+"/rest/api/1.0/projects", "application/json", strings.NewReader(`{"key":"JXP"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	dat, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	for _, el := range []string{"s3cr3t", "jdoe", "BITBUCKETSESSIONID", strings.TrimPrefix(server.URL, "http://")} {
+		assert.NotContains(t, string(dat), el)
+	}
+
+	rep, err := NewReplayer(path)
+	assert.NoError(t, err)
+	cli = &http.Client{Transport: rep}
+
+	resp, err = cli.Get("http://localhost/rest/api/1.0/projects/JXP?start=0&limit=1")
+	assert.NoError(t, err)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"self":"http://`+Host+`/projects/JXP","echo":"REDACTED","path":"/rest/api/1.0/projects/JXP"}`, string(body))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	// The last interaction is repeated.
+	for i := 0; i < 2; i++ {
+		resp, err = cli.Post("http://localhost/rest/api/1.0/projects", "application/json", strings.NewReader(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	_, err = cli.Get("http://localhost/rest/api/1.0/projects/OTHER")
+	assert.Error(t, err)
+}
+
+func TestBinaryBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.json")
+
+	c := &Cassette{Interactions: []Interaction{{
+		Request:  Request{Method: http.MethodGet, URI: "/archive"},
+		Response: Response{Status: http.StatusOK, Body: Body{0x1f, 0x8b, 0xff}},
+	}}}
+	assert.NoError(t, c.Save(path))
+
+	dat, _ := ioutil.ReadFile(path)
+	assert.Contains(t, string(dat), `"base64": "H4v/"`)
+
+	res, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Body{0x1f, 0x8b, 0xff}, res.Interactions[0].Response.Body)
+}
@@ -2,29 +2,48 @@ package clients
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/cassette"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// Reasons of the events recorded for the retried Bitbucket calls.
 const (
-	defaultMaxIdleConnections = 5
-	defaultResponseTimeout    = 30 * time.Second
-	defaultConnectionTimeout  = 15 * time.Second
+	ReasonThrottled = "Throttled"
+	ReasonRetrying  = "RetryingCall"
 )
 
+// cassettes configures the recording of the Bitbucket interactions, see UseCassettes.
+var cassettes struct {
+	dir  string
+	mode string
+}
+
+// UseCassettes records the Bitbucket interactions of every ProviderConfig,
+// appending them to the <dir>/<ProviderConfig name>.json cassette with the
+// credentials scrubbed, or with the Replay mode answers from it instead;
+// an empty dir disables the cassettes. It must be called before the
+// controllers start.
+func UseCassettes(dir, mode string) {
+	cassettes.dir, cassettes.mode = dir, mode
+}
+
 // GetConfig constructs a CreateOpts configuration that
 // can be used to authenticate to the git API provider by the ReST client
 func GetConfig(ctx context.Context, c client.Client, mg resource.Managed) (*bitbucket.ClientOpts, error) {
@@ -36,52 +55,102 @@ func GetConfig(ctx context.Context, c client.Client, mg resource.Managed) (*bitb
 	}
 }
 
-// UseProviderConfig to produce a config that can be used to create an ArgoCD client.
-func UseProviderConfig(ctx context.Context, k client.Client, mg resource.Managed) (*bitbucket.ClientOpts, error) {
+// GetProviderConfig returns the ProviderConfig referenced by the managed resource.
+func GetProviderConfig(ctx context.Context, k client.Client, mg resource.Managed) (*v1alpha1.ProviderConfig, error) {
+	if mg.GetProviderConfigReference() == nil {
+		return nil, errors.New("providerConfigRef is not given")
+	}
+
 	pc := &v1alpha1.ProviderConfig{}
 	err := k.Get(ctx, types.NamespacedName{Name: mg.GetProviderConfigReference().Name}, pc)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot get referenced Provider")
 	}
 
+	return pc, nil
+}
+
+// UseProviderConfig to produce a config that can be used to create an ArgoCD client.
+func UseProviderConfig(ctx context.Context, k client.Client, mg resource.Managed) (*bitbucket.ClientOpts, error) {
+	pc, err := GetProviderConfig(ctx, k, mg)
+	if err != nil {
+		return nil, err
+	}
+
 	t := resource.NewProviderConfigUsageTracker(k, &v1alpha1.ProviderConfigUsage{})
 	err = t.Track(ctx, mg)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot track ProviderConfig usage")
 	}
 
-	if s := pc.Spec.Credentials.Source; s != xpv1.CredentialsSourceSecret {
-		return nil, fmt.Errorf("credentials source %s is not currently supported", s)
-	}
+	return ClientOptsFromProviderConfig(ctx, k, pc)
+}
 
-	csr := pc.Spec.Credentials.SecretRef
-	if csr == nil {
-		return nil, fmt.Errorf("no credentials secret referenced")
-	}
+// ClientOptsFromProviderConfig produces the client configuration described by
+// the ProviderConfig; it is used also by the components not bound to a managed resource.
+// The configuration is cached until the ProviderConfig or its credentials change.
+func ClientOptsFromProviderConfig(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (*bitbucket.ClientOpts, error) {
+	return cachedClientOpts(ctx, k, pc)
+}
 
-	token, err := helpers.GetSecret(ctx, k, csr.DeepCopy())
+// newClientOpts builds the client configuration described by the ProviderConfig.
+func newClientOpts(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (*bitbucket.ClientOpts, error) {
+	creds, err := GetCredentials(ctx, k, pc)
 	if err != nil {
 		return nil, err
 	}
+	if len(creds.Username) == 0 {
+		creds.Username = helpers.StringValue(pc.Spec.Username)
+	}
 
 	opts := &bitbucket.ClientOpts{
 		ApiBaseUrl: pc.Spec.ApiUrl,
-		Username:   helpers.StringValue(pc.Spec.Username),
-		Token:      token,
+		Username:   creds.Username,
+		Token:      creds.Token,
+		Flavor:     helpers.StringValue(helpers.StringOrDefault(pc.Spec.Flavor, v1alpha1.FlavorServer)),
+		Workspace:  helpers.StringValue(pc.Spec.Workspace),
+		Middlewares: []bitbucket.Middleware{
+			bitbucket.Instrumenting(observeCall),
+		},
 	}
 
-	transport := http.DefaultTransport
+	if opts.Flavor == v1alpha1.FlavorCloud {
+		if len(opts.Workspace) == 0 {
+			return nil, fmt.Errorf("no workspace given for the cloud flavor")
+		}
+		if len(opts.ApiBaseUrl) == 0 {
+			opts.ApiBaseUrl = bitbucket.CloudApiUrl
+		}
+	}
 
-	insecure := helpers.BoolValueOrDefault(pc.Spec.Insecure, false)
-	if insecure {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: true,
+	base, timeout, err := newTransport(ctx, k, pc)
+	if err != nil {
+		return nil, err
+	}
+	var transport http.RoundTripper = base
+
+	// The tokens are requested without the cassette and the verbose tracer,
+	// not to record nor print them; the replayed calls need no credentials.
+	replay := len(cassettes.dir) > 0 && cassettes.mode == cassette.ModeReplay
+	if !replay {
+		opts.Auth, err = authenticator(pc, opts, &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		})
+		if err != nil {
+			return nil, err
 		}
-		transport = &http.Transport{
-			Proxy:                 http.ProxyFromEnvironment,
-			TLSClientConfig:       tlsConfig,
-			MaxIdleConnsPerHost:   defaultMaxIdleConnections,
-			ResponseHeaderTimeout: defaultResponseTimeout,
+	}
+
+	if len(cassettes.dir) > 0 {
+		path := filepath.Join(cassettes.dir, pc.GetName()+".json")
+		if replay {
+			transport, err = cassette.NewReplayer(path)
+		} else {
+			transport, err = cassette.NewRecorder(path, transport, opts.Token, opts.Username)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot use cassette")
 		}
 	}
 
@@ -90,14 +159,95 @@ func UseProviderConfig(ctx context.Context, k client.Client, mg resource.Managed
 		transport = &verboseTracer{transport}
 	}
 
-	opts.HttpClient = &http.Client{
-		Transport: transport,
-		Timeout:   defaultConnectionTimeout + defaultResponseTimeout,
-	}
+	// The calls are bounded by the timeout through their context, for the
+	// archive downloads to be bounded by the reconcile only.
+	opts.HttpClient = &http.Client{Transport: transport}
+	opts.CallTimeout = timeout
 
 	return opts, nil
 }
 
+// authenticator returns how to authenticate with the credentials as described
+// by the auth of the ProviderConfig; nil, the default of the client, without it.
+func authenticator(pc *v1alpha1.ProviderConfig, opts *bitbucket.ClientOpts, client *http.Client) (bitbucket.Authenticator, error) {
+	auth := pc.Spec.Auth
+	if auth == nil {
+		return nil, nil
+	}
+
+	switch auth.Type {
+	case v1alpha1.AuthBasic:
+		username := helpers.StringValue(helpers.StringOrDefault(auth.Username, opts.Username))
+		if len(username) == 0 {
+			return nil, fmt.Errorf("no username given for the %s auth", auth.Type)
+		}
+		return bitbucket.BasicAuth(username, opts.Token), nil
+
+	case v1alpha1.AuthBearer:
+		return bitbucket.BearerToken(opts.Token), nil
+
+	case v1alpha1.AuthOAuth2ClientCredentials:
+		clientID := helpers.StringValue(auth.ClientID)
+		if len(clientID) == 0 {
+			return nil, fmt.Errorf("no client id given for the %s auth", auth.Type)
+		}
+		tokenUrl := helpers.StringValue(auth.TokenURL)
+		if len(tokenUrl) == 0 {
+			tokenUrl = strings.TrimSuffix(opts.ApiBaseUrl, "/") + bitbucket.ServerTokenPath
+			if opts.Flavor == v1alpha1.FlavorCloud {
+				tokenUrl = bitbucket.CloudTokenUrl
+			}
+		}
+		return bitbucket.OAuth2ClientCredentials(bitbucket.OAuth2Opts{
+			TokenUrl:     tokenUrl,
+			ClientID:     clientID,
+			ClientSecret: opts.Token,
+			Scopes:       auth.Scopes,
+			HttpClient:   client,
+		}), nil
+
+	case v1alpha1.AuthTokenExchange:
+		tokenUrl := helpers.StringValue(auth.TokenURL)
+		if len(tokenUrl) == 0 {
+			return nil, fmt.Errorf("no token url given for the %s auth", auth.Type)
+		}
+		return bitbucket.TokenExchange(bitbucket.TokenExchangeOpts{
+			TokenUrl:         tokenUrl,
+			SubjectToken:     opts.Token,
+			SubjectTokenType: helpers.StringValue(auth.SubjectTokenType),
+			Audience:         helpers.StringValue(auth.Audience),
+			Scopes:           auth.Scopes,
+			HttpClient:       client,
+		}), nil
+	}
+
+	return nil, fmt.Errorf("auth type %s is not supported", auth.Type)
+}
+
+// Instrument makes the client log the Bitbucket calls made for the managed
+// resource in debug mode and report their retries with ReportRetries.
+func Instrument(opts *bitbucket.ClientOpts, log logging.Logger, rec record.EventRecorder, mg resource.Managed) {
+	opts.Middlewares = append(opts.Middlewares, bitbucket.Logging(log.Debug))
+	opts.Retry.OnRetry = ReportRetries(log, rec, mg)
+}
+
+// ReportRetries returns a callback logging the retries of the Bitbucket
+// calls made for the managed resource and recording them as events.
+func ReportRetries(log logging.Logger, rec record.EventRecorder, mg resource.Managed) func(bitbucket.RetryEvent) {
+	return func(ev bitbucket.RetryEvent) {
+		reason, what := ReasonRetrying, "failed"
+		if ev.Throttled {
+			reason, what = ReasonThrottled, "was throttled"
+		}
+
+		log.Debug("Retrying Bitbucket call", "method", ev.Method, "path", ev.Path,
+			"attempt", ev.Attempt, "maxAttempts", ev.MaxAttempts, "delay", ev.Delay.String(),
+			"throttled", ev.Throttled, "error", ev.Err.Error())
+		rec.Eventf(mg, corev1.EventTypeWarning, reason, "Bitbucket call %s %s %s (attempt %d of %d), retrying in %s: %s",
+			ev.Method, ev.Path, what, ev.Attempt, ev.MaxAttempts, ev.Delay.Round(time.Millisecond), ev.Err)
+	}
+}
+
 // verboseTracer implements http.RoundTripper.  It prints each request and
 // response/error to os.Stderr.  WARNING: this may output sensitive information
 // including bearer tokens.
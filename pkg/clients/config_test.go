@@ -0,0 +1,68 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/cassette"
+)
+
+func TestCassettes(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	kube := &test.MockClient{
+		MockGet: func(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+			obj.(*corev1.Secret).Data = map[string][]byte{"token": []byte("secret")}
+			return nil
+		},
+	}
+
+	pc := &v1alpha1.ProviderConfig{}
+	pc.SetName("recorded")
+	pc.Spec.ApiUrl = server.URL
+	pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+	pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "token"}
+
+	dir := t.TempDir()
+	UseCassettes(dir, cassette.ModeRecord)
+	defer UseCassettes("", "")
+
+	opts, err := newClientOpts(ctx, kube, pc)
+	assert.NoError(t, err)
+	repo, err := bitbucket.NewClient(opts).Repos().Get(ctx, bitbucket.GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	assert.NoError(t, err)
+	assert.Nil(t, repo)
+
+	// The cassette of the ProviderConfig is in the directory, without the token.
+	data, err := os.ReadFile(filepath.Join(dir, "recorded.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "/rest/api/1.0/projects/JXP/repos/demo-repo")
+	assert.NotContains(t, string(data), "secret")
+
+	// Replaying needs no server.
+	server.Close()
+	UseCassettes(dir, cassette.ModeReplay)
+
+	opts, err = newClientOpts(ctx, kube, pc)
+	assert.NoError(t, err)
+	repo, err = bitbucket.NewClient(opts).Repos().Get(ctx, bitbucket.GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	assert.NoError(t, err)
+	assert.Nil(t, repo)
+}
@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Credentials are the username and the secret of the ProviderConfig;
+// the secret is a token, a password or a client secret depending on the auth.
+type Credentials struct {
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token"`
+}
+
+// GetCredentials reads the credentials of the ProviderConfig from its source: a
+// key of a Secret, an environment variable or a file (i.e. mounted by a Vault agent
+// or a CSI driver). They are read on every call, so that the rotated ones are used
+// without restarting the provider.
+//
+// The credentials are either the bare token or a JSON object with the username and
+// the token, i.e. {"username":"jdoe","token":"..."}; the username of the object
+// takes precedence over the one of the spec.
+func GetCredentials(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (Credentials, error) {
+	src := pc.Spec.Credentials.Source
+	switch src {
+	case xpv1.CredentialsSourceSecret, xpv1.CredentialsSourceEnvironment, xpv1.CredentialsSourceFilesystem:
+	default:
+		return Credentials{}, fmt.Errorf("credentials source %s is not currently supported", src)
+	}
+
+	data, err := resource.CommonCredentialExtractor(ctx, src, k, pc.Spec.Credentials.CommonCredentialSelectors)
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "cannot read %s credentials", src)
+	}
+
+	return parseCredentials(data)
+}
+
+// parseCredentials parses the bare token or the JSON object of the credentials;
+// the surrounding whitespace, i.e. the trailing newline of a file, is trimmed.
+func parseCredentials(data []byte) (Credentials, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return Credentials{}, errors.New("no credentials found")
+	}
+
+	if data[0] != '{' {
+		return Credentials{Token: string(data)}, nil
+	}
+
+	res := Credentials{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return Credentials{}, errors.Wrap(err, "cannot parse the credentials")
+	}
+	if len(res.Token) == 0 {
+		return Credentials{}, errors.New("no token found in the credentials")
+	}
+	return res, nil
+}
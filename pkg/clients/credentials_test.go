@@ -0,0 +1,69 @@
+package clients
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+)
+
+func TestGetCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	os.Setenv("BITBUCKET_CREDENTIALS_TEST", `{"username":"jdoe","token":"env-token"}`)
+	defer os.Unsetenv("BITBUCKET_CREDENTIALS_TEST")
+
+	kube := &test.MockClient{
+		MockGet: func(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+			obj.(*corev1.Secret).Data = map[string][]byte{"token": []byte("secret-token")}
+			return nil
+		},
+	}
+
+	newPC := func(src xpv1.CredentialsSource) *v1alpha1.ProviderConfig {
+		pc := &v1alpha1.ProviderConfig{}
+		pc.Spec.Credentials.Source = src
+		pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "token"}
+		pc.Spec.Credentials.Env = &xpv1.EnvSelector{Name: "BITBUCKET_CREDENTIALS_TEST"}
+		pc.Spec.Credentials.Fs = &xpv1.FsSelector{Path: path}
+		return pc
+	}
+
+	creds, err := GetCredentials(ctx, kube, newPC(xpv1.CredentialsSourceSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{Token: "secret-token"}, creds)
+
+	creds, err = GetCredentials(ctx, kube, newPC(xpv1.CredentialsSourceEnvironment))
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "jdoe", Token: "env-token"}, creds)
+
+	// The file is read again once rotated.
+	for _, token := range []string{"fs-token", "rotated-token"} {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(token+"\n"), 0600))
+
+		creds, err = GetCredentials(ctx, kube, newPC(xpv1.CredentialsSourceFilesystem))
+		assert.NoError(t, err)
+		assert.Equal(t, Credentials{Token: token}, creds)
+	}
+
+	_, err = GetCredentials(ctx, kube, newPC(xpv1.CredentialsSourceNone))
+	assert.EqualError(t, err, "credentials source None is not currently supported")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"username":"jdoe"}`), 0600))
+	_, err = GetCredentials(ctx, kube, newPC(xpv1.CredentialsSourceFilesystem))
+	assert.EqualError(t, err, "no token found in the credentials")
+}
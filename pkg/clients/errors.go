@@ -0,0 +1,104 @@
+package clients
+
+import (
+	"context"
+	"errors"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the conditions and events of the failed Bitbucket calls,
+// derived from the kind of the error reported by Bitbucket.
+const (
+	ReasonNotFound         xpv1.ConditionReason = "NotFound"
+	ReasonConflict         xpv1.ConditionReason = "Conflict"
+	ReasonUnauthorized     xpv1.ConditionReason = "Unauthorized"
+	ReasonForbidden        xpv1.ConditionReason = "Forbidden"
+	ReasonValidationFailed xpv1.ConditionReason = "ValidationFailed"
+	ReasonRateLimited      xpv1.ConditionReason = "RateLimited"
+)
+
+// Actions of the external clients, prefixing the messages of the events.
+const (
+	actionObserve = "cannot observe external resource"
+	actionCreate  = "cannot create external resource"
+	actionUpdate  = "cannot update external resource"
+	actionDelete  = "cannot delete external resource"
+)
+
+// ErrorReason returns the reason of the error of a Bitbucket call;
+// it reports false if the error is not of a known kind.
+func ErrorReason(err error) (xpv1.ConditionReason, bool) {
+	switch {
+	case errors.Is(err, bitbucket.ErrNotFound):
+		return ReasonNotFound, true
+	case errors.Is(err, bitbucket.ErrConflict):
+		return ReasonConflict, true
+	case errors.Is(err, bitbucket.ErrUnauthorized):
+		return ReasonUnauthorized, true
+	case errors.Is(err, bitbucket.ErrForbidden):
+		return ReasonForbidden, true
+	case errors.Is(err, bitbucket.ErrValidation):
+		return ReasonValidationFailed, true
+	case errors.Is(err, bitbucket.ErrRateLimited):
+		return ReasonRateLimited, true
+	}
+	return "", false
+}
+
+// ReportError records the error of a Bitbucket call failing the action on the
+// managed resource, when of a known kind, as a Warning event and sets the Ready
+// condition with its reason; it returns the error. The managed reconciler
+// replaces the Ready condition while the resource is being created or deleted.
+func ReportError(rec record.EventRecorder, mg resource.Managed, action string, err error) error {
+	reason, ok := ErrorReason(err)
+	if !ok {
+		return err
+	}
+
+	rec.Eventf(mg, corev1.EventTypeWarning, string(reason), "%s: %s", action, err)
+	mg.SetConditions(xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            err.Error(),
+	})
+	return err
+}
+
+// ReportErrors returns an external client reporting with ReportError
+// the errors of the Bitbucket calls made by the given one.
+func ReportErrors(ext managed.ExternalClient, rec record.EventRecorder) managed.ExternalClient {
+	return &reportingExternal{ExternalClient: ext, rec: rec}
+}
+
+type reportingExternal struct {
+	managed.ExternalClient
+	rec record.EventRecorder
+}
+
+func (e *reportingExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	obs, err := e.ExternalClient.Observe(ctx, mg)
+	return obs, ReportError(e.rec, mg, actionObserve, err)
+}
+
+func (e *reportingExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cre, err := e.ExternalClient.Create(ctx, mg)
+	return cre, ReportError(e.rec, mg, actionCreate, err)
+}
+
+func (e *reportingExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	upd, err := e.ExternalClient.Update(ctx, mg)
+	return upd, ReportError(e.rec, mg, actionUpdate, err)
+}
+
+func (e *reportingExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	return ReportError(e.rec, mg, actionDelete, e.ExternalClient.Delete(ctx, mg))
+}
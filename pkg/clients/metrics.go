@@ -0,0 +1,33 @@
+package clients
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+)
+
+var (
+	callsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitbucket_calls_total",
+		Help: "Total number of Bitbucket calls by method, route and status; the status is 0 if no response was received.",
+	}, []string{"method", "route", "status"})
+
+	callDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bitbucket_call_duration_seconds",
+		Help:    "Duration of the Bitbucket calls by method and route, retries included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(callsTotal, callDuration)
+}
+
+// observeCall collects the metrics of a Bitbucket call.
+func observeCall(st bitbucket.CallStats) {
+	callsTotal.WithLabelValues(st.Method, st.Route, strconv.Itoa(st.Status)).Inc()
+	callDuration.WithLabelValues(st.Method, st.Route).Observe(st.Duration.Seconds())
+}
@@ -0,0 +1,106 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var tlsVersions = map[string]uint16{
+	v1alpha1.TLSVersion10: tls.VersionTLS10,
+	v1alpha1.TLSVersion11: tls.VersionTLS11,
+	v1alpha1.TLSVersion12: tls.VersionTLS12,
+	v1alpha1.TLSVersion13: tls.VersionTLS13,
+}
+
+// newTLSConfig returns the TLS configuration of the connections to Bitbucket
+// described by the ProviderConfig; nil, the default of the transport, without it.
+func newTLSConfig(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (*tls.Config, error) {
+	insecure := helpers.BoolValueOrDefault(pc.Spec.Insecure, false)
+	spec := pc.Spec.TLS
+	if spec == nil && !insecure {
+		return nil, nil
+	}
+
+	res := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+	if spec == nil {
+		return res, nil
+	}
+
+	version := helpers.StringValue(helpers.StringOrDefault(spec.MinVersion, v1alpha1.TLSVersion12))
+	min, ok := tlsVersions[version]
+	if !ok {
+		return nil, fmt.Errorf("TLS version %s is not supported", version)
+	}
+	res.MinVersion = min
+	res.ServerName = helpers.StringValue(spec.ServerName)
+
+	if ca := spec.CA; ca != nil {
+		bundle, err := caBundle(ctx, k, ca)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("no certificates found in the CA bundle")
+		}
+		res.RootCAs = pool
+	}
+
+	certRef, keyRef := spec.ClientCertSecretRef, spec.ClientKeySecretRef
+	if certRef != nil || keyRef != nil {
+		if certRef == nil || keyRef == nil {
+			return nil, errors.New("both the client certificate and key are required")
+		}
+
+		cert, err := helpers.GetSecret(ctx, k, certRef.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+		key, err := helpers.GetSecret(ctx, k, keyRef.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+
+		pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load the client certificate")
+		}
+		res.Certificates = []tls.Certificate{pair}
+	}
+
+	return res, nil
+}
+
+// caBundle reads the PEM bundle from its Secret or ConfigMap.
+func caBundle(ctx context.Context, k client.Client, ca *v1alpha1.CABundle) ([]byte, error) {
+	if ref := ca.SecretRef; ref != nil {
+		res, err := helpers.GetSecret(ctx, k, ref.DeepCopy())
+		return []byte(res), err
+	}
+
+	ref := ca.ConfigMapRef
+	if ref == nil {
+		return nil, errors.New("no CA bundle secret or config map referenced")
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := k.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+		return nil, errors.Wrapf(err, "cannot get %s config map", ref.Name)
+	}
+	return []byte(cm.Data[ref.Key]), nil
+}
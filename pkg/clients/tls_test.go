@@ -0,0 +1,116 @@
+package clients
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+// newClientCert returns a self-signed client certificate and its key, PEM encoded.
+func newClientCert(t *testing.T) (cert, key []byte) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "provider-bitbucket"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(priv)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestTLS(t *testing.T) {
+	ctx := context.Background()
+
+	clients := []string{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		for _, cert := range req.TLS.PeerCertificates {
+			clients = append(clients, cert.Subject.CommonName)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	cert, key := newClientCert(t)
+
+	kube := &test.MockClient{
+		MockGet: func(ctx context.Context, _ client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *corev1.ConfigMap:
+				o.Data = map[string]string{"ca.crt": string(ca)}
+			case *corev1.Secret:
+				o.Data = map[string][]byte{"token": []byte("s3cr3t"), "tls.crt": cert, "tls.key": key}
+			}
+			return nil
+		},
+	}
+
+	newPC := func(uid string) *v1alpha1.ProviderConfig {
+		pc := &v1alpha1.ProviderConfig{}
+		pc.SetUID(types.UID(uid))
+		pc.Spec.ApiUrl = server.URL
+		pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+		pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "token"}
+		pc.Spec.TLS = &v1alpha1.TLS{
+			CA:                  &v1alpha1.CABundle{ConfigMapRef: &v1alpha1.ConfigMapKeySelector{Key: "ca.crt"}},
+			ClientCertSecretRef: &xpv1.SecretKeySelector{Key: "tls.crt"},
+			ClientKeySecretRef:  &xpv1.SecretKeySelector{Key: "tls.key"},
+			// The certificate of the test server is issued for example.com.
+			ServerName: helpers.StringPtr("example.com"),
+			MinVersion: helpers.StringPtr(v1alpha1.TLSVersion13),
+		}
+		return pc
+	}
+
+	opts, err := ClientOptsFromProviderConfig(ctx, kube, newPC("tls-test"))
+	assert.NoError(t, err)
+
+	res, err := opts.HttpClient.Get(server.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, tls.VersionTLS13, int(res.TLS.Version))
+	assert.Equal(t, []string{"provider-bitbucket"}, clients)
+
+	// The certificate of the server is not verified against the wrong name.
+	pc := newPC("tls-test-server-name")
+	pc.Spec.TLS.ServerName = helpers.StringPtr("bitbucket.invalid")
+	opts, err = ClientOptsFromProviderConfig(ctx, kube, pc)
+	assert.NoError(t, err)
+	_, err = opts.HttpClient.Get(server.URL)
+	assert.Error(t, err)
+
+	pc = newPC("tls-test-key")
+	pc.Spec.TLS.ClientKeySecretRef = nil
+	_, err = ClientOptsFromProviderConfig(ctx, kube, pc)
+	assert.EqualError(t, err, "cannot configure TLS: both the client certificate and key are required")
+}
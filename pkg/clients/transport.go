@@ -0,0 +1,119 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultConnectTimeout      = 15 * time.Second
+	defaultResponseTimeout     = 30 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 5
+	defaultKeepAlive           = 30 * time.Second
+)
+
+// newTransport returns the transport of the connections to Bitbucket described
+// by the ProviderConfig, with the TLS configuration, and the timeout of a request.
+func newTransport(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (*http.Transport, time.Duration, error) {
+	spec := pc.Spec.Transport
+	if spec == nil {
+		spec = &v1alpha1.Transport{}
+	}
+
+	tlsConfig, err := newTLSConfig(ctx, k, pc)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "cannot configure TLS")
+	}
+
+	proxy, err := newProxy(ctx, k, spec.Proxy)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "cannot configure the proxy")
+	}
+
+	connectTimeout := durationOrDefault(spec.ConnectTimeout, defaultConnectTimeout)
+	responseTimeout := durationOrDefault(spec.ResponseTimeout, defaultResponseTimeout)
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: defaultKeepAlive,
+	}
+
+	http2 := helpers.BoolValueOrDefault(spec.HTTP2, true)
+	res := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: responseTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          helpers.IntPtrValue(spec.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost:   helpers.IntPtrValue(spec.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		IdleConnTimeout:       durationOrDefault(spec.IdleConnTimeout, defaultIdleConnTimeout),
+		// HTTP/2 is not attempted by default with a custom TLS configuration.
+		ForceAttemptHTTP2: http2,
+	}
+	if !http2 {
+		res.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return res, durationOrDefault(spec.Timeout, connectTimeout+responseTimeout), nil
+}
+
+// newProxy returns the proxy of the requests: the one of the ProviderConfig, with
+// its credentials, if set, otherwise the one of the environment variables.
+func newProxy(ctx context.Context, k client.Client, spec *v1alpha1.Proxy) (func(*http.Request) (*url.URL, error), error) {
+	if spec == nil {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	u, err := url.Parse(spec.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || len(u.Host) == 0 {
+		return nil, errors.Errorf("invalid proxy url %s", spec.URL)
+	}
+
+	if ref := spec.CredentialsSecretRef; ref != nil {
+		creds, err := helpers.GetSecret(ctx, k, ref.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+
+		i := strings.Index(creds, ":")
+		if i < 0 {
+			return nil, errors.New("the proxy credentials are not username:password")
+		}
+		u.User = url.UserPassword(creds[:i], strings.TrimSpace(creds[i+1:]))
+	}
+
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    strings.Join(spec.NoProxy, ","),
+	}).ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+func durationOrDefault(d *metav1.Duration, def time.Duration) time.Duration {
+	if d == nil {
+		return def
+	}
+	return d.Duration
+}
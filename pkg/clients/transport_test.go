@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func TestTransport(t *testing.T) {
+	ctx := context.Background()
+
+	proxied := []string{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		proxied = append(proxied, req.URL.String()+" "+req.Header.Get("Proxy-Authorization"))
+	}))
+	defer proxy.Close()
+
+	kube := &test.MockClient{
+		MockGet: func(ctx context.Context, _ client.ObjectKey, obj client.Object) error {
+			obj.(*corev1.Secret).Data = map[string][]byte{"proxy": []byte("jdoe:s3cr3t\n")}
+			return nil
+		},
+	}
+
+	maxIdleConnsPerHost := 10
+	pc := &v1alpha1.ProviderConfig{}
+	pc.Spec.Transport = &v1alpha1.Transport{
+		Proxy: &v1alpha1.Proxy{
+			URL:                  proxy.URL,
+			NoProxy:              []string{".internal", "10.0.0.0/8"},
+			CredentialsSecretRef: &xpv1.SecretKeySelector{Key: "proxy"},
+		},
+		ResponseTimeout:     &metav1.Duration{Duration: 10 * time.Second},
+		MaxIdleConnsPerHost: &maxIdleConnsPerHost,
+		HTTP2:               helpers.BoolPtr(false),
+	}
+
+	transport, timeout, err := newTransport(ctx, kube, pc)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConnectTimeout+10*time.Second, timeout)
+	assert.Equal(t, 10*time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 10, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, defaultMaxIdleConns, transport.MaxIdleConns)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+
+	// The hosts of the no proxy list are reached directly.
+	for _, host := range []string{"https://bitbucket.internal", "https://10.1.2.3:7990"} {
+		req, _ := http.NewRequest(http.MethodGet, host, nil)
+		u, err := transport.Proxy(req)
+		assert.NoError(t, err)
+		assert.Nil(t, u, host)
+	}
+
+	cli := &http.Client{Transport: transport, Timeout: timeout}
+	res, err := cli.Get("http://bitbucket.example.com/rest/api/1.0/projects")
+	assert.NoError(t, err)
+	res.Body.Close()
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("jdoe:s3cr3t"))
+	assert.Equal(t, []string{"http://bitbucket.example.com/rest/api/1.0/projects " + auth}, proxied)
+
+	pc.Spec.Transport.Proxy.URL = "proxy.internal:3128"
+	_, _, err = newTransport(ctx, kube, pc)
+	assert.EqualError(t, err, "cannot configure the proxy: invalid proxy url proxy.internal:3128")
+}
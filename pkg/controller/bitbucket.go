@@ -5,9 +5,14 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 
+	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/branchingmodel"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/config"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/pullrequest"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/repo"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/repofile"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/repohook"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/repopermissionuser"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/controller/requiredbuilds"
 )
 
 // Setup creates all controllers with the supplied logger and adds them to
@@ -17,6 +22,11 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		config.Setup,
 		repo.Setup,
 		repopermissionuser.Setup,
+		repohook.Setup,
+		requiredbuilds.Setup,
+		branchingmodel.Setup,
+		repofile.Setup,
+		pullrequest.Setup,
 	} {
 		if err := setup(mgr, o); err != nil {
 			return err
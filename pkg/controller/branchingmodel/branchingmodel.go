@@ -0,0 +1,444 @@
+package branchingmodel
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/branchingmodel/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	errNotBranchingModel = "managed resource is not a branching model custom resource"
+	errCloudUnsupported  = "branching models are not supported on Bitbucket Cloud"
+
+	reasonCreated = "CreatedExternalResource"
+	reasonUpdated = "UpdatedExternalResource"
+	reasonDeleted = "DeletedExternalResource"
+)
+
+// defaultTypes are the branch types of a project branching model never configured.
+var defaultTypes = map[string]string{
+	"BUGFIX":  "bugfix/",
+	"FEATURE": "feature/",
+	"HOTFIX":  "hotfix/",
+	"RELEASE": "release/",
+}
+
+// Setup adds a controller that reconciles BranchingModel managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := managed.ControllerName(v1alpha1.BranchingModelGroupKind)
+
+	log := o.Logger.WithValues("controller", name)
+
+	recorder := mgr.GetEventRecorderFor(name)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(v1alpha1.BranchingModelGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     mgr.GetClient(),
+			log:      log,
+			recorder: recorder,
+			clientFn: bitbucket.NewClient,
+		}),
+		managed.WithLogger(log),
+		managed.WithRecorder(event.NewAPIRecorder(recorder)))
+
+	if err := helpers.IndexClaims(mgr, &v1alpha1.BranchingModel{}, claimKey); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.BranchingModel{}).
+		Watches(clients.Requeues(v1alpha1.BranchingModelGroupVersionKind), &handler.EnqueueRequestForObject{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+type connector struct {
+	kube     client.Client
+	log      logging.Logger
+	recorder record.EventRecorder
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.BranchingModel)
+	if !ok {
+		return nil, errors.New(errNotBranchingModel)
+	}
+
+	cfg, err := clients.GetConfig(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
+	if cfg.Flavor == bitbucket.FlavorCloud {
+		return nil, errors.New(errCloudUnsupported)
+	}
+
+	return clients.ReportErrors(&external{
+		kube: c.kube,
+		log:  c.log,
+		cli:  c.clientFn(cfg),
+		rec:  c.recorder,
+	}, c.recorder), nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	kube client.Client
+	log  logging.Logger
+	cli  bitbucket.Interface
+	rec  record.EventRecorder
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.BranchingModel)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotBranchingModel)
+	}
+
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.BranchingModelList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := branchModelOpts(spec)
+
+	cfg, err := e.cli.BranchModels().Get(ctx, opts)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	if cfg == nil {
+		e.log.Debug("Branching model not configured", "project", spec.Project, "slug", opts.RepoSlug)
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	scope := scopeOf(cfg, opts)
+	cr.Status.AtProvider = generateObservation(spec, cfg, scope)
+
+	// A repository branching model is managed only when it overrides
+	// the project one, unless inheritance is explicitly requested.
+	isProject := len(opts.RepoSlug) == 0
+	inherit := !isProject && helpers.BoolValueOrDefault(spec.Inherit, false)
+
+	var exists bool
+	switch {
+	case isProject && meta.WasDeleted(cr):
+		// Bitbucket resets a project branching model to the defaults, never removing it.
+		exists = !isDefaultConfiguration(cfg)
+	case isProject:
+		exists = true
+	case inherit && !meta.WasDeleted(cr):
+		exists = true
+	default:
+		exists = scope == bitbucket.BranchModelScopeRepository
+	}
+
+	if !exists {
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	isUpToDate := scope == bitbucket.BranchModelScopeProject
+	if !inherit {
+		isUpToDate = isConfigurationUpToDate(spec, cfg)
+	}
+
+	cr.Status.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: isUpToDate,
+	}, nil
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.BranchingModel)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotBranchingModel)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	if err := e.configure(ctx, spec); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	e.log.Debug("Branching model configured", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug))
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Branching model '%s' configured", displayName(spec))
+
+	return managed.ExternalCreation{}, nil
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.BranchingModel)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotBranchingModel)
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	if err := e.configure(ctx, spec); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	e.log.Debug("Branching model updated", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug))
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "Branching model '%s' updated", displayName(spec))
+
+	return managed.ExternalUpdate{}, nil
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.BranchingModel)
+	if !ok {
+		return errors.New(errNotBranchingModel)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	err := e.cli.BranchModels().Delete(ctx, branchModelOpts(spec))
+	if err == nil {
+		e.log.Debug("Branching model deleted", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug))
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Branching model '%s' deleted", displayName(spec))
+	}
+
+	return err
+}
+
+// configure applies the desired branching model; branch types not
+// listed in the spec keep their current settings.
+func (e *external) configure(ctx context.Context, spec *v1alpha1.BranchingModelParams) error {
+	opts := branchModelOpts(spec)
+	models := e.cli.BranchModels()
+
+	if len(opts.RepoSlug) > 0 && helpers.BoolValueOrDefault(spec.Inherit, false) {
+		return models.Delete(ctx, opts)
+	}
+
+	current, err := models.Get(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = models.Update(ctx, opts, generateConfiguration(spec, current))
+	return err
+}
+
+func branchModelOpts(spec *v1alpha1.BranchingModelParams) bitbucket.BranchModelOpts {
+	return bitbucket.BranchModelOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   helpers.StringValue(spec.RepoSlug),
+	}
+}
+
+func displayName(spec *v1alpha1.BranchingModelParams) string {
+	if spec.RepoSlug == nil {
+		return spec.Project
+	}
+	return spec.Project + "/" + *spec.RepoSlug
+}
+
+// scopeOf returns the scope of the configuration, assuming the requested
+// level when Bitbucket does not report it.
+func scopeOf(cfg *bitbucket.BranchModelConfiguration, opts bitbucket.BranchModelOpts) string {
+	if cfg.Scope != nil && len(cfg.Scope.Type) > 0 {
+		return cfg.Scope.Type
+	}
+	if len(opts.RepoSlug) == 0 {
+		return bitbucket.BranchModelScopeProject
+	}
+	return bitbucket.BranchModelScopeRepository
+}
+
+// toRefId expands a branch name to a fully qualified ref.
+func toRefId(s string) string {
+	if len(s) == 0 || strings.HasPrefix(s, "refs/") {
+		return s
+	}
+	return "refs/heads/" + s
+}
+
+func generateBranch(in *v1alpha1.BranchSetting) *bitbucket.BranchModelBranch {
+	return &bitbucket.BranchModelBranch{
+		RefId:      toRefId(helpers.StringValue(in.RefID)),
+		UseDefault: helpers.BoolValueOrDefault(in.UseDefault, false),
+	}
+}
+
+// generateConfiguration produces the branching model described by the spec
+func generateConfiguration(spec *v1alpha1.BranchingModelParams, current *bitbucket.BranchModelConfiguration) bitbucket.BranchModelConfiguration {
+	res := bitbucket.BranchModelConfiguration{
+		Development: &bitbucket.BranchModelBranch{UseDefault: true},
+	}
+	if spec.Development != nil {
+		res.Development = generateBranch(spec.Development)
+	}
+	if spec.Production != nil {
+		res.Production = generateBranch(spec.Production)
+	}
+
+	if current != nil {
+		res.Types = append(res.Types, current.Types...)
+	}
+	for _, t := range spec.Types {
+		el := bitbucket.BranchModelType{
+			Id:      t.ID,
+			Prefix:  helpers.StringValue(t.Prefix),
+			Enabled: helpers.BoolValueOrDefault(t.Enabled, true),
+		}
+
+		found := false
+		for i := range res.Types {
+			if res.Types[i].Id == t.ID {
+				if len(el.Prefix) == 0 {
+					el.Prefix = res.Types[i].Prefix
+				}
+				res.Types[i] = el
+				found = true
+			}
+		}
+		if !found {
+			res.Types = append(res.Types, el)
+		}
+	}
+
+	return res
+}
+
+func isBranchUpToDate(desired *v1alpha1.BranchSetting, current *bitbucket.BranchModelBranch) bool {
+	if desired == nil {
+		return current == nil || (!current.UseDefault && len(current.RefId) == 0)
+	}
+	if current == nil {
+		return false
+	}
+
+	want := generateBranch(desired)
+	if want.UseDefault {
+		return current.UseDefault
+	}
+
+	return !current.UseDefault && want.RefId == current.RefId
+}
+
+func isConfigurationUpToDate(spec *v1alpha1.BranchingModelParams, cfg *bitbucket.BranchModelConfiguration) bool {
+	dev := spec.Development
+	if dev == nil {
+		dev = &v1alpha1.BranchSetting{UseDefault: helpers.BoolPtr(true)}
+	}
+	if !isBranchUpToDate(dev, cfg.Development) {
+		return false
+	}
+	if !isBranchUpToDate(spec.Production, cfg.Production) {
+		return false
+	}
+
+	for _, t := range spec.Types {
+		var cur *bitbucket.BranchModelType
+		for i := range cfg.Types {
+			if cfg.Types[i].Id == t.ID {
+				cur = &cfg.Types[i]
+			}
+		}
+		if cur == nil {
+			return false
+		}
+		if cur.Enabled != helpers.BoolValueOrDefault(t.Enabled, true) {
+			return false
+		}
+		if t.Prefix != nil && cur.Prefix != *t.Prefix {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDefaultConfiguration reports whether the branching model is the
+// default one, the development branch being the default branch, without
+// production branch and with the default branch types enabled.
+func isDefaultConfiguration(cfg *bitbucket.BranchModelConfiguration) bool {
+	if cfg.Development == nil || !cfg.Development.UseDefault {
+		return false
+	}
+	if !isBranchUpToDate(nil, cfg.Production) {
+		return false
+	}
+
+	if len(cfg.Types) != len(defaultTypes) {
+		return false
+	}
+	for _, el := range cfg.Types {
+		prefix, ok := defaultTypes[el.Id]
+		if !ok || !el.Enabled || el.Prefix != prefix {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generateObservation produces a branching model observation
+func generateObservation(spec *v1alpha1.BranchingModelParams, cfg *bitbucket.BranchModelConfiguration, scope string) v1alpha1.BranchingModelObservation {
+	res := v1alpha1.BranchingModelObservation{
+		Project: helpers.StringPtr(spec.Project),
+		Scope:   helpers.StringPtr(scope),
+	}
+	if spec.RepoSlug != nil {
+		res.RepoSlug = helpers.StringPtr(*spec.RepoSlug)
+	}
+	if cfg.Development != nil {
+		res.Development = helpers.LateInitializeString(nil, cfg.Development.RefId)
+	}
+	if cfg.Production != nil {
+		res.Production = helpers.LateInitializeString(nil, cfg.Production.RefId)
+	}
+
+	return res
+}
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.BranchingModel)
+	if !ok {
+		return ""
+	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, helpers.StringValue(spec.RepoSlug))
+}
@@ -0,0 +1,138 @@
+package branchingmodel
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/branchingmodel/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func newExternal(t *testing.T) (*external, bitbucket.Interface) {
+	fx := fake.NewFixture(t)
+
+	return &external{
+		kube: fx.Kube,
+		log:  logging.NewNopLogger(),
+		cli:  fx.Client,
+		rec:  record.NewFakeRecorder(10),
+	}, fx.Client
+}
+
+func newBranchingModel(slug *string) *v1alpha1.BranchingModel {
+	cr := &v1alpha1.BranchingModel{}
+	cr.SetName("model")
+	cr.SetUID(types.UID("model"))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider = v1alpha1.BranchingModelParams{
+		Project:    "JXP",
+		RepoSlug:   slug,
+		Production: &v1alpha1.BranchSetting{RefID: helpers.StringPtr("production")},
+		Types: []v1alpha1.BranchType{
+			{ID: "FEATURE", Prefix: helpers.StringPtr("feat/")},
+			{ID: "HOTFIX", Enabled: helpers.BoolPtr(false)},
+		},
+	}
+	return cr
+}
+
+func TestBranchingModelLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+	opts := bitbucket.BranchModelOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	cr := newBranchingModel(helpers.StringPtr("demo-repo"))
+
+	// The repository inherits the project branching model.
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, xpv1.ReasonAvailable, cr.GetCondition(xpv1.TypeReady).Reason)
+	assert.Equal(t, "refs/heads/production", helpers.StringValue(cr.Status.AtProvider.Production))
+	assert.Equal(t, bitbucket.BranchModelScopeRepository, helpers.StringValue(cr.Status.AtProvider.Scope))
+
+	cr.Spec.ForProvider.Production = nil
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// Deleting restores the inheritance of the project branching model.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	cfg, err := cli.BranchModels().Get(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, bitbucket.BranchModelScopeProject, cfg.Scope.Type)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestBranchingModelProject(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	cr := newBranchingModel(nil)
+
+	// A project always has a branching model.
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// The repositories inherit the project branching model.
+	cfg, err := cli.BranchModels().Get(ctx, bitbucket.BranchModelOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "refs/heads/production", cfg.Production.RefId)
+
+	// Deleting resets the defaults, then the resource is gone
+	// although Bitbucket still returns a branching model.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	cfg, err = cli.BranchModels().Get(ctx, bitbucket.BranchModelOpts{ProjectKey: "JXP"})
+	assert.NoError(t, err)
+	assert.NotNil(t, cfg)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
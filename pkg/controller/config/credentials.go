@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+)
+
+const credentialsTimeout = 1 * time.Minute
+
+// setupCredentials adds a controller watching the ProviderConfigs and the Secrets
+// and ConfigMaps of their credentials, TLS certificates and proxy credentials: when
+// they change, the cached client of the ProviderConfig is dropped and the managed
+// resources using it are requeued. The cached clients of the deleted ProviderConfigs
+// are dropped as well.
+func setupCredentials(mgr ctrl.Manager, o controller.Options) error {
+	name := "credentials/" + strings.ToLower(v1alpha1.ProviderConfigGroupKind)
+
+	r := &credentialsReconciler{
+		kube: mgr.GetClient(),
+		log:  o.Logger.WithValues("controller", name),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.ProviderConfig{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.providerConfigsOf)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.providerConfigsOf)).
+		Complete(r)
+}
+
+type credentialsReconciler struct {
+	kube client.Client
+	log  logging.Logger
+}
+
+func (r *credentialsReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialsTimeout)
+	defer cancel()
+
+	pc := &v1alpha1.ProviderConfig{}
+	if err := r.kube.Get(ctx, req.NamespacedName, pc); err != nil {
+		if kerrors.IsNotFound(err) && clients.DropClientOpts(req.Name, "") > 0 {
+			r.log.Debug("ProviderConfig deleted, dropped its client", "name", req.Name)
+		}
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// A ProviderConfig deleted and created again with the same name.
+	clients.DropClientOpts(pc.GetName(), pc.GetUID())
+
+	version, err := clients.ConfigVersion(ctx, r.kube, pc)
+	if err != nil {
+		// The managed resources report the error connecting.
+		r.log.Debug("Cannot get the version of the ProviderConfig", "name", pc.GetName(), "error", err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	if !clients.InvalidateClientOpts(pc.GetUID(), version) {
+		return reconcile.Result{}, nil
+	}
+
+	r.log.Debug("ProviderConfig changed, requeuing its users", "name", pc.GetName(), "version", version)
+	return reconcile.Result{}, clients.RequeueUsers(ctx, r.kube, pc)
+}
+
+// providerConfigsOf returns the ProviderConfigs reading their credentials, TLS
+// certificates or proxy credentials from the Secret or ConfigMap.
+func (r *credentialsReconciler) providerConfigsOf(obj client.Object) []reconcile.Request {
+	l := &v1alpha1.ProviderConfigList{}
+	if err := r.kube.List(context.Background(), l); err != nil {
+		r.log.Info("Cannot list ProviderConfigs", "error", err.Error())
+		return nil
+	}
+
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	_, isConfigMap := obj.(*corev1.ConfigMap)
+
+	res := []reconcile.Request{}
+	for _, pc := range l.Items {
+		secrets, configMaps := references(&pc)
+		refs := secrets
+		if isConfigMap {
+			refs = configMaps
+		}
+		for _, ref := range refs {
+			if ref == key {
+				res = append(res, reconcile.Request{NamespacedName: types.NamespacedName{Name: pc.GetName()}})
+				break
+			}
+		}
+	}
+	return res
+}
+
+// references returns the Secrets and the ConfigMaps the ProviderConfig reads.
+func references(pc *v1alpha1.ProviderConfig) (secrets, configMaps []types.NamespacedName) {
+	if creds := pc.Spec.Credentials; creds.Source == xpv1.CredentialsSourceSecret && creds.SecretRef != nil {
+		secrets = append(secrets, types.NamespacedName{Namespace: creds.SecretRef.Namespace, Name: creds.SecretRef.Name})
+	}
+
+	if t := pc.Spec.Transport; t != nil && t.Proxy != nil && t.Proxy.CredentialsSecretRef != nil {
+		ref := t.Proxy.CredentialsSecretRef
+		secrets = append(secrets, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name})
+	}
+
+	t := pc.Spec.TLS
+	if t == nil {
+		return secrets, configMaps
+	}
+	for _, ref := range []*xpv1.SecretKeySelector{t.ClientCertSecretRef, t.ClientKeySecretRef} {
+		if ref != nil {
+			secrets = append(secrets, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name})
+		}
+	}
+	if ca := t.CA; ca != nil {
+		switch {
+		case ca.SecretRef != nil:
+			secrets = append(secrets, types.NamespacedName{Namespace: ca.SecretRef.Namespace, Name: ca.SecretRef.Name})
+		case ca.ConfigMapRef != nil:
+			configMaps = append(configMaps, types.NamespacedName{Namespace: ca.ConfigMapRef.Namespace, Name: ca.ConfigMapRef.Name})
+		}
+	}
+	return secrets, configMaps
+}
@@ -0,0 +1,502 @@
+package pullrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/pullrequest/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	errNotPullRequest   = "managed resource is not a pull request custom resource"
+	errCloudUnsupported = "pull requests are not supported on Bitbucket Cloud"
+
+	reasonCreated  = "CreatedExternalResource"
+	reasonAdopted  = "AdoptedExternalResource"
+	reasonUpdated  = "UpdatedExternalResource"
+	reasonMerged   = "MergedExternalResource"
+	reasonDeclined = "DeclinedExternalResource"
+
+	// markerPrefix starts the marker of the pull requests opened by a resource.
+	markerPrefix = "[//]: # (krateo:"
+
+	buildStatusSuccessful = "SUCCESSFUL"
+	buildStatusFailed     = "FAILED"
+	buildStatusInProgress = "INPROGRESS"
+	buildStatusNone       = "NONE"
+)
+
+// Setup adds a controller that reconciles PullRequest managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := managed.ControllerName(v1alpha1.PullRequestGroupKind)
+
+	log := o.Logger.WithValues("controller", name)
+
+	recorder := mgr.GetEventRecorderFor(name)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(v1alpha1.PullRequestGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     mgr.GetClient(),
+			log:      log,
+			recorder: recorder,
+			clientFn: bitbucket.NewClient,
+		}),
+		managed.WithLogger(log),
+		managed.WithRecorder(event.NewAPIRecorder(recorder)))
+
+	if err := helpers.IndexClaims(mgr, &v1alpha1.PullRequest{}, claimKey); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.PullRequest{}).
+		Watches(clients.Requeues(v1alpha1.PullRequestGroupVersionKind), &handler.EnqueueRequestForObject{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+type connector struct {
+	kube     client.Client
+	log      logging.Logger
+	recorder record.EventRecorder
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.PullRequest)
+	if !ok {
+		return nil, errors.New(errNotPullRequest)
+	}
+
+	cfg, err := clients.GetConfig(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
+	if cfg.Flavor == bitbucket.FlavorCloud {
+		return nil, errors.New(errCloudUnsupported)
+	}
+
+	return clients.ReportErrors(&external{
+		kube: c.kube,
+		log:  c.log,
+		cli:  c.clientFn(cfg),
+		rec:  c.recorder,
+	}, c.recorder), nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	kube client.Client
+	log  logging.Logger
+	cli  bitbucket.Interface
+	rec  record.EventRecorder
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.PullRequest)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotPullRequest)
+	}
+
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.PullRequestList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	// The external name is the pull request identifier, assigned by Bitbucket on creation.
+	id, err := strconv.Atoi(meta.GetExternalName(cr))
+	if err != nil {
+		return managed.ExternalObservation{
+			ResourceExists: false,
+		}, nil
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := pullRequestOpts(spec)
+
+	pr, err := e.cli.PullRequests().Get(ctx, opts, id)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	if pr == nil {
+		e.log.Debug("Pull request not found", "id", id)
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	isOpen := pr.State == bitbucket.PullRequestStateOpen
+
+	// Merged and declined pull requests are left as they are.
+	if meta.WasDeleted(cr) && !isOpen {
+		return managed.ExternalObservation{
+			ResourceExists: false,
+		}, nil
+	}
+
+	cr.Status.AtProvider = generateObservation(pr)
+
+	isUpToDate := true
+	if isOpen {
+		ms, err := e.cli.PullRequests().MergeStatus(ctx, opts, pr.Id)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+
+		var stats *bitbucket.BuildStats
+		if len(pr.FromRef.LatestCommit) > 0 {
+			stats, err = e.cli.PullRequests().BuildStats(ctx, pr.FromRef.LatestCommit)
+			if err != nil {
+				return managed.ExternalObservation{}, err
+			}
+		}
+
+		observeMergeStatus(&cr.Status.AtProvider, ms, stats)
+
+		isUpToDate = isPullRequestUpToDate(spec, pr) &&
+			!(helpers.BoolValueOrDefault(spec.Merge, false) && ms.CanMerge)
+	}
+
+	cr.Status.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: isUpToDate,
+	}, nil
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.PullRequest)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotPullRequest)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := pullRequestOpts(spec)
+
+	pr := bitbucket.PullRequest{
+		Title:       spec.Title,
+		Description: withMarker(helpers.StringValue(spec.Description), marker(cr)),
+		FromRef:     bitbucket.NewPullRequestRef(opts, toRefId(spec.SourceRef)),
+		ToRef:       bitbucket.NewPullRequestRef(opts, toRefId(spec.TargetRef)),
+		Reviewers:   syncReviewers(spec.Reviewers, nil),
+	}
+
+	res, err := e.cli.PullRequests().Create(ctx, opts, pr)
+	switch {
+	case errors.Is(err, bitbucket.ErrConflict):
+		// The pull request may have been opened by a previous creation
+		// whose external name was not persisted; others are left alone.
+		res, err = e.cli.PullRequests().Find(ctx, opts, pr.FromRef.Id, pr.ToRef.Id)
+		if err != nil {
+			return managed.ExternalCreation{}, err
+		}
+		if res == nil {
+			return managed.ExternalCreation{}, fmt.Errorf("cannot find the open pull request from '%s' to '%s'", pr.FromRef.Id, pr.ToRef.Id)
+		}
+		if _, m := splitMarker(res.Description); m != marker(cr) {
+			return managed.ExternalCreation{}, fmt.Errorf("pull request '%d' from '%s' to '%s' is already open, not opened by this resource: %w",
+				res.Id, pr.FromRef.Id, pr.ToRef.Id, bitbucket.ErrConflict)
+		}
+
+		e.log.Debug("Pull request adopted", "id", res.Id, "url", res.URL())
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonAdopted, "Pull request '%d' adopted", res.Id)
+	case err != nil:
+		return managed.ExternalCreation{}, err
+	default:
+		e.log.Debug("Pull request created", "id", res.Id, "url", res.URL())
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Pull request '%d' created", res.Id)
+	}
+
+	meta.SetExternalName(cr, strconv.Itoa(res.Id))
+	cr.Status.AtProvider = generateObservation(res)
+
+	return managed.ExternalCreation{}, nil
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.PullRequest)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotPullRequest)
+	}
+
+	id, err := strconv.Atoi(meta.GetExternalName(cr))
+	if err != nil {
+		return managed.ExternalUpdate{}, fmt.Errorf("invalid pull request id '%s'", meta.GetExternalName(cr))
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := pullRequestOpts(spec)
+
+	pr, err := e.cli.PullRequests().Get(ctx, opts, id)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if pr == nil {
+		return managed.ExternalUpdate{}, fmt.Errorf("pull request '%d' not found", id)
+	}
+
+	if !isPullRequestUpToDate(spec, pr) {
+		pr, err = e.cli.PullRequests().Update(ctx, opts, generatePullRequest(spec, pr))
+		if err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+
+		e.log.Debug("Pull request updated", "id", pr.Id)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "Pull request '%d' updated", pr.Id)
+	}
+
+	if !helpers.BoolValueOrDefault(spec.Merge, false) {
+		return managed.ExternalUpdate{}, nil
+	}
+
+	ms, err := e.cli.PullRequests().MergeStatus(ctx, opts, pr.Id)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if !ms.CanMerge {
+		return managed.ExternalUpdate{}, nil
+	}
+
+	pr, err = e.cli.PullRequests().Merge(ctx, opts, pr.Id, pr.Version)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	e.log.Debug("Pull request merged", "id", pr.Id)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonMerged, "Pull request '%d' merged", pr.Id)
+
+	cr.Status.AtProvider = generateObservation(pr)
+
+	return managed.ExternalUpdate{}, nil
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.PullRequest)
+	if !ok {
+		return errors.New(errNotPullRequest)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	id, err := strconv.Atoi(meta.GetExternalName(cr))
+	if err != nil {
+		return nil
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := pullRequestOpts(spec)
+
+	pr, err := e.cli.PullRequests().Get(ctx, opts, id)
+	if err != nil || pr == nil {
+		return err
+	}
+
+	if pr.State != bitbucket.PullRequestStateOpen {
+		return nil
+	}
+
+	_, err = e.cli.PullRequests().Decline(ctx, opts, pr.Id, pr.Version)
+	if err == nil {
+		e.log.Debug("Pull request declined", "id", pr.Id)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeclined, "Pull request '%d' declined", pr.Id)
+	}
+
+	return err
+}
+
+func pullRequestOpts(spec *v1alpha1.PullRequestParams) bitbucket.PullRequestOpts {
+	return bitbucket.PullRequestOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   spec.RepoSlug,
+	}
+}
+
+// toRefId expands a branch name to a fully qualified ref.
+func toRefId(s string) string {
+	if len(s) == 0 || strings.HasPrefix(s, "refs/") {
+		return s
+	}
+	return "refs/heads/" + s
+}
+
+// marker returns the last line of the description of the pull requests opened
+// by the resource; it is a Markdown comment, not rendered by Bitbucket.
+func marker(cr *v1alpha1.PullRequest) string {
+	return markerPrefix + string(cr.GetUID()) + ")"
+}
+
+// withMarker appends the marker, if any, to the description.
+func withMarker(desc, marker string) string {
+	if len(marker) == 0 || len(desc) == 0 {
+		return desc + marker
+	}
+	return desc + "\n\n" + marker
+}
+
+// splitMarker returns the description without the marker, and the marker if any.
+func splitMarker(desc string) (string, string) {
+	i := strings.LastIndex(desc, markerPrefix)
+	if i < 0 {
+		return desc, ""
+	}
+	return strings.TrimRight(desc[:i], "\n"), desc[i:]
+}
+
+// syncReviewers returns the desired reviewers, keeping the current participant
+// of those already reviewing; nil names keep the current reviewers.
+func syncReviewers(names []string, current []bitbucket.PullRequestParticipant) []bitbucket.PullRequestParticipant {
+	if names == nil {
+		return append([]bitbucket.PullRequestParticipant{}, current...)
+	}
+
+	res := []bitbucket.PullRequestParticipant{}
+	for _, el := range current {
+		if helpers.StringSliceContains(names, el.User.Name) {
+			res = append(res, el)
+		}
+	}
+	for _, name := range names {
+		if hasReviewer(res, name) {
+			continue
+		}
+		el := bitbucket.PullRequestParticipant{}
+		el.User.Name = name
+		res = append(res, el)
+	}
+	return res
+}
+
+func hasReviewer(reviewers []bitbucket.PullRequestParticipant, name string) bool {
+	for _, el := range reviewers {
+		if el.User.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// generatePullRequest applies the spec to the current pull request
+func generatePullRequest(spec *v1alpha1.PullRequestParams, current *bitbucket.PullRequest) bitbucket.PullRequest {
+	res := *current
+	res.Title = spec.Title
+	if spec.Description != nil {
+		_, m := splitMarker(current.Description)
+		res.Description = withMarker(*spec.Description, m)
+	}
+	res.ToRef = bitbucket.NewPullRequestRef(pullRequestOpts(spec), toRefId(spec.TargetRef))
+	res.Reviewers = syncReviewers(spec.Reviewers, current.Reviewers)
+
+	return res
+}
+
+func isPullRequestUpToDate(spec *v1alpha1.PullRequestParams, pr *bitbucket.PullRequest) bool {
+	if pr.Title != spec.Title {
+		return false
+	}
+	if desc, _ := splitMarker(pr.Description); spec.Description != nil && desc != *spec.Description {
+		return false
+	}
+	if pr.ToRef.Id != toRefId(spec.TargetRef) {
+		return false
+	}
+	if spec.Reviewers == nil {
+		return true
+	}
+	if len(syncReviewers(spec.Reviewers, pr.Reviewers)) != len(pr.Reviewers) {
+		return false
+	}
+	for _, name := range spec.Reviewers {
+		if !hasReviewer(pr.Reviewers, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildStatus summarizes the build results of a commit
+func buildStatus(stats *bitbucket.BuildStats) string {
+	switch {
+	case stats == nil:
+		return buildStatusNone
+	case stats.Failed > 0:
+		return buildStatusFailed
+	case stats.InProgress > 0:
+		return buildStatusInProgress
+	case stats.Successful > 0:
+		return buildStatusSuccessful
+	default:
+		return buildStatusNone
+	}
+}
+
+func observeMergeStatus(obs *v1alpha1.PullRequestObservation, ms *bitbucket.MergeStatus, stats *bitbucket.BuildStats) {
+	obs.Conflicted = helpers.BoolPtr(ms.Conflicted)
+	obs.CanMerge = helpers.BoolPtr(ms.CanMerge)
+	obs.Vetoes = nil
+	for _, el := range ms.Vetoes {
+		obs.Vetoes = append(obs.Vetoes, el.SummaryMessage)
+	}
+	obs.BuildStatus = helpers.StringPtr(buildStatus(stats))
+}
+
+// generateObservation produces a pull request observation
+func generateObservation(pr *bitbucket.PullRequest) v1alpha1.PullRequestObservation {
+	approvals := 0
+	for _, el := range pr.Reviewers {
+		if el.Approved {
+			approvals++
+		}
+	}
+
+	id := pr.Id
+	return v1alpha1.PullRequestObservation{
+		ID:           &id,
+		URL:          helpers.LateInitializeString(nil, pr.URL()),
+		State:        helpers.LateInitializeString(nil, pr.State),
+		Approvals:    &approvals,
+		SourceCommit: helpers.LateInitializeString(nil, pr.FromRef.LatestCommit),
+	}
+}
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.PullRequest)
+	if !ok {
+		return ""
+	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, spec.RepoSlug, toRefId(spec.SourceRef), toRefId(spec.TargetRef))
+}
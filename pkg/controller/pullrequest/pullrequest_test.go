@@ -0,0 +1,239 @@
+package pullrequest
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/pullrequest/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+var opts = bitbucket.PullRequestOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+func newExternal(t *testing.T) (*external, bitbucket.Interface) {
+	fx := fake.NewFixture(t)
+	assert.NoError(t, fx.Bitbucket.AddBranch(fake.Project, fake.Repo, "feature", "main"))
+
+	return &external{
+		kube: fx.Kube,
+		log:  logging.NewNopLogger(),
+		cli:  fx.Client,
+		rec:  record.NewFakeRecorder(10),
+	}, fx.Client
+}
+
+func newPullRequest() *v1alpha1.PullRequest {
+	cr := &v1alpha1.PullRequest{}
+	cr.SetName("feature")
+	cr.SetUID(types.UID("feature"))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider = v1alpha1.PullRequestParams{
+		Project:   "JXP",
+		RepoSlug:  "demo-repo",
+		SourceRef: "feature",
+		TargetRef: "main",
+		Title:     "Add the feature",
+		Reviewers: []string{"jdoe"},
+	}
+	return cr
+}
+
+func reviewers(pr *bitbucket.PullRequest) []string {
+	res := []string{}
+	for _, el := range pr.Reviewers {
+		res = append(res, el.User.Name)
+	}
+	return res
+}
+
+func TestPullRequestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	cr := newPullRequest()
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", meta.GetExternalName(cr))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, xpv1.ReasonAvailable, cr.GetCondition(xpv1.TypeReady).Reason)
+	assert.Equal(t, bitbucket.PullRequestStateOpen, helpers.StringValue(cr.Status.AtProvider.State))
+
+	// The reviewers not listed are removed.
+	cr.Spec.ForProvider.Reviewers = []string{"jsmith"}
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	pr, err := cli.PullRequests().Get(ctx, opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"jsmith"}, reviewers(pr))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// Without reviewers, the current ones are kept.
+	cr.Spec.ForProvider.Reviewers = nil
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// Deleting declines the pull request.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	pr, err = cli.PullRequests().Get(ctx, opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, bitbucket.PullRequestStateDeclined, pr.State)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestPullRequestAdopt(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	// The external name of the first creation was not persisted.
+	_, err := e.Create(ctx, newPullRequest())
+	assert.NoError(t, err)
+
+	cr := newPullRequest()
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", meta.GetExternalName(cr))
+
+	pr, err := cli.PullRequests().Get(ctx, opts, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, pr)
+
+	// The pull request to another branch is a different one.
+	cr = newPullRequest()
+	cr.Spec.ForProvider.SourceRef = "main"
+	cr.Spec.ForProvider.TargetRef = "feature"
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", meta.GetExternalName(cr))
+}
+
+func TestPullRequestOpenedByHand(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	hand := bitbucket.PullRequest{
+		Title:   "Add the feature",
+		FromRef: bitbucket.NewPullRequestRef(opts, "refs/heads/feature"),
+		ToRef:   bitbucket.NewPullRequestRef(opts, "refs/heads/main"),
+	}
+	hand.Reviewers = syncReviewers([]string{"admin"}, nil)
+	_, err := cli.PullRequests().Create(ctx, opts, hand)
+	assert.NoError(t, err)
+
+	cr := newPullRequest()
+	_, err = e.Create(ctx, cr)
+	assert.ErrorIs(t, err, bitbucket.ErrConflict)
+	assert.Empty(t, meta.GetExternalName(cr))
+
+	pr, err := cli.PullRequests().Get(ctx, opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, reviewers(pr))
+}
+
+func TestPullRequestDescription(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	cr := newPullRequest()
+	cr.Spec.ForProvider.Description = helpers.StringPtr("Adds the feature.")
+	_, err := e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// The marker is kept updating the description.
+	cr.Spec.ForProvider.Description = helpers.StringPtr("Adds the whole feature.")
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	pr, err := cli.PullRequests().Get(ctx, opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Adds the whole feature.\n\n[//]: # (krateo:feature)", pr.Description)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+}
+
+func TestPullRequestMerge(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	cr := newPullRequest()
+	cr.Spec.ForProvider.Merge = helpers.BoolPtr(true)
+
+	_, err := e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.False(t, obs.ResourceUpToDate)
+	assert.True(t, helpers.BoolValue(cr.Status.AtProvider.CanMerge))
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	pr, err := cli.PullRequests().Get(ctx, opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, bitbucket.PullRequestStateMerged, pr.State)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// Merged pull requests are left as they are.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestPullRequestClaimKey(t *testing.T) {
+	cr := newPullRequest()
+	assert.Equal(t, "default/JXP/demo-repo/refs/heads/feature/refs/heads/main", claimKey(cr))
+
+	// The same refs, however they are given.
+	cr.Spec.ForProvider.SourceRef = "refs/heads/feature"
+	assert.Equal(t, "default/JXP/demo-repo/refs/heads/feature/refs/heads/main", claimKey(cr))
+}
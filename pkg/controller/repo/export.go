@@ -0,0 +1,261 @@
+package repo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	bbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	reasonExported = "ExportedExternalResource"
+
+	manifestFile = "manifest.json"
+)
+
+type exportedBranch struct {
+	Ref          string `json:"ref"`
+	LatestCommit string `json:"latestCommit,omitempty"`
+	File         string `json:"file"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+}
+
+// exportManifest describes the content of a repository export.
+type exportManifest struct {
+	Repository bitbucket.Repository        `json:"repository"`
+	ExportedAt time.Time                   `json:"exportedAt"`
+	Format     string                      `json:"format"`
+	Branches   []exportedBranch            `json:"branches"`
+	Users      []bitbucket.UserPermission  `json:"users"`
+	Groups     []bitbucket.GroupPermission `json:"groups"`
+}
+
+// export downloads an archive of every branch of the repository and a manifest
+// of its metadata and permissions, then verifies them; it returns the export directory.
+func (e *external) export(ctx context.Context, cfg *bbv1alpha1.RepoExport, projectKey, repoSlug string, now time.Time) (string, error) {
+	repos := e.cli.Repos()
+	opts := bitbucket.GetRepoOpts{ProjectKey: projectKey, RepoSlug: repoSlug}
+
+	repo, err := repos.Get(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if repo == nil {
+		return "", fmt.Errorf("repo '%s/%s' not found", projectKey, repoSlug)
+	}
+
+	branches, err := repos.Branches(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	perms := e.cli.Permissions()
+	users, err := perms.ListUserPermissions(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	groups, err := perms.ListGroupPermissions(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	format := helpers.StringValue(helpers.StringOrDefault(cfg.Format, bitbucket.ArchiveFormatTgz))
+
+	dir := filepath.Join(cfg.Directory, projectKey, repoSlug, now.UTC().Format(timestampLayout))
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", errors.Wrap(err, "cannot create export directory")
+	}
+
+	manifest := exportManifest{
+		Repository: *repo,
+		ExportedAt: now.UTC(),
+		Format:     format,
+		Branches:   []exportedBranch{},
+		Users:      users,
+		Groups:     groups,
+	}
+
+	for _, b := range branches {
+		el, err := e.exportBranch(ctx, dir, projectKey, repoSlug, b, format)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		manifest.Branches = append(manifest.Branches, *el)
+	}
+
+	if err := writeManifest(dir, &manifest); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	if err := verifyExport(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func (e *external) exportBranch(ctx context.Context, dir, projectKey, repoSlug string, b bitbucket.Branch, format string) (*exportedBranch, error) {
+	name := url.PathEscape(b.DisplayId) + "." + format
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create archive of branch '%s'", b.DisplayId)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	err = e.cli.Repos().Archive(ctx, bitbucket.ArchiveOpts{
+		ProjectKey: projectKey,
+		RepoSlug:   repoSlug,
+		At:         b.Id,
+		Format:     format,
+	}, io.MultiWriter(f, h))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot download archive of branch '%s'", b.DisplayId)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return &exportedBranch{
+		Ref:          b.Id,
+		LatestCommit: b.LatestCommit,
+		File:         name,
+		Size:         fi.Size(),
+		SHA256:       hex.EncodeToString(h.Sum(nil)),
+	}, f.Sync()
+}
+
+func writeManifest(dir string, manifest *exportManifest) error {
+	dat, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, manifestFile), dat, 0o640)
+}
+
+// verifyExport checks that every archive listed in the manifest
+// matches its checksum and can be read back completely.
+func verifyExport(dir string) error {
+	dat, err := ioutil.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return errors.Wrap(err, "cannot read export manifest")
+	}
+
+	manifest := exportManifest{}
+	if err := json.Unmarshal(dat, &manifest); err != nil {
+		return errors.Wrap(err, "invalid export manifest")
+	}
+
+	for _, b := range manifest.Branches {
+		fn := filepath.Join(dir, b.File)
+
+		sum, err := fileChecksum(fn)
+		if err != nil {
+			return err
+		}
+		if sum != b.SHA256 {
+			return fmt.Errorf("archive '%s' checksum mismatch", b.File)
+		}
+
+		switch manifest.Format {
+		case bitbucket.ArchiveFormatZip:
+			err = readZip(fn)
+		default:
+			err = readTgz(fn)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "archive '%s' is corrupted", b.File)
+		}
+	}
+
+	return nil
+}
+
+func fileChecksum(fn string) (string, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readZip reads every entry of the archive, checking their CRC.
+func readZip(fn string) error {
+	r, err := zip.OpenReader(fn)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, el := range r.File {
+		rc, err := el.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(ioutil.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readTgz reads the whole archive, checking the gzip checksum.
+func readTgz(fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(ioutil.Discard, tr); err != nil {
+			return err
+		}
+	}
+}
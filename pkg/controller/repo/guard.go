@@ -0,0 +1,163 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bmv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/branchingmodel/v1alpha1"
+	prv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/pullrequest/v1alpha1"
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repo/v1alpha1"
+	rfv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repofile/v1alpha1"
+	rhv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repohook/v1alpha1"
+	rpuv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repopermissionuser/v1alpha1"
+	rbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/requiredbuilds/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	reasonDeletionBlocked = "DeletionBlocked"
+	reasonCascadeDeleted  = "CascadeDeletedDependents"
+)
+
+// repoRef identifies a repository on the Bitbucket server of a ProviderConfig.
+type repoRef struct {
+	providerConfig string
+	project        string
+	slug           string
+}
+
+func (r repoRef) matches(mg resource.Managed, project, slug string) bool {
+	pcr := mg.GetProviderConfigReference()
+	if pcr == nil || pcr.Name != r.providerConfig {
+		return false
+	}
+	return project == r.project && slug == r.slug
+}
+
+// dependent is a managed resource referencing a repository.
+type dependent struct {
+	kind string
+	obj  resource.Managed
+}
+
+func (d dependent) String() string {
+	return fmt.Sprintf("%s/%s", d.kind, d.obj.GetName())
+}
+
+// listDependents returns the managed resources of this provider
+// that reference the repository.
+func listDependents(ctx context.Context, kube client.Client, ref repoRef) ([]dependent, error) {
+	res := []dependent{}
+	add := func(kind string, mg resource.Managed, project, slug string) {
+		if ref.matches(mg, project, slug) {
+			res = append(res, dependent{kind: kind, obj: mg})
+		}
+	}
+
+	rpu := &rpuv1alpha1.RepoPermissionUserList{}
+	if err := kube.List(ctx, rpu); err != nil {
+		return nil, err
+	}
+	for i := range rpu.Items {
+		el := &rpu.Items[i]
+		add(rpuv1alpha1.RepoPermissionUserKind, el, el.Spec.ForProvider.Project, el.Spec.ForProvider.RepoSlug)
+	}
+
+	rh := &rhv1alpha1.RepoHookList{}
+	if err := kube.List(ctx, rh); err != nil {
+		return nil, err
+	}
+	for i := range rh.Items {
+		el := &rh.Items[i]
+		add(rhv1alpha1.RepoHookKind, el, el.Spec.ForProvider.Project, helpers.StringValue(el.Spec.ForProvider.RepoSlug))
+	}
+
+	rb := &rbv1alpha1.RequiredBuildsList{}
+	if err := kube.List(ctx, rb); err != nil {
+		return nil, err
+	}
+	for i := range rb.Items {
+		el := &rb.Items[i]
+		add(rbv1alpha1.RequiredBuildsKind, el, el.Spec.ForProvider.Project, helpers.StringValue(el.Spec.ForProvider.RepoSlug))
+	}
+
+	bm := &bmv1alpha1.BranchingModelList{}
+	if err := kube.List(ctx, bm); err != nil {
+		return nil, err
+	}
+	for i := range bm.Items {
+		el := &bm.Items[i]
+		add(bmv1alpha1.BranchingModelKind, el, el.Spec.ForProvider.Project, helpers.StringValue(el.Spec.ForProvider.RepoSlug))
+	}
+
+	rf := &rfv1alpha1.RepoFileList{}
+	if err := kube.List(ctx, rf); err != nil {
+		return nil, err
+	}
+	for i := range rf.Items {
+		el := &rf.Items[i]
+		add(rfv1alpha1.RepoFileKind, el, el.Spec.ForProvider.Project, el.Spec.ForProvider.RepoSlug)
+	}
+
+	pr := &prv1alpha1.PullRequestList{}
+	if err := kube.List(ctx, pr); err != nil {
+		return nil, err
+	}
+	for i := range pr.Items {
+		el := &pr.Items[i]
+		add(prv1alpha1.PullRequestKind, el, el.Spec.ForProvider.Project, el.Spec.ForProvider.RepoSlug)
+	}
+
+	return res, nil
+}
+
+// guardDeletion returns an error, keeping the Repo finalizer in place, while
+// other managed resources reference the repository; with the cascade annotation
+// the dependents are deleted first.
+func (e *external) guardDeletion(ctx context.Context, cr *v1alpha1.Repo, ref repoRef) error {
+	deps, err := listDependents(ctx, e.kube, ref)
+	if err != nil {
+		return fmt.Errorf("cannot list repo dependents: %w", err)
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(deps))
+	for i, el := range deps {
+		names[i] = el.String()
+	}
+	sort.Strings(names)
+
+	if cr.GetAnnotations()[v1alpha1.AnnotationKeyCascadeDelete] != "true" {
+		msg := fmt.Sprintf("deletion blocked: repo '%s/%s' is still referenced by %s (annotate with %s=true to delete them too)",
+			ref.project, ref.slug, strings.Join(names, ", "), v1alpha1.AnnotationKeyCascadeDelete)
+		cr.SetConditions(xpv1.Deleting().WithMessage(msg))
+		e.rec.Event(cr, corev1.EventTypeWarning, reasonDeletionBlocked, msg)
+		return errors.New(msg)
+	}
+
+	for _, el := range deps {
+		if meta.WasDeleted(el.obj) {
+			continue
+		}
+		if err := e.kube.Delete(ctx, el.obj); resource.Ignore(kerrors.IsNotFound, err) != nil {
+			return fmt.Errorf("cannot delete repo dependent: %w", err)
+		}
+	}
+
+	msg := fmt.Sprintf("waiting for the deletion of %s", strings.Join(names, ", "))
+	cr.SetConditions(xpv1.Deleting().WithMessage(msg))
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCascadeDeleted, "Repo '%s/%s' dependents deleted: %s", ref.project, ref.slug, strings.Join(names, ", "))
+	return errors.New(msg)
+}
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	bbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	reasonPurged = "PurgedExternalResource"
+
+	// timestampLayout formats the suffix of trashed repositories
+	// names and the exports directories.
+	timestampLayout = "20060102150405"
+
+	janitorInterval = time.Hour
+)
+
+// deletionMode returns the repository deletion mode of the flavor. Archiving is
+// the default, but on Bitbucket Cloud, where repositories cannot be archived,
+// moving them to the trash project if any, deleting them otherwise.
+func deletionMode(p *bbv1alpha1.RepoDeletionPolicy, flavor string) string {
+	if p != nil && len(p.Mode) > 0 {
+		return p.Mode
+	}
+	if flavor != bitbucket.FlavorCloud {
+		return bbv1alpha1.RepoDeletionArchive
+	}
+	if p != nil && len(helpers.StringValue(p.TrashProject)) > 0 {
+		return bbv1alpha1.RepoDeletionTrash
+	}
+	return bbv1alpha1.RepoDeletionDelete
+}
+
+// trashName returns the name of a repository moved to the trash project.
+func trashName(slug string, t time.Time) string {
+	return slug + "-" + t.UTC().Format(timestampLayout)
+}
+
+// trashedAt returns when a repository has been moved to the trash
+// project; ok is false if the name has no valid timestamp suffix.
+func trashedAt(slug string) (t time.Time, ok bool) {
+	idx := strings.LastIndex(slug, "-")
+	if idx < 0 {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(timestampLayout, slug[idx+1:])
+	return t, err == nil
+}
+
+// janitor periodically purges the repositories kept in the trash
+// projects longer than the retention of their ProviderConfig.
+type janitor struct {
+	kube     client.Client
+	log      logging.Logger
+	rec      record.EventRecorder
+	interval time.Duration
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
+}
+
+// Start runs the janitor until the context is done.
+func (j *janitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			j.purge(ctx, time.Now())
+		}
+	}
+}
+
+func (j *janitor) purge(ctx context.Context, now time.Time) {
+	all := &bbv1alpha1.ProviderConfigList{}
+	if err := j.kube.List(ctx, all); err != nil {
+		j.log.Info("Cannot list provider configs", "error", err)
+		return
+	}
+
+	for i := range all.Items {
+		pc := &all.Items[i]
+
+		policy := pc.Spec.RepoDeletion
+		if deletionMode(policy, helpers.StringValue(pc.Spec.Flavor)) != bbv1alpha1.RepoDeletionTrash || policy.Retention == nil {
+			continue
+		}
+		trash := helpers.StringValue(policy.TrashProject)
+		if len(trash) == 0 {
+			continue
+		}
+
+		opts, err := clients.ClientOptsFromProviderConfig(ctx, j.kube, pc)
+		if err != nil {
+			j.log.Info("Cannot configure client", "providerConfig", pc.Name, "error", err)
+			continue
+		}
+		// Every trashed repository is looked at, whatever the paging options.
+		opts.Paging.MaxItems = 0
+		repos := j.clientFn(opts).Repos()
+
+		list, err := repos.List(ctx, trash)
+		if err != nil {
+			j.log.Info("Cannot list trashed repos", "providerConfig", pc.Name, "project", trash, "error", err)
+			continue
+		}
+
+		for _, el := range list {
+			t, ok := trashedAt(el.Slug)
+			if !ok || now.Sub(t) < policy.Retention.Duration {
+				continue
+			}
+
+			if err := repos.Delete(ctx, trash, el.Slug); err != nil {
+				j.log.Info("Cannot purge repo", "project", trash, "slug", el.Slug, "error", err)
+				continue
+			}
+			j.log.Debug("Repo purged", "project", trash, "slug", el.Slug)
+			j.rec.Eventf(pc, corev1.EventTypeNormal, reasonPurged, "Repo '%s/%s' purged", trash, el.Slug)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	corev1 "k8s.io/api/core/v1"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repo/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+)
+
+const (
+	reasonMarkerMismatch = "MarkerMismatch"
+	reasonAdopted        = "AdoptedExternalResource"
+	reasonMarked         = "MarkedExternalResource"
+
+	markerPrefix = "krateo-"
+	markerIdLen  = 10
+)
+
+// errNoMarker is returned by checkMarker for repositories without any marker label.
+var errNoMarker = errors.New("not managed by this provider (no marker label)")
+
+// markerId returns a short identifier, usable in a label, of the value.
+func markerId(val string) string {
+	sum := sha256.Sum256([]byte(val))
+	return hex.EncodeToString(sum[:])[:markerIdLen]
+}
+
+// markerLabel returns the label that marks a repository as managed
+// by the Repo with the given name from the given cluster.
+func markerLabel(clusterID, name string) string {
+	return markerPrefix + markerId(clusterID) + "-" + markerId(name)
+}
+
+// parseMarker returns the cluster and Repo identifiers of a
+// marker label; ok is false if the label is not a marker.
+func parseMarker(label string) (cluster, name string, ok bool) {
+	if !strings.HasPrefix(label, markerPrefix) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(label, markerPrefix), "-")
+	if len(parts) != 2 || len(parts[0]) != markerIdLen || len(parts[1]) != markerIdLen {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// checkMarker returns an error, with a message telling why, if the repository
+// labels do not mark it as managed by the Repo; other markers are reported first.
+func checkMarker(clusterID, name string, labels []string) error {
+	want := markerLabel(clusterID, name)
+
+	others := []string{}
+	for _, el := range labels {
+		if el == want {
+			return nil
+		}
+		if _, _, ok := parseMarker(el); ok {
+			others = append(others, el)
+		}
+	}
+
+	if len(others) == 0 {
+		return errNoMarker
+	}
+
+	cluster, _, _ := parseMarker(others[0])
+	if cluster == markerId(clusterID) {
+		return fmt.Errorf("managed by another Repo of this cluster (marker label '%s')", others[0])
+	}
+	return fmt.Errorf("managed from another cluster (marker label '%s')", others[0])
+}
+
+// stamp marks the repository as managed by the Repo.
+func (e *external) stamp(ctx context.Context, cr *v1alpha1.Repo, opts bitbucket.GetRepoOpts) error {
+	label := markerLabel(e.clusterID, cr.GetName())
+	if err := e.cli.Repos().AddLabel(ctx, opts, label); err != nil {
+		return err
+	}
+	e.log.Debug("Repo marked", "project", opts.ProjectKey, "slug", opts.RepoSlug, "label", label)
+	return nil
+}
+
+// verifyMarker refuses to act on repositories not marked as managed by the Repo,
+// setting a condition with the reason; with the force adopt annotation they are
+// marked instead. Repositories without any marker created by this Repo, before
+// the markers were introduced or by a Create failing to mark them, are marked
+// as well.
+func (e *external) verifyMarker(ctx context.Context, cr *v1alpha1.Repo, opts bitbucket.GetRepoOpts) error {
+	labels, err := e.cli.Repos().Labels(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	err = checkMarker(e.clusterID, cr.GetName(), labels)
+	if err == nil {
+		return nil
+	}
+
+	if cr.GetAnnotations()[v1alpha1.AnnotationKeyForceAdopt] == "true" {
+		if err := e.stamp(ctx, cr, opts); err != nil {
+			return err
+		}
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonAdopted, "Repo '%s/%s' adopted: it was %s", opts.ProjectKey, opts.RepoSlug, err)
+		return nil
+	}
+
+	if errors.Is(err, errNoMarker) && isCreatedByProvider(cr) {
+		if err := e.stamp(ctx, cr, opts); err != nil {
+			return err
+		}
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonMarked, "Repo '%s/%s' marked: it was created without the marker label", opts.ProjectKey, opts.RepoSlug)
+		return nil
+	}
+
+	msg := fmt.Sprintf("refusing to manage repo '%s/%s': %s; annotate with %s=true to override",
+		opts.ProjectKey, opts.RepoSlug, err, v1alpha1.AnnotationKeyForceAdopt)
+	cr.SetConditions(xpv1.Unavailable().WithMessage(msg))
+	e.rec.Event(cr, corev1.EventTypeWarning, reasonMarkerMismatch, msg)
+	return errors.New(msg)
+}
+
+// isCreatedByProvider tells whether the managed reconciler created the external
+// resource of the Repo, rather than the external name being set by hand: it
+// annotates the Repo as pending before creating it, and as succeeded after.
+func isCreatedByProvider(cr *v1alpha1.Repo) bool {
+	return !meta.GetExternalCreatePending(cr).IsZero() || !meta.GetExternalCreateSucceeded(cr).IsZero()
+}
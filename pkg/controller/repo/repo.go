@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -14,6 +15,7 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
@@ -22,17 +24,21 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 
 	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repo/v1alpha1"
+	bbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
 	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
 )
 
 const (
-	errNotRepo = "managed resource is not a repo custom resource"
-
-	reasonCannotCreate = "CannotCreateExternalResource"
-	reasonCreated      = "CreatedExternalResource"
-	reasonDeleted      = "DeletedExternalResource"
+	errNotRepo        = "managed resource is not a repo custom resource"
+	errNoTrashProject = "repo deletion mode is Trash but no trash project is configured"
+	errArchiveOnCloud = "repo deletion mode Archive is not supported by the cloud flavor: use Trash or Delete"
+	errCannotExport   = "cannot export repo before deletion"
+
+	reasonCreated = "CreatedExternalResource"
+	reasonUpdated = "UpdatedExternalResource"
+	reasonDeleted = "DeletedExternalResource"
 )
 
 // Setup adds a controller that reconciles Token managed resources.
@@ -54,10 +60,26 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		managed.WithLogger(log),
 		managed.WithRecorder(event.NewAPIRecorder(recorder)))
 
+	err := mgr.Add(&janitor{
+		kube:     mgr.GetClient(),
+		log:      log.WithValues("component", "janitor"),
+		rec:      recorder,
+		interval: janitorInterval,
+		clientFn: bitbucket.NewClient,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := helpers.IndexClaims(mgr, &v1alpha1.Repo{}, claimKey); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		WithOptions(o.ForControllerRuntime()).
 		For(&v1alpha1.Repo{}).
+		Watches(clients.Requeues(v1alpha1.RepoGroupVersionKind), &handler.EnqueueRequestForObject{}).
 		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
 }
 
@@ -65,7 +87,7 @@ type connector struct {
 	kube     client.Client
 	log      logging.Logger
 	recorder record.EventRecorder
-	clientFn func(opts *bitbucket.ClientOpts) *bitbucket.Client
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
 }
 
 func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
@@ -78,13 +100,24 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	if err != nil {
 		return nil, err
 	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
 
-	return &external{
-		kube: c.kube,
-		log:  c.log,
-		cli:  bitbucket.NewClient(cfg),
-		rec:  c.recorder,
-	}, nil
+	pc, err := clients.GetProviderConfig(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Flavor == bitbucket.FlavorCloud && deletionMode(pc.Spec.RepoDeletion, cfg.Flavor) == bbv1alpha1.RepoDeletionArchive {
+		return nil, errors.New(errArchiveOnCloud)
+	}
+
+	return clients.ReportErrors(&external{
+		kube:      c.kube,
+		log:       c.log,
+		cli:       c.clientFn(cfg),
+		rec:       c.recorder,
+		deletion:  pc.Spec.RepoDeletion,
+		clusterID: helpers.StringValue(helpers.StringOrDefault(pc.Spec.ClusterID, string(pc.GetUID()))),
+	}, c.recorder), nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -92,8 +125,11 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 type external struct {
 	kube client.Client
 	log  logging.Logger
-	cli  *bitbucket.Client
+	cli  bitbucket.Interface
 	rec  record.EventRecorder
+
+	deletion  *bbv1alpha1.RepoDeletionPolicy
+	clusterID string
 }
 
 func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -102,6 +138,13 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.New(errNotRepo)
 	}
 
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.RepoList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
 	if meta.GetExternalName(cr) == "" {
 		return managed.ExternalObservation{
 			ResourceExists: false,
@@ -115,7 +158,7 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	if len(parts) == 2 {
 		e.log.Debug("External name exists", "value", meta.GetExternalName(cr),
 			"project", parts[0], "repoSlug", parts[1])
-		repo, err = e.cli.Repos().Get(bitbucket.GetRepoOpts{
+		repo, err = e.cli.Repos().Get(ctx, bitbucket.GetRepoOpts{
 			ProjectKey: parts[0],
 			RepoSlug:   parts[1],
 		})
@@ -127,12 +170,43 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	if repo != nil {
 		e.log.Debug("Observed repo", "value", fmt.Sprintf("%+v", repo))
 
+		// Archiving is how the repository is deleted in Archive mode.
+		if meta.WasDeleted(cr) && repo.Archived && deletionMode(e.deletion, e.cli.Flavor()) == bbv1alpha1.RepoDeletionArchive {
+			return managed.ExternalObservation{
+				ResourceExists: false,
+			}, nil
+		}
+
+		err = e.verifyMarker(ctx, cr, bitbucket.GetRepoOpts{
+			ProjectKey: repo.Project.Key,
+			RepoSlug:   repo.Slug,
+		})
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+
 		cr.Status.AtProvider = generateObservation(repo)
 
+		isUpToDate := isArchivedUpToDate(cr.Spec.ForProvider.Archived, repo)
+		if repo.Origin != nil {
+			sync, err := e.cli.Repos().GetRefSync(ctx, bitbucket.GetRepoOpts{
+				ProjectKey: repo.Project.Key,
+				RepoSlug:   repo.Slug,
+			})
+			if err != nil {
+				return managed.ExternalObservation{}, err
+			}
+
+			isUpToDate = isUpToDate && isRefSyncUpToDate(cr.Spec.ForProvider.RefSync, sync)
+			if sync != nil && sync.Available {
+				cr.Status.AtProvider.RefSync = helpers.BoolPtr(sync.Enabled)
+			}
+		}
+
 		cr.Status.SetConditions(xpv1.Available())
 		return managed.ExternalObservation{
 			ResourceExists:   true,
-			ResourceUpToDate: true,
+			ResourceUpToDate: isUpToDate,
 		}, nil
 	}
 
@@ -155,8 +229,12 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	spec := cr.Spec.ForProvider.DeepCopy()
 
+	if spec.ForkFrom != nil {
+		return e.fork(ctx, cr)
+	}
+
 	repos := e.cli.Repos()
-	res, err := repos.Create(bitbucket.CreateRepoOpts{
+	res, err := repos.Create(ctx, bitbucket.CreateRepoOpts{
 		Name:       spec.Name,
 		Public:     !helpers.BoolValueOrDefault(spec.Private, false),
 		ProjectKey: spec.Project,
@@ -167,8 +245,20 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	e.log.Debug("Repo created", "project", spec.Project, "name", spec.Name, "slug", res.Slug)
 	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Repo '%s/%s' created", spec.Project, spec.Name)
 
+	// The external name is kept even if the next steps fail, not to lose
+	// the repository; it is marked on the next observation.
+	meta.SetExternalName(cr, fmt.Sprintf("%s/%s", spec.Project, res.Slug))
+
+	err = e.stamp(ctx, cr, bitbucket.GetRepoOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   res.Slug,
+	})
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
 	if helpers.BoolValueOrDefault(spec.Initialize, true) {
-		err = repos.Init(bitbucket.RepoInitOpts{
+		err = repos.Init(ctx, bitbucket.RepoInitOpts{
 			ProjectKey: spec.Project,
 			RepoSlug:   res.Slug,
 			Title:      res.Description,
@@ -180,13 +270,48 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Repo '%s/%s' initialized", spec.Project, spec.Name)
 	}
 
-	meta.SetExternalName(cr, fmt.Sprintf("%s/%s", spec.Project, res.Slug))
-
 	return managed.ExternalCreation{}, nil
 }
 
 func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
-	return managed.ExternalUpdate{}, nil // noop
+	cr, ok := mg.(*v1alpha1.Repo)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRepo)
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	projectKey := helpers.StringValue(cr.Status.AtProvider.Project)
+	repoSlug := helpers.StringValue(cr.Status.AtProvider.RepoSlug)
+
+	if spec.Archived != nil && *spec.Archived != helpers.BoolValue(cr.Status.AtProvider.Archived) {
+		_, err := e.cli.Repos().Update(ctx, projectKey, repoSlug, bitbucket.UpdateRepoOpts{
+			Archived: spec.Archived,
+		})
+		if err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+		e.log.Debug("Repo archived flag updated", "project", projectKey, "slug", repoSlug, "archived", *spec.Archived)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "Repo '%s/%s' archived: %t", projectKey, repoSlug, *spec.Archived)
+	}
+
+	// The ref synchronization is observed only on forks where it is available.
+	current := cr.Status.AtProvider.RefSync
+	if spec.RefSync == nil || current == nil || *spec.RefSync == *current {
+		return managed.ExternalUpdate{}, nil
+	}
+
+	_, err := e.cli.Repos().SetRefSync(ctx, bitbucket.GetRepoOpts{
+		ProjectKey: projectKey,
+		RepoSlug:   repoSlug,
+	}, *spec.RefSync)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	e.log.Debug("Repo ref sync updated", "project", projectKey, "slug", repoSlug, "enabled", *spec.RefSync)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "Repo '%s/%s' ref sync enabled: %t", projectKey, repoSlug, *spec.RefSync)
+
+	return managed.ExternalUpdate{}, nil
 }
 
 func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
@@ -199,20 +324,148 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
 
 	projectKey := helpers.StringValue(cr.Status.AtProvider.Project)
 	repoSlug := helpers.StringValue(cr.Status.AtProvider.RepoSlug)
-	err := e.cli.Repos().Delete(projectKey, repoSlug)
-	if err == nil {
-		e.log.Debug("Repo deleted", "project", projectKey, "slug", repoSlug)
-		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Repo '%s/%s' deleted", projectKey, repoSlug)
+
+	ref := repoRef{project: projectKey, slug: repoSlug}
+	if pcr := cr.GetProviderConfigReference(); pcr != nil {
+		ref.providerConfig = pcr.Name
+	}
+	if err := e.guardDeletion(ctx, cr, ref); err != nil {
+		return err
+	}
+
+	err := e.verifyMarker(ctx, cr, bitbucket.GetRepoOpts{
+		ProjectKey: projectKey,
+		RepoSlug:   repoSlug,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch mode := deletionMode(e.deletion, e.cli.Flavor()); mode {
+	case bbv1alpha1.RepoDeletionArchive:
+		_, err := e.cli.Repos().Update(ctx, projectKey, repoSlug, bitbucket.UpdateRepoOpts{
+			Archived: helpers.BoolPtr(true),
+		})
+		if err == nil {
+			e.log.Debug("Repo archived", "project", projectKey, "slug", repoSlug)
+			e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Repo '%s/%s' archived", projectKey, repoSlug)
+		}
+		return err
+
+	case bbv1alpha1.RepoDeletionTrash:
+		trash := helpers.StringValue(e.deletion.TrashProject)
+		if len(trash) == 0 {
+			return errors.New(errNoTrashProject)
+		}
+
+		name := trashName(repoSlug, time.Now())
+		_, err := e.cli.Repos().Update(ctx, projectKey, repoSlug, bitbucket.UpdateRepoOpts{
+			Name:       helpers.StringPtr(name),
+			ProjectKey: helpers.StringPtr(trash),
+		})
+		if err == nil {
+			e.log.Debug("Repo moved to trash", "project", projectKey, "slug", repoSlug, "trash", trash, "name", name)
+			e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Repo '%s/%s' moved to '%s/%s'", projectKey, repoSlug, trash, name)
+		}
+		return err
+
+	default:
+		if e.deletion != nil && e.deletion.Export != nil {
+			dir, err := e.export(ctx, e.deletion.Export, projectKey, repoSlug, time.Now())
+			if err != nil {
+				return fmt.Errorf("%s: %w", errCannotExport, err)
+			}
+			e.log.Debug("Repo exported", "project", projectKey, "slug", repoSlug, "path", dir)
+			e.rec.Eventf(cr, corev1.EventTypeNormal, reasonExported, "Repo '%s/%s' exported to '%s'", projectKey, repoSlug, dir)
+		}
+
+		err := e.cli.Repos().Delete(ctx, projectKey, repoSlug)
+		if err == nil {
+			e.log.Debug("Repo deleted", "project", projectKey, "slug", repoSlug)
+			e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Repo '%s/%s' deleted", projectKey, repoSlug)
+		}
+		return err
+	}
+}
+
+// fork creates the repository as a fork of the spec one.
+func (e *external) fork(ctx context.Context, cr *v1alpha1.Repo) (managed.ExternalCreation, error) {
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	repos := e.cli.Repos()
+	res, err := repos.Fork(ctx, bitbucket.ForkRepoOpts{
+		ProjectKey:       spec.ForkFrom.Project,
+		RepoSlug:         spec.ForkFrom.RepoSlug,
+		Name:             spec.Name,
+		TargetProjectKey: spec.Project,
+	})
+	if err != nil {
+		return managed.ExternalCreation{}, err
 	}
+	e.log.Debug("Repo forked", "project", spec.Project, "name", spec.Name, "slug", res.Slug,
+		"origin", fmt.Sprintf("%s/%s", spec.ForkFrom.Project, spec.ForkFrom.RepoSlug))
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Repo '%s/%s' forked from '%s/%s'",
+		spec.Project, spec.Name, spec.ForkFrom.Project, spec.ForkFrom.RepoSlug)
 
-	return err
+	meta.SetExternalName(cr, fmt.Sprintf("%s/%s", spec.Project, res.Slug))
+
+	err = e.stamp(ctx, cr, bitbucket.GetRepoOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   res.Slug,
+	})
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	if helpers.BoolValueOrDefault(spec.RefSync, false) {
+		_, err = repos.SetRefSync(ctx, bitbucket.GetRepoOpts{
+			ProjectKey: spec.Project,
+			RepoSlug:   res.Slug,
+		}, true)
+		if err != nil {
+			return managed.ExternalCreation{}, err
+		}
+		e.log.Debug("Repo ref sync enabled", "project", spec.Project, "slug", res.Slug)
+	}
+
+	return managed.ExternalCreation{}, nil
+}
+
+// isArchivedUpToDate tells whether the repository archived flag matches the desired one.
+func isArchivedUpToDate(desired *bool, repo *bitbucket.Repository) bool {
+	return desired == nil || *desired == repo.Archived
+}
+
+// isRefSyncUpToDate tells whether the fork ref synchronization matches
+// the desired one; it is ignored when not available on the server.
+func isRefSyncUpToDate(desired *bool, sync *bitbucket.RefSyncStatus) bool {
+	if desired == nil || sync == nil || !sync.Available {
+		return true
+	}
+	return sync.Enabled == *desired
 }
 
 // generateObservation produces a repo observation
 func generateObservation(repo *bitbucket.Repository) v1alpha1.RepoObservation {
-	return v1alpha1.RepoObservation{
+	res := v1alpha1.RepoObservation{
 		Project:  helpers.StringPtr(repo.Project.Key),
 		State:    helpers.StringPtr(repo.State),
 		RepoSlug: helpers.StringPtr(repo.Slug),
+		Archived: helpers.BoolPtr(repo.Archived),
+	}
+	if repo.Origin != nil {
+		res.Origin = helpers.StringPtr(fmt.Sprintf("%s/%s", repo.Origin.Project.Key, repo.Origin.Slug))
+	}
+
+	return res
+}
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.Repo)
+	if !ok {
+		return ""
 	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, bitbucket.Slug(spec.Name))
 }
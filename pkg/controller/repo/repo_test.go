@@ -0,0 +1,326 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repo/v1alpha1"
+	bbv1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func newExternal(bb *fake.Bitbucket, opts *bitbucket.ClientOpts) *external {
+	return &external{
+		kube:      &test.MockClient{MockList: test.NewMockListFn(nil), MockUpdate: test.NewMockUpdateFn(nil)},
+		log:       logging.NewNopLogger(),
+		cli:       bb.NewClient(opts),
+		rec:       record.NewFakeRecorder(10),
+		clusterID: "c1",
+	}
+}
+
+func newRepo(name string) *v1alpha1.Repo {
+	cr := &v1alpha1.Repo{}
+	cr.SetName(name)
+	cr.SetUID(types.UID(name))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider.Project = "JXP"
+	cr.Spec.ForProvider.Name = "Demo Repo"
+	return cr
+}
+
+func TestRepoLifecycle(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP")
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+	cr := newRepo("demo")
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "JXP/demo-repo", meta.GetExternalName(cr))
+	assert.Equal(t, []string{markerLabel("c1", "demo")}, bb.Labels("JXP", "demo-repo"))
+	assert.Equal(t, "# demo-repo", string(bb.File("JXP", "demo-repo", "main", "README.md")))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, xpv1.ReasonAvailable, cr.GetCondition(xpv1.TypeReady).Reason)
+	assert.Equal(t, "demo-repo", helpers.StringValue(cr.Status.AtProvider.RepoSlug))
+
+	cr.Spec.ForProvider.Archived = helpers.BoolPtr(true)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, bb.Repo("JXP", "demo-repo").Archived)
+
+	e.deletion = &bbv1alpha1.RepoDeletionPolicy{Mode: bbv1alpha1.RepoDeletionDelete}
+	assert.NoError(t, e.Delete(ctx, cr))
+	assert.Nil(t, bb.Repo("JXP", "demo-repo"))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestRepoCreateConflict(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP")
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+
+	_, err := e.Create(ctx, newRepo("first"))
+	assert.NoError(t, err)
+
+	_, err = e.Create(ctx, newRepo("second"))
+	assert.Error(t, err)
+
+	cr := newRepo("third")
+	cr.Spec.ForProvider.Project = "MISSING"
+	_, err = e.Create(ctx, cr)
+	assert.Error(t, err)
+}
+
+func TestRepoForkRefSync(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP", "JXPF")
+	_, err := bb.NewClient(&bitbucket.ClientOpts{}).Repos().Create(ctx, bitbucket.CreateRepoOpts{
+		Name:       "Demo Repo",
+		ProjectKey: "JXP",
+	})
+	assert.NoError(t, err)
+
+	rec := record.NewFakeRecorder(10)
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+	e.rec = rec
+
+	cr := newRepo("demo")
+	cr.Spec.ForProvider.Project = "JXPF"
+	cr.Spec.ForProvider.ForkFrom = &v1alpha1.ForkSource{Project: "JXP", RepoSlug: "demo-repo"}
+	cr.Spec.ForProvider.RefSync = helpers.BoolPtr(true)
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	<-rec.Events
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.True(t, helpers.BoolValue(cr.Status.AtProvider.RefSync))
+
+	// The synchronization already matching is left as it is.
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+	assert.Empty(t, rec.Events)
+
+	cr.Spec.ForProvider.RefSync = helpers.BoolPtr(false)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+	assert.Contains(t, <-rec.Events, "ref sync enabled: false")
+}
+
+func TestRepoClaimKey(t *testing.T) {
+	// The repository name "Demo Repo" has the slug "demo-repo".
+	assert.Equal(t, "default/JXP/demo-repo", claimKey(newRepo("demo")))
+}
+
+func TestRepoMarker(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP")
+	_, err := bb.NewClient(&bitbucket.ClientOpts{}).Repos().Create(ctx, bitbucket.CreateRepoOpts{
+		Name:       "Demo Repo",
+		ProjectKey: "JXP",
+	})
+	assert.NoError(t, err)
+
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+	cr := newRepo("demo")
+	meta.SetExternalName(cr, "JXP/demo-repo")
+
+	_, err = e.Observe(ctx, cr)
+	assert.Error(t, err)
+	assert.Equal(t, xpv1.ReasonUnavailable, cr.GetCondition(xpv1.TypeReady).Reason)
+	assert.Empty(t, bb.Labels("JXP", "demo-repo"))
+
+	meta.AddAnnotations(cr, map[string]string{v1alpha1.AnnotationKeyForceAdopt: "true"})
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.Equal(t, []string{markerLabel("c1", "demo")}, bb.Labels("JXP", "demo-repo"))
+}
+
+func TestRepoMarkerMigration(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP")
+	_, err := bb.NewClient(&bitbucket.ClientOpts{}).Repos().Create(ctx, bitbucket.CreateRepoOpts{
+		Name:       "Demo Repo",
+		ProjectKey: "JXP",
+	})
+	assert.NoError(t, err)
+
+	// Created by a provider version without markers.
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+	cr := newRepo("demo")
+	meta.SetExternalName(cr, "JXP/demo-repo")
+	meta.SetExternalCreateSucceeded(cr, time.Now())
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.Equal(t, []string{markerLabel("c1", "demo")}, bb.Labels("JXP", "demo-repo"))
+
+	// Another Repo is still refused.
+	other := newRepo("other")
+	meta.SetExternalName(other, "JXP/demo-repo")
+	meta.SetExternalCreateSucceeded(other, time.Now())
+
+	_, err = e.Observe(ctx, other)
+	assert.Error(t, err)
+	assert.Equal(t, []string{markerLabel("c1", "demo")}, bb.Labels("JXP", "demo-repo"))
+}
+
+// failingLabels fails to add the repository labels.
+type failingLabels struct {
+	bitbucket.Interface
+}
+
+func (c failingLabels) Repos() bitbucket.RepoService {
+	return failingLabelsRepos{c.Interface.Repos()}
+}
+
+type failingLabelsRepos struct {
+	bitbucket.RepoService
+}
+
+func (failingLabelsRepos) AddLabel(ctx context.Context, opts bitbucket.GetRepoOpts, name string) error {
+	return errors.New("labels unavailable")
+}
+
+func TestRepoCreateMarkerFailure(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP")
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+	cli := e.cli
+	cr := newRepo("demo")
+
+	// As the managed reconciler does around Create.
+	meta.SetExternalCreatePending(cr, time.Now())
+	e.cli = failingLabels{cli}
+	_, err := e.Create(ctx, cr)
+	assert.Error(t, err)
+	meta.SetExternalCreateFailed(cr, time.Now())
+
+	// The repository is not lost, and is marked once the labels are available.
+	assert.Equal(t, "JXP/demo-repo", meta.GetExternalName(cr))
+	assert.Empty(t, bb.Labels("JXP", "demo-repo"))
+
+	e.cli = cli
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.Equal(t, []string{markerLabel("c1", "demo")}, bb.Labels("JXP", "demo-repo"))
+}
+
+func TestRepoDeleteArchive(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP")
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+	cr := newRepo("demo")
+
+	_, err := e.Create(ctx, cr)
+	assert.NoError(t, err)
+	_, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+	assert.True(t, bb.Repo("JXP", "demo-repo").Archived)
+
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestRepoDeleteCloud(t *testing.T) {
+	ctx := context.Background()
+	bb := fake.New("JXP", "TRASH")
+	bb.Cloud = true
+	e := newExternal(bb, &bitbucket.ClientOpts{})
+
+	// Without a trash project, repositories are deleted.
+	cr := newRepo("demo")
+	_, err := e.Create(ctx, cr)
+	assert.NoError(t, err)
+	_, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+	assert.Nil(t, bb.Repo("JXP", "demo-repo"))
+
+	// With a trash project, they are moved there.
+	e.deletion = &bbv1alpha1.RepoDeletionPolicy{TrashProject: helpers.StringPtr("TRASH")}
+	cr = newRepo("demo")
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	_, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+	assert.Nil(t, bb.Repo("JXP", "demo-repo"))
+	all, err := e.cli.Repos().List(ctx, "TRASH")
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestRepoUnauthorized(t *testing.T) {
+	bb := fake.New("JXP")
+	bb.Token = "secret"
+	e := newExternal(bb, &bitbucket.ClientOpts{Token: "wrong"})
+
+	cr := newRepo("demo")
+	meta.SetExternalName(cr, "JXP/demo-repo")
+
+	_, err := e.Observe(context.Background(), cr)
+	var se bitbucket.StatusError
+	assert.ErrorAs(t, err, &se)
+	assert.Equal(t, 401, se.Code)
+}
+
+func TestRepoReportsErrors(t *testing.T) {
+	bb := fake.New("JXP")
+	bb.Token = "secret"
+	rec := record.NewFakeRecorder(10)
+	e := clients.ReportErrors(newExternal(bb, &bitbucket.ClientOpts{Token: "wrong"}), rec)
+
+	cr := newRepo("demo")
+	meta.SetExternalName(cr, "JXP/demo-repo")
+
+	_, err := e.Observe(context.Background(), cr)
+	assert.ErrorIs(t, err, bitbucket.ErrUnauthorized)
+	assert.Equal(t, clients.ReasonUnauthorized, cr.GetCondition(xpv1.TypeReady).Reason)
+	assert.Equal(t, "Warning Unauthorized cannot observe external resource: "+err.Error(), <-rec.Events)
+}
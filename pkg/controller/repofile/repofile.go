@@ -0,0 +1,374 @@
+package repofile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/repofile/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	errNotRepoFile = "managed resource is not a repo file custom resource"
+
+	reasonCreated = "CreatedExternalResource"
+	reasonUpdated = "UpdatedExternalResource"
+	reasonDeleted = "DeletedExternalResource"
+
+	defaultBranch        = "main"
+	defaultCommitMessage = "Update {{ .Path }}"
+)
+
+// Setup adds a controller that reconciles RepoFile managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := managed.ControllerName(v1alpha1.RepoFileGroupKind)
+
+	log := o.Logger.WithValues("controller", name)
+
+	recorder := mgr.GetEventRecorderFor(name)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(v1alpha1.RepoFileGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     mgr.GetClient(),
+			log:      log,
+			recorder: recorder,
+			clientFn: bitbucket.NewClient,
+		}),
+		managed.WithLogger(log),
+		managed.WithRecorder(event.NewAPIRecorder(recorder)))
+
+	if err := helpers.IndexClaims(mgr, &v1alpha1.RepoFile{}, claimKey); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.RepoFile{}).
+		Watches(clients.Requeues(v1alpha1.RepoFileGroupVersionKind), &handler.EnqueueRequestForObject{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+type connector struct {
+	kube     client.Client
+	log      logging.Logger
+	recorder record.EventRecorder
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.RepoFile)
+	if !ok {
+		return nil, errors.New(errNotRepoFile)
+	}
+
+	cfg, err := clients.GetConfig(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
+
+	return clients.ReportErrors(&external{
+		kube: c.kube,
+		log:  c.log,
+		cli:  c.clientFn(cfg),
+		rec:  c.recorder,
+	}, c.recorder), nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	kube client.Client
+	log  logging.Logger
+	cli  bitbucket.Interface
+	rec  record.EventRecorder
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.RepoFile)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRepoFile)
+	}
+
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.RepoFileList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	// Bitbucket has no API to remove a file: a deleted
+	// resource leaves the file in the repository.
+	if meta.WasDeleted(cr) {
+		spec := cr.Spec.ForProvider
+		e.log.Debug("File no more managed", "path", spec.Path)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "File '%s' left in repository '%s/%s'", spec.Path, spec.Project, spec.RepoSlug)
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := fileOpts(spec)
+
+	raw, err := e.cli.Repos().GetRaw(ctx, opts)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	if raw == nil {
+		e.log.Debug("File not found", "path", spec.Path, "branch", opts.Branch)
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	last, err := e.cli.Repos().LastCommit(ctx, opts)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	cr.Status.AtProvider = generateObservation(spec, last)
+
+	content, err := e.content(ctx, spec)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	cr.Status.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: bytes.Equal(raw, content),
+	}, nil
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.RepoFile)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotRepoFile)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	commit, err := e.commit(ctx, cr, "")
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	e.log.Debug("File created", "path", spec.Path, "commit", commit.Id)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "File '%s' created (commit: %s)", spec.Path, commit.Id)
+
+	return managed.ExternalCreation{}, nil
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.RepoFile)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRepoFile)
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := fileOpts(spec)
+
+	// The edit is based on the head of the branch, as long as the file
+	// has not been changed since it was observed: the last commit of the
+	// file observed along with its content guards against overwriting
+	// changes pushed in the meantime.
+	head, err := e.head(ctx, spec)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	opts.Branch = head
+	last, err := e.cli.Repos().LastCommit(ctx, opts)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if observed := helpers.StringValue(cr.Status.AtProvider.CommitID); last == nil || last.Id != observed {
+		return managed.ExternalUpdate{}, fmt.Errorf("file '%s' changed since commit '%s'", spec.Path, observed)
+	}
+
+	commit, err := e.commit(ctx, cr, head)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	e.log.Debug("File updated", "path", spec.Path, "commit", commit.Id)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "File '%s' updated (commit: %s)", spec.Path, commit.Id)
+
+	return managed.ExternalUpdate{}, nil
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.RepoFile)
+	if !ok {
+		return errors.New(errNotRepoFile)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	// Never called, since Observe reports a deleted resource as gone.
+	return nil
+}
+
+// commit pushes the desired content and records the new commit in the status.
+func (e *external) commit(ctx context.Context, cr *v1alpha1.RepoFile, sourceCommitId string) (*bitbucket.Commit, error) {
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	content, err := e.content(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := commitMessage(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := e.cli.Repos().CommitFile(ctx, bitbucket.CommitFileOpts{
+		ProjectKey:     spec.Project,
+		RepoSlug:       spec.RepoSlug,
+		Path:           spec.Path,
+		Branch:         branchOf(spec),
+		Message:        msg,
+		Content:        content,
+		SourceCommitId: sourceCommitId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cr.Status.AtProvider = generateObservation(spec, commit)
+
+	return commit, nil
+}
+
+// head returns the last commit of the branch of the file.
+func (e *external) head(ctx context.Context, spec *v1alpha1.RepoFileParams) (string, error) {
+	branches, err := e.cli.Repos().Branches(ctx, bitbucket.GetRepoOpts{ProjectKey: spec.Project, RepoSlug: spec.RepoSlug})
+	if err != nil {
+		return "", err
+	}
+
+	name := branchName(spec)
+	for _, el := range branches {
+		if el.DisplayId == name || el.Id == "refs/heads/"+name {
+			return el.LatestCommit, nil
+		}
+	}
+
+	return "", fmt.Errorf("branch '%s' not found", name)
+}
+
+// content returns the desired file content.
+func (e *external) content(ctx context.Context, spec *v1alpha1.RepoFileParams) ([]byte, error) {
+	if spec.Content != nil {
+		return []byte(*spec.Content), nil
+	}
+
+	src := spec.ContentFrom
+	if src == nil {
+		return []byte{}, nil
+	}
+
+	if ref := src.ConfigMapKeyRef; ref != nil {
+		val, err := helpers.GetConfigMapValue(ctx, e.kube, ref.Namespace, ref.Name, ref.Key)
+		return []byte(val), err
+	}
+
+	if ref := src.SecretKeyRef; ref != nil {
+		val, err := helpers.GetSecret(ctx, e.kube, ref)
+		return []byte(val), err
+	}
+
+	return []byte{}, nil
+}
+
+func branchOf(spec *v1alpha1.RepoFileParams) string {
+	return helpers.StringValue(helpers.StringOrDefault(spec.Branch, defaultBranch))
+}
+
+// branchName returns the name of the branch of the file, without the
+// refs/heads/ prefix it may be given with.
+func branchName(spec *v1alpha1.RepoFileParams) string {
+	return strings.TrimPrefix(branchOf(spec), "refs/heads/")
+}
+
+func fileOpts(spec *v1alpha1.RepoFileParams) bitbucket.FileOpts {
+	return bitbucket.FileOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   spec.RepoSlug,
+		Path:       spec.Path,
+		Branch:     branchOf(spec),
+	}
+}
+
+// commitMessage renders the commit message template
+func commitMessage(cr *v1alpha1.RepoFile) (string, error) {
+	spec := cr.Spec.ForProvider
+
+	tpl, err := template.New("message").Parse(helpers.StringValue(helpers.StringOrDefault(spec.CommitMessage, defaultCommitMessage)))
+	if err != nil {
+		return "", err
+	}
+
+	buf := strings.Builder{}
+	err = tpl.Execute(&buf, map[string]string{
+		"Path":   spec.Path,
+		"Branch": branchOf(&spec),
+		"Name":   cr.GetName(),
+	})
+
+	return buf.String(), err
+}
+
+// generateObservation produces a repo file observation
+func generateObservation(spec *v1alpha1.RepoFileParams, commit *bitbucket.Commit) v1alpha1.RepoFileObservation {
+	res := v1alpha1.RepoFileObservation{
+		Project:  helpers.StringPtr(spec.Project),
+		RepoSlug: helpers.StringPtr(spec.RepoSlug),
+		Path:     helpers.StringPtr(spec.Path),
+		Branch:   helpers.StringPtr(branchOf(spec)),
+	}
+	if commit != nil {
+		res.CommitID = helpers.LateInitializeString(nil, commit.Id)
+	}
+
+	return res
+}
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.RepoFile)
+	if !ok {
+		return ""
+	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, spec.RepoSlug, branchName(&spec), spec.Path)
+}
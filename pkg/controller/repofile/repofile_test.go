@@ -0,0 +1,142 @@
+package repofile
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repofile/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func newExternal(t *testing.T) (*external, *fake.Bitbucket) {
+	fx := fake.NewFixture(t)
+
+	return &external{
+		kube: fx.Kube,
+		log:  logging.NewNopLogger(),
+		cli:  fx.Client,
+		rec:  record.NewFakeRecorder(10),
+	}, fx.Bitbucket
+}
+
+func newRepoFile(content string) *v1alpha1.RepoFile {
+	cr := &v1alpha1.RepoFile{}
+	cr.SetName("codeowners")
+	cr.SetUID(types.UID("codeowners"))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider = v1alpha1.RepoFileParams{
+		Project:  "JXP",
+		RepoSlug: "demo-repo",
+		Path:     "CODEOWNERS",
+		Content:  helpers.StringPtr(content),
+	}
+	return cr
+}
+
+// push commits a file as someone else would.
+func push(t *testing.T, e *external, path, content string) {
+	opts := bitbucket.FileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: path, Branch: "main"}
+	last, err := e.cli.Repos().LastCommit(context.Background(), opts)
+	assert.NoError(t, err)
+
+	commit := bitbucket.CommitFileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: path, Branch: "main", Content: []byte(content)}
+	if last != nil {
+		commit.SourceCommitId = last.Id
+	}
+	_, err = e.cli.Repos().CommitFile(context.Background(), commit)
+	assert.NoError(t, err)
+}
+
+func TestRepoFileLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e, bb := newExternal(t)
+
+	cr := newRepoFile("* @jdoe")
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "* @jdoe", string(bb.File("JXP", "demo-repo", "main", "CODEOWNERS")))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, xpv1.ReasonAvailable, cr.GetCondition(xpv1.TypeReady).Reason)
+
+	cr.Spec.ForProvider.Content = helpers.StringPtr("* @jdoe @jsmith")
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	// Other files changed on the branch do not conflict with the edit.
+	push(t, e, "README.md", "# demo")
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "* @jdoe @jsmith", string(bb.File("JXP", "demo-repo", "main", "CODEOWNERS")))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// The file is left in the repository.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+	assert.Equal(t, "* @jdoe @jsmith", string(bb.File("JXP", "demo-repo", "main", "CODEOWNERS")))
+}
+
+func TestRepoFileChangedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	e, bb := newExternal(t)
+
+	cr := newRepoFile("* @jdoe")
+	_, err := e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	cr.Spec.ForProvider.Content = helpers.StringPtr("* @jsmith")
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	// The file is changed after being observed.
+	push(t, e, "CODEOWNERS", "* @admins")
+
+	_, err = e.Update(ctx, cr)
+	assert.Error(t, err)
+	assert.Equal(t, "* @admins", string(bb.File("JXP", "demo-repo", "main", "CODEOWNERS")))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+	assert.Equal(t, "* @jsmith", string(bb.File("JXP", "demo-repo", "main", "CODEOWNERS")))
+}
+
+func TestRepoFileClaimKey(t *testing.T) {
+	cr := newRepoFile("")
+	assert.Equal(t, "default/JXP/demo-repo/main/CODEOWNERS", claimKey(cr))
+
+	// The same branch, however it is given.
+	for _, el := range []string{"main", "refs/heads/main"} {
+		cr.Spec.ForProvider.Branch = helpers.StringPtr(el)
+		assert.Equal(t, "default/JXP/demo-repo/main/CODEOWNERS", claimKey(cr))
+	}
+}
@@ -0,0 +1,342 @@
+package repohook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/repohook/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	errNotRepoHook      = "managed resource is not a repo hook custom resource"
+	errCloudUnsupported = "repo hooks are not supported on Bitbucket Cloud"
+
+	reasonCreated = "CreatedExternalResource"
+	reasonUpdated = "UpdatedExternalResource"
+	reasonDeleted = "DeletedExternalResource"
+)
+
+// Setup adds a controller that reconciles RepoHook managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := managed.ControllerName(v1alpha1.RepoHookGroupKind)
+
+	log := o.Logger.WithValues("controller", name)
+
+	recorder := mgr.GetEventRecorderFor(name)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(v1alpha1.RepoHookGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     mgr.GetClient(),
+			log:      log,
+			recorder: recorder,
+			clientFn: bitbucket.NewClient,
+		}),
+		managed.WithLogger(log),
+		managed.WithRecorder(event.NewAPIRecorder(recorder)))
+
+	if err := helpers.IndexClaims(mgr, &v1alpha1.RepoHook{}, claimKey); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.RepoHook{}).
+		Watches(clients.Requeues(v1alpha1.RepoHookGroupVersionKind), &handler.EnqueueRequestForObject{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+type connector struct {
+	kube     client.Client
+	log      logging.Logger
+	recorder record.EventRecorder
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.RepoHook)
+	if !ok {
+		return nil, errors.New(errNotRepoHook)
+	}
+
+	cfg, err := clients.GetConfig(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
+	if cfg.Flavor == bitbucket.FlavorCloud {
+		return nil, errors.New(errCloudUnsupported)
+	}
+
+	return clients.ReportErrors(&external{
+		kube: c.kube,
+		log:  c.log,
+		cli:  c.clientFn(cfg),
+		rec:  c.recorder,
+	}, c.recorder), nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	kube client.Client
+	log  logging.Logger
+	cli  bitbucket.Interface
+	rec  record.EventRecorder
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.RepoHook)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRepoHook)
+	}
+
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.RepoHookList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := hookOpts(spec)
+
+	hook, err := e.cli.Hooks().Get(ctx, opts)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	if hook == nil {
+		e.log.Debug("Hook not found", "project", spec.Project, "slug", opts.RepoSlug, "hook", spec.HookKey)
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	cr.Status.AtProvider = generateObservation(spec, hook)
+
+	// A project hook always has a state, while a repository hook is
+	// managed only when it overrides the project configuration.
+	isProject := len(opts.RepoSlug) == 0
+	inherit := !isProject && helpers.BoolValueOrDefault(spec.Inherit, false)
+
+	var exists bool
+	switch {
+	case meta.WasDeleted(cr) && isProject:
+		exists = hook.Enabled
+	case meta.WasDeleted(cr):
+		exists = hook.Scope.Type == bitbucket.HookScopeRepository
+	case isProject, inherit:
+		exists = true
+	default:
+		exists = hook.Scope.Type == bitbucket.HookScopeRepository
+	}
+
+	if !exists {
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	isUpToDate, err := e.isUpToDate(ctx, spec, hook)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	cr.Status.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: isUpToDate,
+	}, nil
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.RepoHook)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotRepoHook)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	if err := e.configure(ctx, spec); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	e.log.Debug("Hook configured", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug), "hook", spec.HookKey)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Hook '%s' configured", spec.HookKey)
+
+	return managed.ExternalCreation{}, nil
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.RepoHook)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRepoHook)
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	if err := e.configure(ctx, spec); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	e.log.Debug("Hook updated", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug), "hook", spec.HookKey)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "Hook '%s' updated", spec.HookKey)
+
+	return managed.ExternalUpdate{}, nil
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.RepoHook)
+	if !ok {
+		return errors.New(errNotRepoHook)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	opts := hookOpts(spec)
+
+	var err error
+	if len(opts.RepoSlug) == 0 {
+		_, err = e.cli.Hooks().Disable(ctx, opts)
+	} else {
+		err = e.cli.Hooks().Inherit(ctx, opts)
+	}
+	if err == nil {
+		e.log.Debug("Hook reset", "project", spec.Project, "slug", opts.RepoSlug, "hook", spec.HookKey)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Hook '%s' reset", spec.HookKey)
+	}
+
+	return err
+}
+
+// configure applies the desired hook state; settings are stored before
+// enabling, since hooks requiring configuration cannot be enabled without.
+func (e *external) configure(ctx context.Context, spec *v1alpha1.RepoHookParams) error {
+	opts := hookOpts(spec)
+	hooks := e.cli.Hooks()
+
+	if len(opts.RepoSlug) > 0 && helpers.BoolValueOrDefault(spec.Inherit, false) {
+		return hooks.Inherit(ctx, opts)
+	}
+
+	if spec.Settings != nil && len(spec.Settings.Raw) > 0 {
+		if err := hooks.SetSettings(ctx, opts, spec.Settings.Raw); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if helpers.BoolValueOrDefault(spec.Enabled, true) {
+		_, err = hooks.Enable(ctx, opts)
+	} else {
+		_, err = hooks.Disable(ctx, opts)
+	}
+
+	return err
+}
+
+func (e *external) isUpToDate(ctx context.Context, spec *v1alpha1.RepoHookParams, hook *bitbucket.RepositoryHook) (bool, error) {
+	opts := hookOpts(spec)
+
+	if len(opts.RepoSlug) > 0 && helpers.BoolValueOrDefault(spec.Inherit, false) {
+		return hook.Scope.Type == bitbucket.HookScopeProject, nil
+	}
+
+	if hook.Enabled != helpers.BoolValueOrDefault(spec.Enabled, true) {
+		return false, nil
+	}
+
+	if spec.Settings == nil || len(spec.Settings.Raw) == 0 {
+		return true, nil
+	}
+
+	current, err := e.cli.Hooks().GetSettings(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+
+	return isJSONEqual(spec.Settings.Raw, current), nil
+}
+
+// isJSONEqual compares two JSON documents ignoring formatting and keys order.
+func isJSONEqual(a, b []byte) bool {
+	if len(b) == 0 {
+		return len(a) == 0
+	}
+
+	var x, y interface{}
+	if err := json.Unmarshal(a, &x); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(x, y)
+}
+
+func hookOpts(spec *v1alpha1.RepoHookParams) bitbucket.HookOpts {
+	return bitbucket.HookOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   helpers.StringValue(spec.RepoSlug),
+		HookKey:    spec.HookKey,
+	}
+}
+
+// generateObservation produces a repo hook observation
+func generateObservation(spec *v1alpha1.RepoHookParams, hook *bitbucket.RepositoryHook) v1alpha1.RepoHookObservation {
+	res := v1alpha1.RepoHookObservation{
+		Project: helpers.StringPtr(spec.Project),
+		Name:    helpers.StringPtr(hook.Details.Name),
+		Enabled: helpers.BoolPtr(hook.Enabled),
+		Scope:   helpers.StringPtr(hook.Scope.Type),
+	}
+	if spec.RepoSlug != nil {
+		res.RepoSlug = helpers.StringPtr(*spec.RepoSlug)
+	}
+
+	if len(hook.Details.Name) == 0 {
+		res.Name = helpers.StringPtr(spec.HookKey)
+	}
+
+	return res
+}
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.RepoHook)
+	if !ok {
+		return ""
+	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, helpers.StringValue(spec.RepoSlug), spec.HookKey)
+}
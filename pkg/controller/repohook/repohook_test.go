@@ -0,0 +1,157 @@
+package repohook
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repohook/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const hookKey = "com.atlassian.bitbucket.server.bitbucket-bundled-hooks:force-push-hook"
+
+func newExternal(t *testing.T) (*external, bitbucket.Interface) {
+	fx := fake.NewFixture(t)
+
+	return &external{
+		kube: fx.Kube,
+		log:  logging.NewNopLogger(),
+		cli:  fx.Client,
+		rec:  record.NewFakeRecorder(10),
+	}, fx.Client
+}
+
+func newRepoHook(slug *string) *v1alpha1.RepoHook {
+	cr := &v1alpha1.RepoHook{}
+	cr.SetName("force-push")
+	cr.SetUID(types.UID("force-push"))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider = v1alpha1.RepoHookParams{
+		Project:  "JXP",
+		RepoSlug: slug,
+		HookKey:  hookKey,
+	}
+	return cr
+}
+
+func TestRepoHookLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+	opts := bitbucket.HookOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", HookKey: hookKey}
+
+	cr := newRepoHook(helpers.StringPtr("demo-repo"))
+	cr.Spec.ForProvider.Settings = &runtime.RawExtension{Raw: []byte(`{"refs": "refs/heads/main"}`)}
+
+	// The repository inherits the project configuration.
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+	assert.Equal(t, bitbucket.HookScopeProject, helpers.StringValue(cr.Status.AtProvider.Scope))
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	hook, err := cli.Hooks().Get(ctx, opts)
+	assert.NoError(t, err)
+	assert.True(t, hook.Enabled)
+	assert.Equal(t, bitbucket.HookScopeRepository, hook.Scope.Type)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, xpv1.ReasonAvailable, cr.GetCondition(xpv1.TypeReady).Reason)
+
+	cr.Spec.ForProvider.Settings = &runtime.RawExtension{Raw: []byte(`{"refs": "refs/heads/release"}`)}
+	cr.Spec.ForProvider.Enabled = helpers.BoolPtr(false)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// Deleting restores the inheritance of the project configuration.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	hook, err = cli.Hooks().Get(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, bitbucket.HookScopeProject, hook.Scope.Type)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestRepoHookProject(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+	opts := bitbucket.HookOpts{ProjectKey: "JXP", HookKey: hookKey}
+
+	cr := newRepoHook(nil)
+
+	// A project hook always exists, disabled until configured.
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	// The repositories inherit the project hook.
+	hook, err := cli.Hooks().Get(ctx, bitbucket.HookOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", HookKey: hookKey})
+	assert.NoError(t, err)
+	assert.True(t, hook.Enabled)
+
+	// Deleting disables the hook, then the resource is gone.
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	hook, err = cli.Hooks().Get(ctx, opts)
+	assert.NoError(t, err)
+	assert.False(t, hook.Enabled)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
+
+func TestRepoHookRepoNotFound(t *testing.T) {
+	ctx := context.Background()
+	e, _ := newExternal(t)
+
+	cr := newRepoHook(helpers.StringPtr("missing"))
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.Error(t, err)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
@@ -24,13 +25,13 @@ import (
 	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 const (
 	errNotRepoPermissionUser = "managed resource is not a repo permission user custom resource"
 
-	reasonCannotCreate = "CannotCreateExternalResource"
-	reasonCreated      = "CreatedExternalResource"
+	reasonCreated = "CreatedExternalResource"
 )
 
 // Setup adds a controller that reconciles Token managed resources.
@@ -50,10 +51,15 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		managed.WithLogger(log),
 		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
 
+	if err := helpers.IndexClaims(mgr, &v1alpha1.RepoPermissionUser{}, claimKey); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		WithOptions(o.ForControllerRuntime()).
 		For(&v1alpha1.RepoPermissionUser{}).
+		Watches(clients.Requeues(v1alpha1.RepoPermissionUserGroupVersionKind), &handler.EnqueueRequestForObject{}).
 		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
 }
 
@@ -61,7 +67,7 @@ type connector struct {
 	kube     client.Client
 	log      logging.Logger
 	recorder record.EventRecorder
-	clientFn func(opts *bitbucket.ClientOpts) *bitbucket.Client
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
 }
 
 func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
@@ -74,13 +80,14 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	if err != nil {
 		return nil, err
 	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
 
-	return &external{
+	return clients.ReportErrors(&external{
 		kube: c.kube,
 		log:  c.log,
-		cli:  bitbucket.NewClient(cfg),
+		cli:  c.clientFn(cfg),
 		rec:  c.recorder,
-	}, nil
+	}, c.recorder), nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -88,7 +95,7 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 type external struct {
 	kube client.Client
 	log  logging.Logger
-	cli  *bitbucket.Client
+	cli  bitbucket.Interface
 	rec  record.EventRecorder
 }
 
@@ -98,9 +105,16 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.New(errNotRepoPermissionUser)
 	}
 
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.RepoPermissionUserList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
 	spec := cr.Spec.ForProvider.DeepCopy()
 
-	usr, err := e.cli.Repos().GetUserPermissions(bitbucket.UserPermissionOpts{
+	usr, err := e.cli.Permissions().GetUserPermissions(ctx, bitbucket.UserPermissionOpts{
 		ProjectKey: spec.Project,
 		RepoSlug:   spec.RepoSlug,
 		User:       spec.User,
@@ -139,8 +153,7 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	spec := cr.Spec.ForProvider.DeepCopy()
 
-	repos := e.cli.Repos()
-	err := repos.SetUserPermissions(bitbucket.UserPermissionOpts{
+	err := e.cli.Permissions().SetUserPermissions(ctx, bitbucket.UserPermissionOpts{
 		ProjectKey: spec.Project,
 		RepoSlug:   spec.RepoSlug,
 		User:       spec.User,
@@ -170,8 +183,7 @@ func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	spec := cr.Spec.ForProvider.DeepCopy()
 
-	repos := e.cli.Repos()
-	err := repos.SetUserPermissions(bitbucket.UserPermissionOpts{
+	err := e.cli.Permissions().SetUserPermissions(ctx, bitbucket.UserPermissionOpts{
 		ProjectKey: spec.Project,
 		RepoSlug:   spec.RepoSlug,
 		User:       spec.User,
@@ -199,9 +211,22 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
 
 	cr.SetConditions(xpv1.Deleting())
 
-	return e.cli.Repos().DeleteUserPermissions(bitbucket.UserPermissionOpts{
-		ProjectKey: helpers.StringValue(cr.Status.AtProvider.Project),
-		RepoSlug:   helpers.StringValue(cr.Status.AtProvider.RepoSlug),
-		User:       helpers.StringValue(cr.Status.AtProvider.User),
+	// The spec is used since the status may have never been observed.
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	return e.cli.Permissions().DeleteUserPermissions(ctx, bitbucket.UserPermissionOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   spec.RepoSlug,
+		User:       spec.User,
 	})
 }
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.RepoPermissionUser)
+	if !ok {
+		return ""
+	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, spec.RepoSlug, spec.User)
+}
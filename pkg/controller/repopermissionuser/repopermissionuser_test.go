@@ -0,0 +1,74 @@
+package repopermissionuser
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/repopermissionuser/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func TestRepoPermissionUserLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	bb := fake.New("JXP")
+	cli := bb.NewClient(&bitbucket.ClientOpts{})
+	_, err := cli.Repos().Create(ctx, bitbucket.CreateRepoOpts{Name: "demo-repo", ProjectKey: "JXP"})
+	assert.NoError(t, err)
+
+	e := &external{
+		kube: &test.MockClient{MockList: test.NewMockListFn(nil), MockUpdate: test.NewMockUpdateFn(nil)},
+		log:  logging.NewNopLogger(),
+		cli:  cli,
+		rec:  record.NewFakeRecorder(10),
+	}
+
+	cr := &v1alpha1.RepoPermissionUser{}
+	cr.SetName("jdoe")
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider = v1alpha1.RepoPermissionUserParams{
+		Project:    "JXP",
+		RepoSlug:   "demo-repo",
+		User:       "jdoe",
+		Permission: "read",
+	}
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, "REPO_READ", helpers.StringValue(cr.Status.AtProvider.Permission))
+
+	cr.Spec.ForProvider.Permission = "write"
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
@@ -0,0 +1,274 @@
+package requiredbuilds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/requiredbuilds/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+const (
+	errNotRequiredBuilds = "managed resource is not a required builds custom resource"
+	errCloudUnsupported  = "required builds merge checks are not supported on Bitbucket Cloud"
+
+	reasonCreated = "CreatedExternalResource"
+	reasonUpdated = "UpdatedExternalResource"
+	reasonDeleted = "DeletedExternalResource"
+)
+
+// Setup adds a controller that reconciles RequiredBuilds managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := managed.ControllerName(v1alpha1.RequiredBuildsGroupKind)
+
+	log := o.Logger.WithValues("controller", name)
+
+	recorder := mgr.GetEventRecorderFor(name)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(v1alpha1.RequiredBuildsGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     mgr.GetClient(),
+			log:      log,
+			recorder: recorder,
+			clientFn: bitbucket.NewClient,
+		}),
+		managed.WithLogger(log),
+		managed.WithRecorder(event.NewAPIRecorder(recorder)))
+
+	if err := helpers.IndexClaims(mgr, &v1alpha1.RequiredBuilds{}, claimKey); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(&v1alpha1.RequiredBuilds{}).
+		Watches(clients.Requeues(v1alpha1.RequiredBuildsGroupVersionKind), &handler.EnqueueRequestForObject{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+type connector struct {
+	kube     client.Client
+	log      logging.Logger
+	recorder record.EventRecorder
+	clientFn func(opts *bitbucket.ClientOpts) bitbucket.Interface
+}
+
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*v1alpha1.RequiredBuilds)
+	if !ok {
+		return nil, errors.New(errNotRequiredBuilds)
+	}
+
+	cfg, err := clients.GetConfig(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+	clients.Instrument(cfg, c.log, c.recorder, cr)
+	if cfg.Flavor == bitbucket.FlavorCloud {
+		return nil, errors.New(errCloudUnsupported)
+	}
+
+	return clients.ReportErrors(&external{
+		kube: c.kube,
+		log:  c.log,
+		cli:  c.clientFn(cfg),
+		rec:  c.recorder,
+	}, c.recorder), nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	kube client.Client
+	log  logging.Logger
+	cli  bitbucket.Interface
+	rec  record.EventRecorder
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.RequiredBuilds)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRequiredBuilds)
+	}
+
+	if err := helpers.CheckClaim(ctx, e.kube, cr, &v1alpha1.RequiredBuildsList{}, claimKey); err != nil {
+		if meta.WasDeleted(cr) && helpers.IsClaimConflict(err) {
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	// The external name is the condition identifier, assigned by Bitbucket on creation.
+	id, err := strconv.Atoi(meta.GetExternalName(cr))
+	if err != nil {
+		return managed.ExternalObservation{
+			ResourceExists: false,
+		}, nil
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	cond, err := e.cli.RequiredBuilds().Get(ctx, requiredBuildsOpts(spec), id)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	if cond == nil {
+		e.log.Debug("Required builds condition does not exists", "project", spec.Project, "id", id)
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
+
+	cr.Status.AtProvider = generateObservation(spec, cond)
+	desired := generateCondition(spec)
+
+	cr.Status.SetConditions(xpv1.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists:   true,
+		ResourceUpToDate: bitbucket.IsSameCondition(&desired, cond),
+	}, nil
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.RequiredBuilds)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotRequiredBuilds)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	res, err := e.cli.RequiredBuilds().Create(ctx, requiredBuildsOpts(spec), generateCondition(spec))
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	e.log.Debug("Required builds created", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug), "id", res.Id)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonCreated, "Required builds '%d' created", res.Id)
+
+	meta.SetExternalName(cr, strconv.Itoa(res.Id))
+
+	return managed.ExternalCreation{}, nil
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.RequiredBuilds)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRequiredBuilds)
+	}
+
+	id, err := strconv.Atoi(meta.GetExternalName(cr))
+	if err != nil {
+		return managed.ExternalUpdate{}, fmt.Errorf("invalid required builds id '%s'", meta.GetExternalName(cr))
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	_, err = e.cli.RequiredBuilds().Update(ctx, requiredBuildsOpts(spec), id, generateCondition(spec))
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	e.log.Debug("Required builds updated", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug), "id", id)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, reasonUpdated, "Required builds '%d' updated", id)
+
+	return managed.ExternalUpdate{}, nil
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.RequiredBuilds)
+	if !ok {
+		return errors.New(errNotRequiredBuilds)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	id, err := strconv.Atoi(meta.GetExternalName(cr))
+	if err != nil {
+		return nil
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+
+	err = e.cli.RequiredBuilds().Delete(ctx, requiredBuildsOpts(spec), id)
+	if err == nil {
+		e.log.Debug("Required builds deleted", "project", spec.Project, "slug", helpers.StringValue(spec.RepoSlug), "id", id)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, reasonDeleted, "Required builds '%d' deleted", id)
+	}
+
+	return err
+}
+
+func requiredBuildsOpts(spec *v1alpha1.RequiredBuildsParams) bitbucket.RequiredBuildsOpts {
+	return bitbucket.RequiredBuildsOpts{
+		ProjectKey: spec.Project,
+		RepoSlug:   helpers.StringValue(spec.RepoSlug),
+	}
+}
+
+// generateCondition produces the required builds condition described by the spec
+func generateCondition(spec *v1alpha1.RequiredBuildsParams) bitbucket.RequiredBuildCondition {
+	res := bitbucket.RequiredBuildCondition{
+		BuildParentKeys: spec.BuildParentKeys,
+		RefMatcher:      bitbucket.NewRefMatcher(spec.TargetRefMatcher.Type, spec.TargetRefMatcher.ID),
+	}
+	if m := spec.SourceRefMatcher; m != nil {
+		res.SourceRefMatcher = bitbucket.NewRefMatcher(m.Type, m.ID)
+	}
+	if m := spec.ExemptRefMatcher; m != nil {
+		res.ExemptRefMatcher = bitbucket.NewRefMatcher(m.Type, m.ID)
+	}
+
+	return res
+}
+
+// generateObservation produces a required builds observation
+func generateObservation(spec *v1alpha1.RequiredBuildsParams, cond *bitbucket.RequiredBuildCondition) v1alpha1.RequiredBuildsObservation {
+	res := v1alpha1.RequiredBuildsObservation{
+		ID:              &cond.Id,
+		Project:         helpers.StringPtr(spec.Project),
+		BuildParentKeys: cond.BuildParentKeys,
+	}
+	if spec.RepoSlug != nil {
+		res.RepoSlug = helpers.StringPtr(*spec.RepoSlug)
+	}
+
+	return res
+}
+
+// claimKey returns the key of the Bitbucket object managed by the resource.
+func claimKey(mg resource.Managed) string {
+	cr, ok := mg.(*v1alpha1.RequiredBuilds)
+	if !ok {
+		return ""
+	}
+	spec := cr.Spec.ForProvider
+	return helpers.ClaimKey(cr, spec.Project, helpers.StringValue(spec.RepoSlug),
+		spec.TargetRefMatcher.Type, spec.TargetRefMatcher.ID)
+}
@@ -0,0 +1,140 @@
+package requiredbuilds
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1alpha1 "github.com/krateoplatformops/provider-bitbucket/apis/requiredbuilds/v1alpha1"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket/fake"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func newExternal(t *testing.T) (*external, bitbucket.Interface) {
+	fx := fake.NewFixture(t)
+
+	return &external{
+		kube: fx.Kube,
+		log:  logging.NewNopLogger(),
+		cli:  fx.Client,
+		rec:  record.NewFakeRecorder(10),
+	}, fx.Client
+}
+
+func newRequiredBuilds() *v1alpha1.RequiredBuilds {
+	cr := &v1alpha1.RequiredBuilds{}
+	cr.SetName("main-builds")
+	cr.SetUID(types.UID("main-builds"))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider = v1alpha1.RequiredBuildsParams{
+		Project:          "JXP",
+		RepoSlug:         helpers.StringPtr("demo-repo"),
+		BuildParentKeys:  []string{"build"},
+		TargetRefMatcher: v1alpha1.RefMatcher{Type: "BRANCH", ID: "refs/heads/main"},
+	}
+	return cr
+}
+
+func TestRequiredBuildsLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+	opts := bitbucket.RequiredBuildsOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	cr := newRequiredBuilds()
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	_, err = e.Create(ctx, cr)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, meta.GetExternalName(cr))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+	assert.True(t, obs.ResourceUpToDate)
+	assert.Equal(t, xpv1.ReasonAvailable, cr.GetCondition(xpv1.TypeReady).Reason)
+
+	cr.Spec.ForProvider.BuildParentKeys = []string{"build", "test"}
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceUpToDate)
+
+	_, err = e.Update(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceUpToDate)
+
+	now := metav1.Now()
+	cr.SetDeletionTimestamp(&now)
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	all, err := cli.RequiredBuilds().List(ctx, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+
+	// Deleting again is a no-op.
+	assert.NoError(t, e.Delete(ctx, cr))
+}
+
+func TestRequiredBuildsCreateExisting(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+	opts := bitbucket.RequiredBuildsOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+
+	// An identical condition, i.e. created by hand, is not taken over.
+	_, err := cli.RequiredBuilds().Create(ctx, opts, bitbucket.RequiredBuildCondition{
+		BuildParentKeys: []string{"build"},
+		RefMatcher:      bitbucket.NewRefMatcher("BRANCH", "refs/heads/main"),
+	})
+	assert.NoError(t, err)
+
+	cr := newRequiredBuilds()
+	_, err = e.Create(ctx, cr)
+	assert.ErrorIs(t, err, bitbucket.ErrConflict)
+	assert.Empty(t, meta.GetExternalName(cr))
+
+	all, err := cli.RequiredBuilds().List(ctx, opts)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestRequiredBuildsProject(t *testing.T) {
+	ctx := context.Background()
+	e, cli := newExternal(t)
+
+	cr := newRequiredBuilds()
+	cr.Spec.ForProvider.RepoSlug = nil
+
+	_, err := e.Create(ctx, cr)
+	assert.NoError(t, err)
+
+	obs, err := e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.True(t, obs.ResourceExists)
+
+	all, err := cli.RequiredBuilds().List(ctx, bitbucket.RequiredBuildsOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+
+	assert.NoError(t, e.Delete(ctx, cr))
+
+	obs, err = e.Observe(ctx, cr)
+	assert.NoError(t, err)
+	assert.False(t, obs.ResourceExists)
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func GetConfigMapValue(ctx context.Context, k client.Client, namespace, name, key string) (string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := k.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+		return "", errors.Wrapf(err, "cannot get %s configmap", name)
+	}
+
+	val, ok := cm.Data[key]
+	if !ok {
+		return "", errors.Errorf("key %s not found in %s configmap", key, name)
+	}
+
+	return val, nil
+}
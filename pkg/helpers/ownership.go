@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ClaimIndexField is the field index holding the key of the
+	// Bitbucket object a managed resource manages.
+	ClaimIndexField = "bitbucket.krateo.io/claim"
+
+	// AnnotationKeyClaim is the annotation holding the key of the
+	// Bitbucket object claimed by a managed resource.
+	AnnotationKeyClaim = "bitbucket.krateo.io/claim"
+)
+
+// A ClaimKeyFunc returns the key of the Bitbucket object a managed resource
+// manages; it must only depend on the spec and the ProviderConfig reference.
+type ClaimKeyFunc func(mg resource.Managed) string
+
+// ClaimKey joins the ProviderConfig name and the parts identifying a Bitbucket object.
+func ClaimKey(mg resource.Managed, parts ...string) string {
+	pc := ""
+	if ref := mg.GetProviderConfigReference(); ref != nil {
+		pc = ref.Name
+	}
+	return strings.Join(append([]string{pc}, parts...), "/")
+}
+
+// IndexClaims indexes the managed resources of a kind by claim key.
+func IndexClaims(mgr ctrl.Manager, obj client.Object, fn ClaimKeyFunc) error {
+	return mgr.GetFieldIndexer().IndexField(context.Background(), obj, ClaimIndexField, func(o client.Object) []string {
+		mg, ok := o.(resource.Managed)
+		if !ok {
+			return nil
+		}
+		return []string{fn(mg)}
+	})
+}
+
+// A ClaimConflictError tells that the Bitbucket object
+// is claimed by another managed resource.
+type ClaimConflictError struct {
+	Key   string
+	Owner string
+}
+
+func (e *ClaimConflictError) Error() string {
+	return fmt.Sprintf("'%s' is already managed by '%s'", e.Key, e.Owner)
+}
+
+// IsClaimConflict tells whether the error is a ClaimConflictError.
+func IsClaimConflict(err error) bool {
+	var e *ClaimConflictError
+	return errors.As(err, &e)
+}
+
+// CheckClaim returns a ClaimConflictError naming the owner if another managed
+// resource of the same kind claimed the same key first; otherwise the claim is
+// recorded in the AnnotationKeyClaim annotation of the resource. The first
+// resource to claim the Bitbucket object owns it; a conflicting one is never
+// synced and, when deleted, leaves the object to its owner.
+func CheckClaim(ctx context.Context, k client.Client, mg resource.Managed, list resource.ManagedList, fn ClaimKeyFunc) error {
+	key := fn(mg)
+	if err := k.List(ctx, list, client.MatchingFields{ClaimIndexField: key}); err != nil {
+		return errors.Wrap(err, "cannot list claims")
+	}
+
+	var owner resource.Managed
+	for _, el := range list.GetItems() {
+		if el.GetUID() == mg.GetUID() || el.GetAnnotations()[AnnotationKeyClaim] != key {
+			continue
+		}
+		if owner == nil || claimedBefore(el, owner) {
+			owner = el
+		}
+	}
+
+	// Simultaneous claims are settled by creation time, then by name.
+	if owner != nil && (mg.GetAnnotations()[AnnotationKeyClaim] != key || claimedBefore(owner, mg)) {
+		return &ClaimConflictError{Key: key, Owner: owner.GetName()}
+	}
+
+	if mg.GetAnnotations()[AnnotationKeyClaim] == key || meta.WasDeleted(mg) {
+		return nil
+	}
+
+	meta.AddAnnotations(mg, map[string]string{AnnotationKeyClaim: key})
+	return errors.Wrap(k.Update(ctx, mg), "cannot record claim")
+}
+
+func claimedBefore(a, b resource.Managed) bool {
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return a.GetName() < b.GetName()
+}
@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/krateoplatformops/provider-bitbucket/apis/repo/v1alpha1"
+)
+
+func newRepo(name string, created time.Time) v1alpha1.Repo {
+	cr := v1alpha1.Repo{}
+	cr.SetName(name)
+	cr.SetUID(types.UID(name))
+	cr.SetCreationTimestamp(metav1.NewTime(created))
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.Spec.ForProvider.Project = "JXP"
+	cr.Spec.ForProvider.Name = "demo-repo"
+	return cr
+}
+
+func repoClaimKey(mg resource.Managed) string {
+	cr := mg.(*v1alpha1.Repo)
+	return ClaimKey(cr, cr.Spec.ForProvider.Project, cr.Spec.ForProvider.Name)
+}
+
+func claim(cr *v1alpha1.Repo) *v1alpha1.Repo {
+	cr.SetAnnotations(map[string]string{AnnotationKeyClaim: "default/JXP/demo-repo"})
+	return cr
+}
+
+func TestCheckClaim(t *testing.T) {
+	now := time.Now()
+	older := newRepo("older", now)
+	newer := newRepo("newer", now.Add(time.Minute))
+	claim(&newer)
+
+	items := []v1alpha1.Repo{older, newer}
+	updated := 0
+	kube := &test.MockClient{
+		MockList: func(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+			lo := &client.ListOptions{}
+			lo.ApplyOptions(opts)
+			assert.Equal(t, ClaimIndexField+"=default/JXP/demo-repo", lo.FieldSelector.String())
+
+			list.(*v1alpha1.RepoList).Items = items
+			return nil
+		},
+		MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+			updated++
+			return nil
+		},
+	}
+
+	// The first claimant wins, even if created later.
+	err := CheckClaim(context.Background(), kube, older.DeepCopy(), &v1alpha1.RepoList{}, repoClaimKey)
+	assert.True(t, IsClaimConflict(err))
+	assert.Equal(t, "'default/JXP/demo-repo' is already managed by 'newer'", err.Error())
+
+	err = CheckClaim(context.Background(), kube, newer.DeepCopy(), &v1alpha1.RepoList{}, repoClaimKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updated)
+
+	// Simultaneous claims are settled by creation time.
+	items[0] = *claim(older.DeepCopy())
+	err = CheckClaim(context.Background(), kube, &items[0], &v1alpha1.RepoList{}, repoClaimKey)
+	assert.NoError(t, err)
+	err = CheckClaim(context.Background(), kube, &newer, &v1alpha1.RepoList{}, repoClaimKey)
+	assert.True(t, IsClaimConflict(err))
+	assert.Equal(t, 0, updated)
+}
+
+func TestCheckClaimRecord(t *testing.T) {
+	first := newRepo("first", time.Now())
+
+	var recorded client.Object
+	kube := &test.MockClient{
+		MockList: test.NewMockListFn(nil),
+		MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+			recorded = obj
+			return nil
+		},
+	}
+
+	err := CheckClaim(context.Background(), kube, &first, &v1alpha1.RepoList{}, repoClaimKey)
+	assert.NoError(t, err)
+	assert.Equal(t, &first, recorded)
+	assert.Equal(t, "default/JXP/demo-repo", first.GetAnnotations()[AnnotationKeyClaim])
+}
@@ -0,0 +1,797 @@
+// Package simulator emulates the subset of the Bitbucket Server REST API used
+// by the provider: projects, repositories, labels, branches, archives, files
+// and permissions, with paging and the Bitbucket error format.
+package simulator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+const defaultPageLimit = 25
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// slugify returns the slug Bitbucket assigns to a repository name.
+func slugify(name string) string {
+	return slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+type Options struct {
+	// StatePath, when set, is the file where the state is
+	// loaded from and saved after every change.
+	StatePath string
+	// Username and Token, when the token is set, are the only credentials
+	// accepted, as bearer token or basic auth password.
+	Username string
+	Token    string
+	// PageLimit is the maximum size of a page (default: 25).
+	PageLimit int
+	Log       logging.Logger
+}
+
+// Server is a Bitbucket Server simulator; it is an http.Handler.
+type Server struct {
+	opts Options
+
+	mu    sync.Mutex
+	state *State
+}
+
+// New returns a simulator, loading the state file if any.
+func New(opts Options) (*Server, error) {
+	if opts.PageLimit <= 0 {
+		opts.PageLimit = defaultPageLimit
+	}
+	if opts.Log == nil {
+		opts.Log = logging.NewNopLogger()
+	}
+
+	st := newState()
+	if len(opts.StatePath) > 0 {
+		var err error
+		if st, err = loadState(opts.StatePath); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Server{opts: opts, state: st}, nil
+}
+
+// Seed adds the seed projects and repositories missing from the state;
+// what has been persisted is never overwritten.
+func (s *Server) Seed(seed *Seed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.apply(seed)
+	return s.save()
+}
+
+func (s *Server) save() error {
+	if len(s.opts.StatePath) == 0 {
+		return nil
+	}
+	return saveState(s.opts.StatePath, s.state)
+}
+
+// apiError is an error reported with the Bitbucket error format.
+type apiError struct {
+	code      int
+	exception string
+	message   string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func errorf(code int, exception, format string, a ...interface{}) *apiError {
+	return &apiError{
+		code:      code,
+		exception: "com.atlassian.bitbucket." + exception,
+		message:   fmt.Sprintf(format, a...),
+	}
+}
+
+func writeError(w http.ResponseWriter, e *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"context":       nil,
+			"message":       e.message,
+			"exceptionName": e.exception,
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) authorized(req *http.Request) bool {
+	if len(s.opts.Token) == 0 {
+		return true
+	}
+	if user, pass, ok := req.BasicAuth(); ok {
+		return pass == s.opts.Token && (len(s.opts.Username) == 0 || user == s.opts.Username)
+	}
+	return req.Header.Get("Authorization") == "Bearer "+s.opts.Token
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.opts.Log.Debug("Request", "method", req.Method, "url", req.URL.String())
+
+	if !s.authorized(req) {
+		writeError(w, errorf(http.StatusUnauthorized, "auth.IncorrectPasswordAuthenticationException",
+			"Authentication failed. Please check your credentials and try again."))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed, err := s.route(w, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			s.opts.Log.Info("Cannot save state", "path", s.opts.StatePath, "error", err.Error())
+		}
+	}
+}
+
+// route dispatches the request, writing the response unless an error is
+// returned; changed tells whether the state has been changed.
+func (s *Server) route(w http.ResponseWriter, req *http.Request) (changed bool, err *apiError) {
+	p := req.URL.Path
+	switch {
+	case strings.HasPrefix(p, "/rest/api/1.0/projects"):
+		p = strings.TrimPrefix(p, "/rest/api/1.0/projects")
+	case strings.HasPrefix(p, "/rest/sync/latest/projects/"):
+		return s.refSync(w, req, strings.TrimPrefix(p, "/rest/sync/latest/projects/"))
+	default:
+		return false, errorf(http.StatusNotFound, "rest.NotFoundException", "No resource at %s.", p)
+	}
+
+	parts := strings.SplitN(strings.Trim(p, "/"), "/", 5)
+	if parts[0] == "" {
+		return s.projects(w, req)
+	}
+
+	proj, ok := s.state.Projects[parts[0]]
+	if !ok {
+		return false, errorf(http.StatusNotFound, "project.NoSuchProjectException", "Project %s does not exist.", parts[0])
+	}
+
+	switch {
+	case len(parts) == 1:
+		return s.project(w, req, proj)
+	case parts[1] != "repos":
+		return false, errorf(http.StatusNotFound, "rest.NotFoundException", "No resource at %s.", req.URL.Path)
+	case len(parts) == 2:
+		return s.repos(w, req, proj)
+	}
+
+	r, ok := proj.Repos[parts[2]]
+	if !ok {
+		return false, errorf(http.StatusNotFound, "repository.NoSuchRepositoryException",
+			"Repository %s/%s does not exist.", proj.Key, parts[2])
+	}
+	if len(parts) == 3 {
+		return s.repo(w, req, proj, r)
+	}
+
+	rest := ""
+	if len(parts) == 5 {
+		rest = parts[4]
+	}
+
+	switch parts[3] {
+	case "labels":
+		return s.labels(w, req, r)
+	case "branches":
+		return false, s.branches(w, req, r)
+	case "archive":
+		return false, s.archive(w, req, r)
+	case "raw":
+		return false, s.raw(w, req, r, rest)
+	case "commits":
+		return false, s.commits(w, req, r)
+	case "browse":
+		return s.browse(w, req, r, rest)
+	case "permissions":
+		switch rest {
+		case "users":
+			return s.permissions(w, req, r.Users, "user")
+		case "groups":
+			return s.permissions(w, req, r.Groups, "group")
+		}
+	}
+
+	return false, errorf(http.StatusNotFound, "rest.NotFoundException", "No resource at %s.", req.URL.Path)
+}
+
+// page writes the page of the values selected by the start and limit parameters.
+func (s *Server) page(w http.ResponseWriter, req *http.Request, values []interface{}) {
+	start, _ := strconv.Atoi(req.URL.Query().Get("start"))
+	if start < 0 || start > len(values) {
+		start = len(values)
+	}
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	if limit <= 0 || limit > s.opts.PageLimit {
+		limit = s.opts.PageLimit
+	}
+
+	end := start + limit
+	if end > len(values) {
+		end = len(values)
+	}
+
+	res := map[string]interface{}{
+		"values":     values[start:end],
+		"size":       end - start,
+		"limit":      limit,
+		"start":      start,
+		"isLastPage": end == len(values),
+	}
+	if end < len(values) {
+		res["nextPageStart"] = end
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func methodNotAllowed(req *http.Request) *apiError {
+	return errorf(http.StatusMethodNotAllowed, "rest.MethodNotAllowedException",
+		"Method %s is not supported for %s.", req.Method, req.URL.Path)
+}
+
+func decodeBody(req *http.Request, v interface{}) *apiError {
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "rest.BadRequestException", "Invalid request body: %s.", err)
+	}
+	return nil
+}
+
+func projectJSON(p *Project) map[string]interface{} {
+	return map[string]interface{}{"key": p.Key, "name": p.Name}
+}
+
+func (s *Server) projects(w http.ResponseWriter, req *http.Request) (bool, *apiError) {
+	switch req.Method {
+	case http.MethodGet:
+		keys := make([]string, 0, len(s.state.Projects))
+		for k := range s.state.Projects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = projectJSON(s.state.Projects[k])
+		}
+		s.page(w, req, values)
+		return false, nil
+
+	case http.MethodPost:
+		body := struct {
+			Key  string `json:"key"`
+			Name string `json:"name"`
+		}{}
+		if err := decodeBody(req, &body); err != nil {
+			return false, err
+		}
+		if len(body.Key) == 0 {
+			return false, errorf(http.StatusBadRequest, "validation.ArgumentValidationException", "The project key is required.")
+		}
+		if _, ok := s.state.Projects[body.Key]; ok {
+			return false, errorf(http.StatusConflict, "project.DuplicateProjectKeyException", "Project key %s is already taken.", body.Key)
+		}
+		if len(body.Name) == 0 {
+			body.Name = body.Key
+		}
+
+		p := &Project{Key: body.Key, Name: body.Name, Repos: map[string]*Repo{}}
+		s.state.Projects[p.Key] = p
+		writeJSON(w, http.StatusCreated, projectJSON(p))
+		return true, nil
+	}
+
+	return false, methodNotAllowed(req)
+}
+
+func (s *Server) project(w http.ResponseWriter, req *http.Request, p *Project) (bool, *apiError) {
+	if req.Method != http.MethodGet {
+		return false, methodNotAllowed(req)
+	}
+	writeJSON(w, http.StatusOK, projectJSON(p))
+	return false, nil
+}
+
+func (s *Server) repoJSON(p *Project, r *Repo) map[string]interface{} {
+	res := map[string]interface{}{
+		"name":     r.Name,
+		"slug":     r.Slug,
+		"scmId":    "git",
+		"state":    "AVAILABLE",
+		"public":   r.Public,
+		"archived": r.Archived,
+		"project":  projectJSON(p),
+	}
+
+	if parts := strings.SplitN(r.Origin, "/", 2); len(parts) == 2 {
+		if op, ok := s.state.Projects[parts[0]]; ok {
+			if or, ok := op.Repos[parts[1]]; ok {
+				res["origin"] = s.repoJSON(op, or)
+			}
+		}
+	}
+	return res
+}
+
+func (s *Server) repos(w http.ResponseWriter, req *http.Request, p *Project) (bool, *apiError) {
+	switch req.Method {
+	case http.MethodGet:
+		slugs := make([]string, 0, len(p.Repos))
+		for k := range p.Repos {
+			slugs = append(slugs, k)
+		}
+		sort.Strings(slugs)
+
+		values := make([]interface{}, len(slugs))
+		for i, k := range slugs {
+			values[i] = s.repoJSON(p, p.Repos[k])
+		}
+		s.page(w, req, values)
+		return false, nil
+
+	case http.MethodPost:
+		body := struct {
+			Name          string `json:"name"`
+			Public        bool   `json:"public"`
+			DefaultBranch string `json:"defaultBranch"`
+		}{}
+		if err := decodeBody(req, &body); err != nil {
+			return false, err
+		}
+		if len(body.Name) == 0 {
+			return false, errorf(http.StatusBadRequest, "validation.ArgumentValidationException", "The repository name is required.")
+		}
+
+		slug := slugify(body.Name)
+		if _, ok := p.Repos[slug]; ok {
+			return false, errorf(http.StatusConflict, "repository.DuplicateRepositoryNameException", "This repository URL is already taken.")
+		}
+
+		r := newRepo(body.Name, slug, body.Public)
+		if len(body.DefaultBranch) > 0 {
+			r.DefaultBranch = body.DefaultBranch
+		}
+		p.Repos[slug] = r
+		writeJSON(w, http.StatusCreated, s.repoJSON(p, r))
+		return true, nil
+	}
+
+	return false, methodNotAllowed(req)
+}
+
+func (s *Server) repo(w http.ResponseWriter, req *http.Request, p *Project, r *Repo) (bool, *apiError) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.repoJSON(p, r))
+		return false, nil
+
+	case http.MethodPut:
+		body := struct {
+			Name    *string `json:"name"`
+			Project *struct {
+				Key string `json:"key"`
+			} `json:"project"`
+			Archived *bool `json:"archived"`
+		}{}
+		if err := decodeBody(req, &body); err != nil {
+			return false, err
+		}
+
+		target := p
+		if body.Project != nil && body.Project.Key != p.Key {
+			var ok bool
+			if target, ok = s.state.Projects[body.Project.Key]; !ok {
+				return false, errorf(http.StatusNotFound, "project.NoSuchProjectException", "Project %s does not exist.", body.Project.Key)
+			}
+		}
+		slug := r.Slug
+		if body.Name != nil {
+			slug = slugify(*body.Name)
+		}
+		if target != p || slug != r.Slug {
+			if _, ok := target.Repos[slug]; ok {
+				return false, errorf(http.StatusConflict, "repository.DuplicateRepositoryNameException", "This repository URL is already taken.")
+			}
+		}
+
+		if body.Name != nil {
+			r.Name = *body.Name
+		}
+		if body.Archived != nil {
+			r.Archived = *body.Archived
+		}
+		delete(p.Repos, r.Slug)
+		r.Slug = slug
+		target.Repos[slug] = r
+
+		writeJSON(w, http.StatusOK, s.repoJSON(target, r))
+		return true, nil
+
+	case http.MethodDelete:
+		delete(p.Repos, r.Slug)
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"context":       nil,
+			"message":       fmt.Sprintf("Repository %s/%s has been deleted.", p.Key, r.Slug),
+			"exceptionName": nil,
+		})
+		return true, nil
+
+	case http.MethodPost:
+		return s.fork(w, req, p, r)
+	}
+
+	return false, methodNotAllowed(req)
+}
+
+func (s *Server) fork(w http.ResponseWriter, req *http.Request, p *Project, src *Repo) (bool, *apiError) {
+	body := struct {
+		Name    string `json:"name"`
+		Project *struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	}{}
+	if err := decodeBody(req, &body); err != nil {
+		return false, err
+	}
+
+	target := p
+	if body.Project != nil && len(body.Project.Key) > 0 {
+		var ok bool
+		if target, ok = s.state.Projects[body.Project.Key]; !ok {
+			return false, errorf(http.StatusNotFound, "project.NoSuchProjectException", "Project %s does not exist.", body.Project.Key)
+		}
+	}
+	if len(body.Name) == 0 {
+		body.Name = src.Name
+	}
+
+	slug := slugify(body.Name)
+	if _, ok := target.Repos[slug]; ok {
+		return false, errorf(http.StatusConflict, "repository.DuplicateRepositoryNameException", "This repository URL is already taken.")
+	}
+
+	r := newRepo(body.Name, slug, src.Public)
+	r.DefaultBranch = src.DefaultBranch
+	r.Origin = p.Key + "/" + src.Slug
+	for name, br := range src.Branches {
+		cp := &Branch{Head: br.Head, Files: map[string]*File{}}
+		for k, v := range br.Files {
+			f := *v
+			cp.Files[k] = &f
+		}
+		r.Branches[name] = cp
+	}
+	target.Repos[slug] = r
+
+	writeJSON(w, http.StatusCreated, s.repoJSON(target, r))
+	return true, nil
+}
+
+func (s *Server) refSync(w http.ResponseWriter, req *http.Request, p string) (bool, *apiError) {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	if len(parts) != 3 || parts[1] != "repos" {
+		return false, errorf(http.StatusNotFound, "rest.NotFoundException", "No resource at %s.", req.URL.Path)
+	}
+
+	proj, ok := s.state.Projects[parts[0]]
+	if !ok {
+		return false, errorf(http.StatusNotFound, "project.NoSuchProjectException", "Project %s does not exist.", parts[0])
+	}
+	r, ok := proj.Repos[parts[2]]
+	if !ok {
+		return false, errorf(http.StatusNotFound, "repository.NoSuchRepositoryException",
+			"Repository %s/%s does not exist.", proj.Key, parts[2])
+	}
+
+	changed := false
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if len(r.Origin) == 0 {
+			return false, errorf(http.StatusConflict, "repository.sync.RefSyncNotAvailableException",
+				"Repository %s/%s is not a fork.", proj.Key, r.Slug)
+		}
+		body := struct {
+			Enabled bool `json:"enabled"`
+		}{}
+		if err := decodeBody(req, &body); err != nil {
+			return false, err
+		}
+		r.RefSync = body.Enabled
+		changed = true
+	default:
+		return false, methodNotAllowed(req)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"available": len(r.Origin) > 0,
+		"enabled":   r.RefSync,
+	})
+	return changed, nil
+}
+
+func (s *Server) labels(w http.ResponseWriter, req *http.Request, r *Repo) (bool, *apiError) {
+	switch req.Method {
+	case http.MethodGet:
+		values := make([]interface{}, len(r.Labels))
+		for i, el := range r.Labels {
+			values[i] = map[string]string{"name": el}
+		}
+		s.page(w, req, values)
+		return false, nil
+
+	case http.MethodPost:
+		body := struct {
+			Name string `json:"name"`
+		}{}
+		if err := decodeBody(req, &body); err != nil {
+			return false, err
+		}
+		for _, el := range r.Labels {
+			if el == body.Name {
+				return false, errorf(http.StatusConflict, "label.DuplicateLabelException", "Label %s is already applied.", body.Name)
+			}
+		}
+		r.Labels = append(r.Labels, body.Name)
+		writeJSON(w, http.StatusOK, map[string]string{"name": body.Name})
+		return true, nil
+	}
+
+	return false, methodNotAllowed(req)
+}
+
+func (s *Server) branches(w http.ResponseWriter, req *http.Request, r *Repo) *apiError {
+	if req.Method != http.MethodGet {
+		return methodNotAllowed(req)
+	}
+
+	names := make([]string, 0, len(r.Branches))
+	for k := range r.Branches {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	values := make([]interface{}, len(names))
+	for i, k := range names {
+		values[i] = map[string]interface{}{
+			"id":           "refs/heads/" + k,
+			"displayId":    k,
+			"latestCommit": r.Branches[k].Head,
+			"isDefault":    k == r.DefaultBranch,
+		}
+	}
+	s.page(w, req, values)
+	return nil
+}
+
+// branch returns the branch of the ref, the default one if empty; the ref
+// is a branch name or the head commit of a branch.
+func branch(r *Repo, ref string) (*Branch, *apiError) {
+	name := strings.TrimPrefix(ref, "refs/heads/")
+	if len(name) == 0 {
+		name = r.DefaultBranch
+	}
+	if br, ok := r.Branches[name]; ok {
+		return br, nil
+	}
+	// A commit is resolved only if it is the head of a branch.
+	for _, br := range r.Branches {
+		if br.Head == ref {
+			return br, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "commit.NoSuchCommitException", "Commit '%s' does not exist in repository '%s'.", ref, r.Slug)
+}
+
+// archive writes the files of the branch as a tgz, whatever the format.
+func (s *Server) archive(w http.ResponseWriter, req *http.Request, r *Repo) *apiError {
+	if req.Method != http.MethodGet {
+		return methodNotAllowed(req)
+	}
+
+	br, err := branch(r, req.URL.Query().Get("at"))
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(br.Files))
+	for k := range br.Files {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, el := range paths {
+		dat := br.Files[el].Content
+		if err := tw.WriteHeader(&tar.Header{Name: el, Mode: 0o644, Size: int64(len(dat))}); err != nil {
+			return nil
+		}
+		if _, err := tw.Write(dat); err != nil {
+			return nil
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return nil
+}
+
+func (s *Server) raw(w http.ResponseWriter, req *http.Request, r *Repo, path string) *apiError {
+	if req.Method != http.MethodGet {
+		return methodNotAllowed(req)
+	}
+
+	br, err := branch(r, req.URL.Query().Get("at"))
+	if err != nil {
+		return err
+	}
+	f, ok := br.Files[path]
+	if !ok {
+		return errorf(http.StatusNotFound, "content.NoSuchPathException", "The path \"%s\" does not exist at revision \"%s\"", path, br.Head)
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write(f.Content)
+	return nil
+}
+
+// commits lists the last commit changing the path, the only one the provider uses.
+func (s *Server) commits(w http.ResponseWriter, req *http.Request, r *Repo) *apiError {
+	if req.Method != http.MethodGet {
+		return methodNotAllowed(req)
+	}
+
+	br, err := branch(r, req.URL.Query().Get("until"))
+	if err != nil {
+		return err
+	}
+
+	values := []interface{}{}
+	if f, ok := br.Files[req.URL.Query().Get("path")]; ok {
+		values = append(values, f.Commit)
+	}
+	s.page(w, req, values)
+	return nil
+}
+
+// browse commits a file; the branch is created only if the repository is
+// empty and edits not based on the last commit of the file are rejected.
+func (s *Server) browse(w http.ResponseWriter, req *http.Request, r *Repo, path string) (bool, *apiError) {
+	if req.Method != http.MethodPut {
+		return false, methodNotAllowed(req)
+	}
+	if len(path) == 0 {
+		return false, errorf(http.StatusBadRequest, "validation.ArgumentValidationException", "The path is required.")
+	}
+	if err := req.ParseMultipartForm(32 << 20); err != nil {
+		return false, errorf(http.StatusBadRequest, "rest.BadRequestException", "Invalid multipart request: %s.", err)
+	}
+
+	file, _, err := req.FormFile("content")
+	if err != nil {
+		return false, errorf(http.StatusBadRequest, "validation.ArgumentValidationException", "The content is required.")
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(file, 32<<20))
+	if err != nil {
+		return false, errorf(http.StatusBadRequest, "rest.BadRequestException", "Cannot read the content: %s.", err)
+	}
+
+	name := strings.TrimPrefix(req.FormValue("branch"), "refs/heads/")
+	if len(name) == 0 {
+		name = r.DefaultBranch
+	}
+	if _, ok := r.Branches[name]; !ok && len(r.Branches) > 0 {
+		return false, errorf(http.StatusNotFound, "repository.NoSuchBranchException", "Branch %s does not exist.", name)
+	}
+
+	source := req.FormValue("sourceCommitId")
+	if br, ok := r.Branches[name]; ok {
+		if f, ok := br.Files[path]; ok {
+			switch {
+			case len(source) == 0:
+				return false, errorf(http.StatusConflict, "content.FileAlreadyExistsException",
+					"The file '%s' already exists.", path)
+			case source < f.Commit.Id:
+				// The commits are numbered, thus ordered.
+				return false, errorf(http.StatusConflict, "content.FileOutOfDateException",
+					"The file '%s' has been modified since the commit %s.", path, source)
+			}
+		}
+	}
+
+	message := req.FormValue("message")
+	if len(message) == 0 {
+		message = "Edited " + path
+	}
+
+	c := s.state.commit(r, name, path, message, content)
+	writeJSON(w, http.StatusOK, c)
+	return true, nil
+}
+
+// permissions handles the users or the groups permissions, the kind
+// tells which one and names the entity of the JSON values.
+func (s *Server) permissions(w http.ResponseWriter, req *http.Request, perms map[string]string, kind string) (bool, *apiError) {
+	q := req.URL.Query()
+
+	switch req.Method {
+	case http.MethodGet:
+		names := make([]string, 0, len(perms))
+		for k := range perms {
+			if filter := q.Get("filter"); len(filter) == 0 || strings.Contains(k, filter) {
+				names = append(names, k)
+			}
+		}
+		sort.Strings(names)
+
+		values := make([]interface{}, len(names))
+		for i, k := range names {
+			values[i] = map[string]interface{}{
+				kind:         map[string]string{"name": k},
+				"permission": perms[k],
+			}
+		}
+		s.page(w, req, values)
+		return false, nil
+
+	case http.MethodPut:
+		name, perm := q.Get("name"), q.Get("permission")
+		switch perm {
+		case "REPO_READ", "REPO_WRITE", "REPO_ADMIN":
+		default:
+			return false, errorf(http.StatusBadRequest, "validation.ArgumentValidationException",
+				"The permission '%s' is not a repository permission.", perm)
+		}
+		if len(name) == 0 {
+			return false, errorf(http.StatusBadRequest, "validation.ArgumentValidationException", "The %s name is required.", kind)
+		}
+		perms[name] = perm
+		w.WriteHeader(http.StatusNoContent)
+		return true, nil
+
+	case http.MethodDelete:
+		delete(perms, q.Get("name"))
+		w.WriteHeader(http.StatusNoContent)
+		return true, nil
+	}
+
+	return false, methodNotAllowed(req)
+}
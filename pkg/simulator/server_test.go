@@ -0,0 +1,146 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+	"github.com/krateoplatformops/provider-bitbucket/pkg/helpers"
+)
+
+func newClient(server *httptest.Server, token string) bitbucket.Interface {
+	return bitbucket.NewClient(&bitbucket.ClientOpts{
+		ApiBaseUrl: server.URL,
+		HttpClient: server.Client(),
+		Token:      token,
+	})
+}
+
+func TestServerRepos(t *testing.T) {
+	ctx := context.Background()
+	sim, err := New(Options{Token: "secret"})
+	assert.NoError(t, err)
+	assert.NoError(t, sim.Seed(&Seed{Projects: []SeedProject{{Key: "JXP"}}}))
+
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	repos := newClient(server, "secret").Repos()
+
+	res, err := repos.Create(ctx, bitbucket.CreateRepoOpts{Name: "Demo Repo", ProjectKey: "JXP"})
+	assert.NoError(t, err)
+	assert.Equal(t, "demo-repo", res.Slug)
+
+	_, err = repos.Create(ctx, bitbucket.CreateRepoOpts{Name: "Demo Repo", ProjectKey: "JXP"})
+	assert.EqualError(t, err, "This repository URL is already taken.")
+
+	opts := bitbucket.GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}
+	assert.NoError(t, repos.Init(ctx, bitbucket.RepoInitOpts{ProjectKey: "JXP", RepoSlug: "demo-repo"}))
+
+	raw, err := repos.GetRaw(ctx, bitbucket.FileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: "README.md"})
+	assert.NoError(t, err)
+	assert.Equal(t, "# demo-repo", string(raw))
+
+	last, err := repos.LastCommit(ctx, bitbucket.FileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: "README.md", Branch: "main"})
+	assert.NoError(t, err)
+	assert.NotNil(t, last)
+
+	_, err = repos.CommitFile(ctx, bitbucket.CommitFileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: "README.md", Branch: "main", Content: []byte("# stale")})
+	assert.EqualError(t, err, "The file 'README.md' already exists.")
+
+	_, err = repos.CommitFile(ctx, bitbucket.CommitFileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: "README.md", Branch: "main",
+		Content: []byte("# updated"), SourceCommitId: last.Id})
+	assert.NoError(t, err)
+
+	_, err = repos.CommitFile(ctx, bitbucket.CommitFileOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Path: "README.md", Branch: "main",
+		Content: []byte("# outdated"), SourceCommitId: last.Id})
+	assert.EqualError(t, err, "The file 'README.md' has been modified since the commit "+last.Id+".")
+
+	assert.NoError(t, repos.AddLabel(ctx, opts, "managed"))
+	labels, err := repos.Labels(ctx, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"managed"}, labels)
+
+	fork, err := repos.Fork(ctx, bitbucket.ForkRepoOpts{ProjectKey: "JXP", RepoSlug: "demo-repo", Name: "demo-fork", TargetProjectKey: "JXP"})
+	assert.NoError(t, err)
+	assert.Equal(t, "demo-repo", fork.Origin.Slug)
+
+	_, err = repos.Update(ctx, "JXP", "demo-repo", bitbucket.UpdateRepoOpts{Archived: helpers.BoolPtr(true)})
+	assert.NoError(t, err)
+	res, err = repos.Get(ctx, opts)
+	assert.NoError(t, err)
+	assert.True(t, res.Archived)
+
+	assert.NoError(t, repos.Delete(ctx, "JXP", "demo-repo"))
+	res, err = repos.Get(ctx, opts)
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+
+	_, err = newClient(server, "wrong").Repos().List(ctx, "JXP")
+	assert.EqualError(t, err, "Authentication failed. Please check your credentials and try again.")
+}
+
+func TestServerPermissionsPaging(t *testing.T) {
+	ctx := context.Background()
+	sim, err := New(Options{PageLimit: 2})
+	assert.NoError(t, err)
+	assert.NoError(t, sim.Seed(&Seed{Projects: []SeedProject{{Key: "JXP", Repos: []SeedRepo{{Name: "demo"}}}}}))
+
+	server := httptest.NewServer(sim)
+	defer server.Close()
+
+	perms := newClient(server, "").Permissions()
+	for i := 0; i < 5; i++ {
+		err := perms.SetUserPermissions(ctx, bitbucket.UserPermissionOpts{
+			ProjectKey: "JXP", RepoSlug: "demo", User: fmt.Sprintf("user%d", i), Permission: "REPO_READ",
+		})
+		assert.NoError(t, err)
+	}
+
+	all, err := perms.ListUserPermissions(ctx, bitbucket.GetRepoOpts{ProjectKey: "JXP", RepoSlug: "demo"})
+	assert.NoError(t, err)
+	assert.Len(t, all, 5)
+	assert.Equal(t, "user4", all[4].User.Name)
+
+	err = perms.SetUserPermissions(ctx, bitbucket.UserPermissionOpts{ProjectKey: "JXP", RepoSlug: "demo", User: "user0", Permission: "REPO_OWNER"})
+	assert.EqualError(t, err, "The permission 'REPO_OWNER' is not a repository permission.")
+}
+
+func TestServerPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+	seed := &Seed{Projects: []SeedProject{{
+		Key:   "JXP",
+		Repos: []SeedRepo{{Name: "demo", Files: map[string]string{"README.md": "# demo"}}},
+	}}}
+
+	sim, err := New(Options{StatePath: path})
+	assert.NoError(t, err)
+	assert.NoError(t, sim.Seed(seed))
+
+	server := httptest.NewServer(sim)
+	_, err = newClient(server, "").Repos().Create(ctx, bitbucket.CreateRepoOpts{Name: "other", ProjectKey: "JXP"})
+	assert.NoError(t, err)
+	server.Close()
+
+	sim, err = New(Options{StatePath: path})
+	assert.NoError(t, err)
+	// Seeding again leaves the persisted state untouched.
+	assert.NoError(t, sim.Seed(seed))
+
+	server = httptest.NewServer(sim)
+	defer server.Close()
+
+	all, err := newClient(server, "").Repos().List(ctx, "JXP")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	raw, err := newClient(server, "").Repos().GetRaw(ctx, bitbucket.FileOpts{ProjectKey: "JXP", RepoSlug: "demo", Path: "README.md"})
+	assert.NoError(t, err)
+	assert.Equal(t, "# demo", string(raw))
+}
@@ -0,0 +1,208 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/krateoplatformops/provider-bitbucket/pkg/clients/bitbucket"
+)
+
+// State is everything the simulator knows; it is persisted as JSON.
+type State struct {
+	Projects map[string]*Project `json:"projects"`
+	// Commits counts the commits, to generate their ids.
+	Commits int `json:"commits"`
+}
+
+type Project struct {
+	Key   string           `json:"key"`
+	Name  string           `json:"name"`
+	Repos map[string]*Repo `json:"repos"`
+}
+
+type Repo struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Public   bool   `json:"public"`
+	Archived bool   `json:"archived"`
+	// Origin is the project key and slug of the forked repository.
+	Origin        string             `json:"origin,omitempty"`
+	RefSync       bool               `json:"refSync"`
+	DefaultBranch string             `json:"defaultBranch"`
+	Labels        []string           `json:"labels"`
+	Users         map[string]string  `json:"users"`
+	Groups        map[string]string  `json:"groups"`
+	Branches      map[string]*Branch `json:"branches"`
+}
+
+type Branch struct {
+	Head  string           `json:"head"`
+	Files map[string]*File `json:"files"`
+}
+
+type File struct {
+	Content []byte `json:"content"`
+	// Commit is the last commit changing the file.
+	Commit bitbucket.Commit `json:"commit"`
+}
+
+func newState() *State {
+	return &State{Projects: map[string]*Project{}}
+}
+
+func newRepo(name, slug string, public bool) *Repo {
+	return &Repo{
+		Name:          name,
+		Slug:          slug,
+		Public:        public,
+		DefaultBranch: "main",
+		Labels:        []string{},
+		Users:         map[string]string{},
+		Groups:        map[string]string{},
+		Branches:      map[string]*Branch{},
+	}
+}
+
+func (st *State) nextCommit(message string) bitbucket.Commit {
+	st.Commits++
+	id := fmt.Sprintf("%040x", st.Commits)
+	return bitbucket.Commit{Id: id, DisplayId: id[:11], Message: message}
+}
+
+// commit writes the file on the branch, creating the branch if missing.
+func (st *State) commit(r *Repo, branch, path, message string, content []byte) bitbucket.Commit {
+	br, ok := r.Branches[branch]
+	if !ok {
+		br = &Branch{Files: map[string]*File{}}
+		r.Branches[branch] = br
+	}
+	c := st.nextCommit(message)
+	br.Files[path] = &File{Content: append([]byte{}, content...), Commit: c}
+	br.Head = c.Id
+	return c
+}
+
+// loadState reads the state from the file; a missing file is an empty state.
+func loadState(path string) (*State, error) {
+	dat, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st := newState()
+	if err := json.Unmarshal(dat, st); err != nil {
+		return nil, fmt.Errorf("cannot decode state '%s': %w", path, err)
+	}
+	return st, nil
+}
+
+// saveState writes the state to the file, replacing it atomically.
+func saveState(path string, st *State) error {
+	dat, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(dat); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Seed describes projects and repositories to create at startup.
+type Seed struct {
+	Projects []SeedProject `json:"projects"`
+}
+
+type SeedProject struct {
+	Key   string     `json:"key"`
+	Name  string     `json:"name,omitempty"`
+	Repos []SeedRepo `json:"repos,omitempty"`
+}
+
+type SeedRepo struct {
+	Name     string   `json:"name"`
+	Public   bool     `json:"public,omitempty"`
+	Archived bool     `json:"archived,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	// Users and Groups map names to permissions (i.e. REPO_WRITE).
+	Users  map[string]string `json:"users,omitempty"`
+	Groups map[string]string `json:"groups,omitempty"`
+	// Files maps paths to contents, committed on the main branch.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// LoadSeed reads a YAML seed file.
+func LoadSeed(path string) (*Seed, error) {
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := &Seed{}
+	if err := yaml.UnmarshalStrict(dat, seed); err != nil {
+		return nil, fmt.Errorf("cannot decode seed '%s': %w", path, err)
+	}
+	return seed, nil
+}
+
+// apply adds the seed projects and repositories missing from the state.
+func (st *State) apply(seed *Seed) {
+	for _, sp := range seed.Projects {
+		p, ok := st.Projects[sp.Key]
+		if !ok {
+			p = &Project{Key: sp.Key, Name: sp.Name, Repos: map[string]*Repo{}}
+			if len(p.Name) == 0 {
+				p.Name = sp.Key
+			}
+			st.Projects[sp.Key] = p
+		}
+
+		for _, sr := range sp.Repos {
+			slug := slugify(sr.Name)
+			if _, ok := p.Repos[slug]; ok {
+				continue
+			}
+
+			r := newRepo(sr.Name, slug, sr.Public)
+			r.Archived = sr.Archived
+			r.Labels = append(r.Labels, sr.Labels...)
+			for k, v := range sr.Users {
+				r.Users[k] = v
+			}
+			for k, v := range sr.Groups {
+				r.Groups[k] = v
+			}
+
+			paths := make([]string, 0, len(sr.Files))
+			for k := range sr.Files {
+				paths = append(paths, k)
+			}
+			sort.Strings(paths)
+			for _, el := range paths {
+				st.commit(r, r.DefaultBranch, el, "seed "+el, []byte(sr.Files[el]))
+			}
+
+			p.Repos[slug] = r
+		}
+	}
+}